@@ -0,0 +1,210 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// asyncOperationStatus is the status resource of a long-running operation
+//
+// Azure APIs give a status (e.g.: "Running", "Succeeded"), Google APIs give a done flag.
+type asyncOperationStatus struct {
+	Status           string          `json:"status"`
+	Done             *bool           `json:"done"`
+	Error            json.RawMessage `json:"error"`
+	ResourceLocation string          `json:"resourceLocation"`
+}
+
+// SendAsyncOperation sends the request and waits for the long-running operation it starts to complete
+//
+// When the response is 202 Accepted (or 201 Created) with an Operation-Location, Azure-AsyncOperation, or Location header,
+// the status resource at that location is polled with GET requests until the operation reaches a terminal status:
+//   - a response that is not 202 Accepted and has no status,
+//   - a JSON response with a "status" of "Succeeded", "Failed", "Canceled", or "Cancelled" (Azure),
+//   - a JSON response with "done": true (Google).
+//
+// The first status request is sent after PollInterval (by default: 1 second), the delay doubles after each request
+// up to MaxPollInterval (by default: 1 minute). A Retry-After header in the response overrides the delay, up to MaxRetryAfter.
+// To give up after some time, use a Context with a deadline in the options.
+//
+// If the operation succeeds, the final Content is decoded into results (if not nil) and returned.
+// If the status resource gives a "resourceLocation", the Content of that resource is returned instead.
+// If the operation fails or is canceled, an AsyncOperationFailed error is returned with the final Content.
+func SendAsyncOperation(options *Options, results interface{}) (*Content, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	return sendAsyncOperation(options, results, false)
+}
+
+// followAsyncLocation sends the request of Options with FollowAsyncLocation and waits for the operation it starts, if any
+//
+// Unlike SendAsyncOperation, a response without a location to follow is returned as is.
+func followAsyncLocation(options *Options, results interface{}) (*Content, error) {
+	if isContentReader(results) {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("results", fmt.Sprintf("%T", results)), fmt.Errorf("an asynchronous operation is read before it is given to the results"))
+	}
+	if writers, ok := results.([]io.Writer); ok {
+		for index, writer := range writers {
+			if writer == nil {
+				return nil, errors.ArgumentMissing.With(fmt.Sprintf("results[%d]", index))
+			}
+		}
+		results = io.MultiWriter(writers...)
+	}
+	return sendAsyncOperation(options, results, true)
+}
+
+// sendAsyncOperation sends the request and polls the operation it starts until it reaches a terminal status
+//
+// If locationRequired is true, the first response is final when it has no location to follow.
+func sendAsyncOperation(options *Options, results interface{}, locationRequired bool) (*Content, error) {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	operationOptions := options.Clone()
+	operationOptions.FollowAsyncLocation = false
+
+	delay := options.PollInterval
+	if delay <= 0 {
+		delay = DefaultAsyncPollInterval
+	}
+	maxDelay := options.MaxPollInterval
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxPollInterval
+	}
+	maxRetryAfter := options.MaxRetryAfter
+	if maxRetryAfter <= 0 {
+		maxRetryAfter = DefaultMaxRetryAfter
+	}
+
+	content, err := Send(operationOptions, nil)
+	if err != nil {
+		return content, err
+	}
+	statusOptions := operationOptions
+	polling := false
+	for {
+		location := asyncLocation(content.Headers, content.URL)
+		status := readAsyncOperationStatus(content)
+		running := content.StatusCode == http.StatusAccepted
+		if !polling && content.StatusCode == http.StatusCreated && location != nil {
+			running = true
+		}
+		if !polling && location == nil && locationRequired {
+			status, running = nil, false // there is nothing to follow
+		}
+		if status != nil {
+			switch state := strings.ToLower(status.Status); {
+			case state == "succeeded":
+				running = false
+			case state == "failed" || state == "canceled" || state == "cancelled":
+				return content, AsyncOperationFailed.With(describeURL(content.URL), asyncOperationFailure(status.Status, status.Error))
+			case status.Done != nil:
+				running = !*status.Done
+				if *status.Done && len(status.Error) > 0 && string(status.Error) != "null" {
+					return content, AsyncOperationFailed.With(describeURL(content.URL), asyncOperationFailure("Failed", status.Error))
+				}
+			case len(state) > 0:
+				running = true
+			}
+		}
+
+		if !running {
+			if status != nil && len(status.ResourceLocation) > 0 && content.URL != nil {
+				if resource, err := content.URL.Parse(status.ResourceLocation); err == nil {
+					if content, err = Send(asyncStatusOptions(operationOptions, resource), nil); err != nil {
+						return content, err
+					}
+				}
+			}
+			if results != nil && len(content.Data) > 0 {
+				if writer, ok := results.(io.Writer); ok {
+					if _, err := writer.Write(content.Data); err != nil {
+						return content, errors.WithStack(err)
+					}
+				} else if err := decodeResults(content, results, operationOptions); err != nil {
+					return content, err
+				}
+			}
+			return content, nil
+		}
+
+		if location != nil && (!polling || content.StatusCode == http.StatusAccepted) {
+			statusOptions = asyncStatusOptions(operationOptions, location)
+		} else if !polling && !isGetRequest(operationOptions) {
+			return content, ResponseHeaderInvalid.With("Location", "any value") // sending the request again would start another operation
+		}
+		polling = true
+
+		wait, found := parseRetryAfter(content.Headers)
+		if found && wait > maxRetryAfter {
+			wait = maxRetryAfter
+		} else if !found {
+			wait = delay
+			if delay = 2 * delay; delay > maxDelay {
+				delay = maxDelay
+			}
+		}
+		if operationOptions.Logger != nil {
+			operationOptions.Logger.Debugf("Asynchronous operation is still running, checking %s in %s", statusOptions.URL, wait)
+		}
+		if !waitPollInterval(ctx, wait, 0) {
+			return content, errors.WithStack(ctx.Err())
+		}
+		if content, err = Send(statusOptions, nil); err != nil {
+			return content, err
+		}
+	}
+}
+
+// readAsyncOperationStatus reads the status of a long-running operation from a JSON response
+//
+// returns nil if the response is not JSON or does not contain a status
+func readAsyncOperationStatus(content *Content) *asyncOperationStatus {
+	if len(content.Data) == 0 || !strings.Contains(content.Type, "json") {
+		return nil
+	}
+	status := asyncOperationStatus{}
+	if err := json.Unmarshal(jsonData(content.Data), &status); err != nil {
+		return nil
+	}
+	if len(status.Status) == 0 && status.Done == nil {
+		return nil
+	}
+	return &status
+}
+
+// asyncOperationFailure describes why a long-running operation failed
+func asyncOperationFailure(state string, details json.RawMessage) string {
+	if len(details) == 0 || string(details) == "null" {
+		return state
+	}
+	var message string
+	if err := json.Unmarshal(details, &message); err == nil && len(message) > 0 {
+		return state + ": " + message
+	}
+	described := struct {
+		Code    interface{} `json:"code"`
+		Message string      `json:"message"`
+	}{}
+	if err := json.Unmarshal(details, &described); err == nil && len(described.Message) > 0 {
+		return state + ": " + described.Message
+	}
+	return state
+}
+
+// isGetRequest tells if the options send a GET request
+func isGetRequest(options *Options) bool {
+	if len(options.Method) > 0 {
+		return strings.EqualFold(options.Method, http.MethodGet)
+	}
+	return options.Payload == nil && options.Attachment == nil && len(options.Attachments) == 0
+}
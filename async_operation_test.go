@@ -0,0 +1,259 @@
+package request_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateAsyncOperationTestServer(suite *RequestSuite, checks *atomic.Int32, finalStatus string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/resources":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Azure-AsyncOperation", "/operations/1")
+			w.WriteHeader(http.StatusAccepted)
+		case "/jobs":
+			w.Header().Set("Location", "/operations/2")
+			w.WriteHeader(http.StatusAccepted)
+		case "/operations/1":
+			w.Header().Set("Content-Type", "application/json")
+			if checks.Add(1) < 3 {
+				_, _ = w.Write([]byte(`{"status": "Running"}`))
+				return
+			}
+			switch finalStatus {
+			case "Failed":
+				_, _ = w.Write([]byte(`{"status": "Failed", "error": {"code": "QuotaExceeded", "message": "Quota exceeded"}}`))
+			default:
+				_, _ = w.Write([]byte(fmt.Sprintf(`{"status": "%s", "resourceLocation": "/resources/1"}`, finalStatus)))
+			}
+		case "/operations/2":
+			w.Header().Set("Content-Type", "application/json")
+			if checks.Add(1) < 2 {
+				_, _ = w.Write([]byte(`{"name": "operations/2", "done": false}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"name": "operations/2", "done": true, "response": {"id": "2"}}`))
+		case "/resources/1":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": "1", "name": "Resource 1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendAsyncOperation() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Succeeded")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	resource := struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}{}
+	content, err := request.SendAsyncOperation(&request.Options{
+		URL:          serverURL.JoinPath("resources"),
+		Payload:      struct{ Name string }{Name: "Resource 1"},
+		PollInterval: 10 * time.Millisecond,
+		Logger:       suite.Logger,
+	}, &resource)
+	suite.Require().NoError(err, "Failed to send the async operation")
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("/resources/1", content.URL.Path)
+	suite.Assert().Equal("1", resource.ID)
+	suite.Assert().Equal("Resource 1", resource.Name)
+	suite.Assert().Equal(int32(3), checks.Load())
+}
+
+func (suite *RequestSuite) TestCanSendAsyncOperationWithDoneFlag() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Succeeded")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	operation := struct {
+		Name     string `json:"name"`
+		Done     bool   `json:"done"`
+		Response struct {
+			ID string `json:"id"`
+		} `json:"response"`
+	}{}
+	_, err := request.SendAsyncOperation(&request.Options{
+		Method:       http.MethodPost,
+		URL:          serverURL.JoinPath("jobs"),
+		PollInterval: 10 * time.Millisecond,
+		Logger:       suite.Logger,
+	}, &operation)
+	suite.Require().NoError(err, "Failed to send the async operation")
+	suite.Assert().True(operation.Done)
+	suite.Assert().Equal("2", operation.Response.ID)
+	suite.Assert().Equal(int32(2), checks.Load())
+}
+
+func (suite *RequestSuite) TestShouldFailSendingAsyncOperationWhenOperationFails() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Failed")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	content, err := request.SendAsyncOperation(&request.Options{
+		URL:          serverURL.JoinPath("resources"),
+		Payload:      struct{ Name string }{Name: "Resource 1"},
+		PollInterval: 10 * time.Millisecond,
+		Logger:       suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "The async operation should have failed")
+	suite.Assert().ErrorIs(err, request.AsyncOperationFailed)
+	suite.Assert().Contains(err.Error(), "Quota exceeded")
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("/operations/1", content.URL.Path)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingAsyncOperationWhenCanceled() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Canceled")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	_, err := request.SendAsyncOperation(&request.Options{
+		URL:          serverURL.JoinPath("resources"),
+		Payload:      struct{ Name string }{Name: "Resource 1"},
+		PollInterval: 10 * time.Millisecond,
+		Logger:       suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "The async operation should have failed")
+	suite.Assert().ErrorIs(err, request.AsyncOperationFailed)
+}
+
+func (suite *RequestSuite) TestShouldStopSendingAsyncOperationWhenContextIsDone() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Succeeded")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := request.SendAsyncOperation(&request.Options{
+		Context:      ctx,
+		URL:          serverURL.JoinPath("resources"),
+		Payload:      struct{ Name string }{Name: "Resource 1"},
+		PollInterval: time.Second,
+		Logger:       suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "The async operation should have been stopped")
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded)
+	suite.Assert().Equal(int32(0), checks.Load())
+}
+
+func (suite *RequestSuite) TestCanSendAsyncOperationThatCompletesImmediately() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Succeeded")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	resource := struct {
+		ID string `json:"id"`
+	}{}
+	content, err := request.SendAsyncOperation(&request.Options{
+		URL:    serverURL.JoinPath("resources", "1"),
+		Logger: suite.Logger,
+	}, &resource)
+	suite.Require().NoError(err, "Failed to send the request")
+	suite.Assert().Equal(http.StatusOK, content.StatusCode)
+	suite.Assert().Equal("1", resource.ID)
+	suite.Assert().Equal(int32(0), checks.Load())
+}
+
+func (suite *RequestSuite) TestShouldFailSendingAsyncOperationWithoutOptions() {
+	_, err := request.SendAsyncOperation(nil, nil)
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
+
+func (suite *RequestSuite) TestCanFollowAsyncLocationUntilTerminalStatus() {
+	checks := atomic.Int32{}
+	server := CreateAsyncOperationTestServer(suite, &checks, "Succeeded")
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	resource := struct {
+		ID string `json:"id"`
+	}{}
+	content, err := request.Send(&request.Options{
+		URL:                 serverURL.JoinPath("resources"),
+		Payload:             struct{ Name string }{Name: "Resource 1"},
+		FollowAsyncLocation: true,
+		PollInterval:        10 * time.Millisecond,
+		Logger:              suite.Logger,
+	}, &resource)
+	suite.Require().NoError(err, "Failed to send the request, err=%+v", err)
+	suite.Assert().Equal("/resources/1", content.URL.Path)
+	suite.Assert().Equal("1", resource.ID)
+	suite.Assert().Equal(int32(3), checks.Load(), "Running statuses should be polled again")
+}
+
+func (suite *RequestSuite) TestShouldCapRetryAfterWhenFollowingAsyncLocation() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jobs" {
+			w.Header().Set("Location", "/operations/1")
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status": "Succeeded"}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	start := time.Now()
+	_, err := request.Send(&request.Options{
+		URL:                 serverURL.JoinPath("jobs"),
+		Method:              http.MethodPost,
+		FollowAsyncLocation: true,
+		MaxRetryAfter:       10 * time.Millisecond,
+		Logger:              suite.Logger,
+	}, nil)
+	suite.Require().NoError(err, "Failed to send the request, err=%+v", err)
+	suite.Assert().Less(time.Since(start), 2*time.Second, "Retry-After should be capped by MaxRetryAfter")
+}
+
+func (suite *RequestSuite) TestShouldStopFollowingAsyncLocationWhenContextIsDone() {
+	requests := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Location", "/operations/1") // the operation never ends
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := request.Send(&request.Options{
+		Context:             ctx,
+		URL:                 serverURL.JoinPath("jobs"),
+		Method:              http.MethodPost,
+		FollowAsyncLocation: true,
+		PollInterval:        10 * time.Millisecond,
+		MaxPollInterval:     10 * time.Millisecond,
+		Logger:              suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "Should have failed sending the request")
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded, "Error should be a deadline exceeded, err=%+v", err)
+	suite.Assert().Greater(requests.Load(), int32(2), "The status should have been polled several times")
+}
@@ -1,6 +1,12 @@
 package request
 
-import "encoding/base64"
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gildas/go-errors"
+)
 
 // BasicAuthorization builds a basic authorization string
 func BasicAuthorization(user, password string) string {
@@ -11,3 +17,84 @@ func BasicAuthorization(user, password string) string {
 func BearerAuthorization(token string) string {
 	return "Bearer " + token
 }
+
+// TokenProvider provides the Authorization of requests
+//
+// Send calls it before each attempt, so it can refresh tokens that are about to expire.
+type TokenProvider interface {
+	Authorization(context context.Context) (string, error)
+}
+
+// TokenProviderFunc is a function that implements TokenProvider
+type TokenProviderFunc func(context context.Context) (string, error)
+
+// Authorization provides the Authorization of a request
+//
+// implements TokenProvider
+func (provider TokenProviderFunc) Authorization(context context.Context) (string, error) {
+	return provider(context)
+}
+
+// APIKeyLocation tells where an API Key is placed in a request
+type APIKeyLocation string
+
+const (
+	APIKeyInHeader APIKeyLocation = "header" // the API Key is sent as a header, by default: X-Api-Key
+	APIKeyInQuery  APIKeyLocation = "query"  // the API Key is sent as a query parameter, by default: api_key
+	APIKeyInCookie APIKeyLocation = "cookie" // the API Key is sent as a cookie, by default: api_key
+)
+
+// APIKey describes an API Key and where it is placed in requests
+type APIKey struct {
+	Key  string
+	In   APIKeyLocation
+	Name string
+}
+
+// APIKeyAuthorization builds an API Key to use in Options.APIKey
+//
+// If name is empty, X-Api-Key is used for headers and api_key for query parameters and cookies.
+func APIKeyAuthorization(key string, in APIKeyLocation, name string) *APIKey {
+	if len(name) == 0 {
+		if in == APIKeyInHeader {
+			name = "X-Api-Key"
+		} else {
+			name = "api_key"
+		}
+	}
+	return &APIKey{Key: key, In: in, Name: name}
+}
+
+// apply places the API Key in the request
+func (apiKey APIKey) apply(req *http.Request) error {
+	switch apiKey.In {
+	case APIKeyInHeader:
+		req.Header.Set(apiKey.Name, apiKey.Key)
+	case APIKeyInQuery:
+		query := req.URL.Query()
+		query.Set(apiKey.Name, apiKey.Key)
+		req.URL.RawQuery = query.Encode()
+	case APIKeyInCookie:
+		req.AddCookie(&http.Cookie{Name: apiKey.Name, Value: apiKey.Key})
+	default:
+		return errors.ArgumentInvalid.With("APIKey.In", apiKey.In)
+	}
+	return nil
+}
+
+// Signer signs requests before they are sent
+//
+// Send calls it before each attempt, once all the headers are set. The payload is the body of the request.
+type Signer interface {
+	Sign(req *http.Request, payload []byte) error
+}
+
+// SignerFunc is a function that implements Signer
+type SignerFunc func(req *http.Request, payload []byte) error
+
+// Sign signs the request
+//
+// implements Signer
+func (signer SignerFunc) Sign(req *http.Request, payload []byte) error {
+	return signer(req, payload)
+}
@@ -1,10 +1,22 @@
 package request_test
 
-import "testing"
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
 
-import "github.com/stretchr/testify/assert"
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
-import "github.com/gildas/go-request"
+	"github.com/gildas/go-request"
+)
 
 func TestCanCreateBasicAuthorization(t *testing.T) {
 	expected := "Basic dXNlcjpwYXNzd29yZA=="
@@ -15,3 +27,62 @@ func TestCanCreateTokenAuthorization(t *testing.T) {
 	expected := "Bearer mytoken"
 	assert.Equal(t, expected, request.BearerAuthorization("mytoken"))
 }
+
+func TestCanCreateJWTAuthorization(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	provider := request.JWTAuthorization(map[string]interface{}{"iss": "12345"}, key)
+
+	authorization, err := provider.Authorization(context.Background())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(authorization, "Bearer "), "Authorization should be a Bearer")
+	token := strings.TrimPrefix(authorization, "Bearer ")
+
+	verified, err := request.ContentWithData([]byte(token)).VerifyJWS(&key.PublicKey)
+	require.NoError(t, err, "JWT should be signed with ES256")
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(verified.Data, &claims))
+	assert.Equal(t, "12345", claims["iss"])
+	assert.Equal(t, claims["iat"].(float64)+3600, claims["exp"], "JWT should be valid for an hour by default")
+
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"alg":"ES256","typ":"JWT"}`, string(header))
+
+	again, err := provider.Authorization(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, authorization, again, "JWT should be cached")
+}
+
+func TestCanRefreshJWTBeforeExpiry(t *testing.T) {
+	provider := request.JWTAuthorization(map[string]interface{}{"iss": "12345"}, []byte("my-shared-secret"))
+	provider.Lifetime = 2 * time.Second
+	provider.RefreshBefore = 1500 * time.Millisecond
+
+	first, err := provider.Token()
+	require.NoError(t, err)
+	again, err := provider.Token()
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "JWT should be cached")
+
+	time.Sleep(1 * time.Second) // iat and exp are in seconds, the new JWT must differ
+	refreshed, err := provider.Token()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, refreshed, "JWT should have been minted again")
+}
+
+func TestShouldFailCreatingJWTWithUnsupportedKey(t *testing.T) {
+	_, err := request.JWTAuthorization(nil, "ThisIsASecret").Token()
+	require.Error(t, err, "Should have failed minting a JWT")
+	assert.ErrorIs(t, err, errors.ArgumentInvalid)
+	assert.NotContains(t, err.Error(), "ThisIsASecret", "The error should not contain the key")
+	var details errors.Error
+	require.ErrorAs(t, err, &details)
+	assert.Equal(t, "string", details.Value, "The error should only tell the type of the key")
+}
+
+func TestCanCreateAPIKeyAuthorization(t *testing.T) {
+	assert.Equal(t, &request.APIKey{Key: "key", In: request.APIKeyInHeader, Name: "X-Api-Key"}, request.APIKeyAuthorization("key", request.APIKeyInHeader, ""))
+	assert.Equal(t, &request.APIKey{Key: "key", In: request.APIKeyInQuery, Name: "api_key"}, request.APIKeyAuthorization("key", request.APIKeyInQuery, ""))
+	assert.Equal(t, &request.APIKey{Key: "key", In: request.APIKeyInCookie, Name: "token"}, request.APIKeyAuthorization("key", request.APIKeyInCookie, "token"))
+}
@@ -0,0 +1,205 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// Batch composes several requests into a single multipart/mixed batch request
+//
+// This is the format used by OData ($batch) and Google batch endpoints: each part is an "application/http" request.
+type Batch struct {
+	Requests []*Options
+}
+
+// BatchResult is the result of one request of a Batch
+//
+// Error is set when the request's response status is 400 or more, Content is still populated.
+type BatchResult struct {
+	Content *Content
+	Error   error
+}
+
+// NewBatch instantiates a new Batch with the given requests
+func NewBatch(requests ...*Options) *Batch {
+	return &Batch{Requests: requests}
+}
+
+// Add adds a request to this Batch
+func (batch *Batch) Add(options *Options) *Batch {
+	batch.Requests = append(batch.Requests, options)
+	return batch
+}
+
+// Content builds the multipart/mixed Content of this Batch
+//
+// The URL of each request is computed like Send does (BaseURL, Path, PathParameters, Parameters, QueryValues),
+// its TokenProvider, APIKey, Headers, and Cookies are applied. The requests are not modified.
+func (batch Batch) Content() (*Content, error) {
+	if len(batch.Requests) == 0 {
+		return nil, errors.Empty.With("batch")
+	}
+	log := logger.Create("request", &logger.NilStream{})
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for index, options := range batch.Requests {
+		if options == nil {
+			return nil, errors.ArgumentMissing.With(fmt.Sprintf("requests[%d]", index))
+		}
+		if options.URL == nil && len(options.URLString) == 0 && options.BaseURL == nil {
+			return nil, errors.ArgumentMissing.With(fmt.Sprintf("requests[%d].URL", index))
+		}
+		options = options.Clone() // building the part must not modify the request options
+		if err := normalizeURL(options); err != nil {
+			return nil, err // err is already decorated
+		}
+		if options.Context == nil {
+			options.Context = context.Background()
+		}
+		reqContent, err := buildRequestContent(log, options)
+		if err != nil {
+			return nil, err // err is already decorated
+		}
+		method := options.Method
+		if len(method) == 0 {
+			if reqContent.Length > 0 {
+				method = http.MethodPost
+			} else {
+				method = http.MethodGet
+			}
+		}
+		req, err := batchPartRequest(method, options, reqContent)
+		if err != nil {
+			return nil, err // err is already decorated
+		}
+
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Type", "application/http")
+		partHeader.Set("Content-Transfer-Encoding", "binary")
+		partHeader.Set("Content-ID", "<"+strconv.Itoa(index+1)+">")
+		part, err := writer.CreatePart(partHeader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to create batch part %d", index+1)
+		}
+
+		sb := &bytes.Buffer{}
+		fmt.Fprintf(sb, "%s %s HTTP/1.1\r\n", method, req.URL.RequestURI())
+		if err := req.Header.Write(sb); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sb.WriteString("\r\n")
+		sb.Write(reqContent.Data)
+		if _, err := part.Write(sb.Bytes()); err != nil {
+			return nil, errors.Wrapf(err, "Failed to write batch part %d", index+1)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to create batch data")
+	}
+	return ContentWithData(body.Bytes(), "multipart/mixed; boundary="+writer.Boundary()), nil
+}
+
+// batchPartRequest builds the request of a Batch part, with its authorization, API Key, headers, and cookies
+func batchPartRequest(method string, options *Options, reqContent *Content) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(options.Context, method, options.URL.String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(options.Accept) > 0 {
+		req.Header.Set("Accept", options.Accept)
+	}
+	if options.TokenProvider != nil {
+		authorization, err := options.TokenProvider.Authorization(options.Context)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get the Authorization from the TokenProvider")
+		}
+		req.Header.Set("Authorization", authorization)
+	} else if len(options.Authorization) > 0 {
+		req.Header.Set("Authorization", options.Authorization)
+	}
+	if options.APIKey != nil {
+		if err := options.APIKey.apply(req); err != nil {
+			return nil, err
+		}
+	}
+	if len(reqContent.Type) > 0 {
+		req.Header.Set("Content-Type", reqContent.Type)
+	}
+	if reqContent.Length > 0 {
+		req.Header.Set("Content-Length", strconv.FormatUint(reqContent.Length, 10))
+	}
+	setHeaders(req.Header, options.Headers, options.PreserveHeaderCase)
+	mergeHeader(req.Header, options.Header)
+	for _, cookie := range options.Cookies {
+		req.AddCookie(cookie)
+	}
+	return req, nil
+}
+
+// Send sends this Batch to the batch endpoint given in the options
+//
+// The Batch Content is sent as the Payload and the Method defaults to POST, the options are not modified.
+// The results are given in the same order as the Batch requests.
+func (batch Batch) Send(options *Options) ([]BatchResult, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	batchContent, err := batch.Content()
+	if err != nil {
+		return nil, err // err is already decorated
+	}
+	options = options.Clone()
+	options.Payload = batchContent
+	if len(options.Method) == 0 {
+		options.Method = http.MethodPost
+	}
+	if len(options.Accept) == 0 {
+		options.Accept = "multipart/mixed"
+	}
+	content, err := Send(options, nil)
+	if err != nil {
+		return nil, err
+	}
+	return SplitBatchContent(content)
+}
+
+// SplitBatchContent splits a multipart/mixed batch response into the results of each request
+func SplitBatchContent(content *Content) ([]BatchResult, error) {
+	if content == nil {
+		return nil, errors.ArgumentMissing.With("content")
+	}
+	parts, err := content.Parts()
+	if err != nil {
+		return nil, err // err is already decorated
+	}
+	results := make([]BatchResult, 0, len(parts))
+	for _, part := range parts {
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(part.Data)), nil)
+		if err != nil {
+			results = append(results, BatchResult{Error: errors.WithStack(err)})
+			continue
+		}
+		data, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			results = append(results, BatchResult{Error: errors.WithStack(err)})
+			continue
+		}
+		result := BatchResult{Content: ContentWithData(data, res.Header.Get("Content-Type"), res.Header)}
+		if res.StatusCode >= 400 {
+			result.Error = errors.FromHTTPStatusCode(res.StatusCode)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,83 @@
+package request_test
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanSendBatch() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	batchURL, _ := serverURL.Parse("/batch")
+	resultsURL, _ := serverURL.Parse("/results")
+	missingURL, _ := serverURL.Parse("/missing")
+	results, err := request.NewBatch(
+		&request.Options{URL: resultsURL},
+		&request.Options{URL: missingURL, Method: http.MethodGet},
+	).Send(&request.Options{
+		URL:    batchURL,
+		Logger: suite.Logger,
+	})
+	suite.Require().NoError(err, "Failed sending batch, err=%+v", err)
+	suite.Require().Len(results, 2)
+	suite.Require().NoError(results[0].Error)
+	suite.Require().NotNil(results[0].Content)
+	suite.Assert().Equal("application/json", results[0].Content.Type)
+	code := struct {
+		Code int `json:"code"`
+	}{}
+	suite.Require().NoError(results[0].Content.UnmarshalContentJSON(&code))
+	suite.Assert().Equal(1234, code.Code)
+	suite.Require().Error(results[1].Error)
+	suite.Assert().ErrorIs(results[1].Error, errors.HTTPNotFound)
+}
+
+func (suite *RequestSuite) TestCanBuildBatchContent() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	itemURL, _ := serverURL.Parse("/item?kind=book")
+	content, err := request.NewBatch().Add(&request.Options{
+		URL:     itemURL,
+		Payload: struct{ ID string }{ID: "1234"},
+	}).Content()
+	suite.Require().NoError(err, "Failed building batch, err=%+v", err)
+	suite.Require().True(content.IsMultipart())
+	parts, err := content.Parts()
+	suite.Require().NoError(err, "Failed reading batch parts, err=%+v", err)
+	suite.Require().Len(parts, 1)
+	suite.Assert().Equal("application/http", parts[0].Type)
+	suite.Assert().Equal("<1>", parts[0].Headers.Get("Content-ID"))
+	suite.Assert().Contains(string(parts[0].Data), "POST /item?kind=book HTTP/1.1\r\n")
+	suite.Assert().Contains(string(parts[0].Data), "Content-Type: application/json\r\n")
+	suite.Assert().Contains(string(parts[0].Data), "\r\n\r\n{\"ID\":\"1234\"}")
+}
+
+func (suite *RequestSuite) TestCanBuildBatchContentWithNormalizedParts() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	options := &request.Options{
+		BaseURL:        serverURL,
+		Path:           "/items/{id}",
+		PathParameters: map[string]string{"id": "1234"},
+		Parameters:     map[string]string{"page": "2"},
+		QueryValues:    url.Values{"tag": {"a", "b"}},
+		APIKey:         request.APIKeyAuthorization("secret", request.APIKeyInQuery, ""),
+		Cookies:        []*http.Cookie{{Name: "session", Value: "5678"}},
+		Payload:        struct{ ID string }{ID: "1234"},
+	}
+	content, err := request.NewBatch(options).Content()
+	suite.Require().NoError(err, "Failed building batch, err=%+v", err)
+	parts, err := content.Parts()
+	suite.Require().NoError(err, "Failed reading batch parts, err=%+v", err)
+	suite.Require().Len(parts, 1)
+	suite.Assert().Contains(string(parts[0].Data), "POST /items/1234?api_key=secret&page=2&tag=a&tag=b HTTP/1.1\r\n")
+	suite.Assert().Contains(string(parts[0].Data), "Cookie: session=5678\r\n")
+	suite.Assert().Nil(options.URL, "The options should not be modified")
+	suite.Assert().Empty(options.PayloadType, "The options should not be modified")
+}
+
+func (suite *RequestSuite) TestShouldFailBuildingEmptyBatch() {
+	_, err := request.NewBatch().Content()
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.Empty)
+}
@@ -0,0 +1,144 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gildas/go-logger"
+)
+
+// Builder builds a request step by step before sending it
+//
+//	res, err := request.New().
+//	    Post("https://api.acme.com/users").
+//	    Header("X-Tenant", tenant).
+//	    JSONBody(newUser).
+//	    Expect(http.StatusCreated).
+//	    Into(&user).
+//	    Do(ctx)
+//
+// Each step adds an Option, they are applied in order by Do like SendWith does.
+// So conflicting steps (e.g.: 2 bodies) make Do return an error.
+type Builder struct {
+	options []Option
+}
+
+// New creates a new Builder
+func New() *Builder {
+	return &Builder{}
+}
+
+// With adds Options to the request
+func (builder *Builder) With(options ...Option) *Builder {
+	builder.options = append(builder.options, options...)
+	return builder
+}
+
+// Method sends the request with the given HTTP method to the given URL
+func (builder *Builder) Method(method, rawURL string) *Builder {
+	return builder.With(WithMethod(method), To(rawURL))
+}
+
+// URL sends the request to the given URL
+func (builder *Builder) URL(target *url.URL) *Builder {
+	return builder.With(ToURL(target))
+}
+
+// Get sends a GET request to the given URL
+func (builder *Builder) Get(rawURL string) *Builder {
+	return builder.Method(http.MethodGet, rawURL)
+}
+
+// Head sends a HEAD request to the given URL
+func (builder *Builder) Head(rawURL string) *Builder {
+	return builder.Method(http.MethodHead, rawURL)
+}
+
+// Post sends a POST request to the given URL
+func (builder *Builder) Post(rawURL string) *Builder {
+	return builder.Method(http.MethodPost, rawURL)
+}
+
+// Put sends a PUT request to the given URL
+func (builder *Builder) Put(rawURL string) *Builder {
+	return builder.Method(http.MethodPut, rawURL)
+}
+
+// Patch sends a PATCH request to the given URL
+func (builder *Builder) Patch(rawURL string) *Builder {
+	return builder.Method(http.MethodPatch, rawURL)
+}
+
+// Delete sends a DELETE request to the given URL
+func (builder *Builder) Delete(rawURL string) *Builder {
+	return builder.Method(http.MethodDelete, rawURL)
+}
+
+// Header adds a header to the request
+func (builder *Builder) Header(key, value string) *Builder {
+	return builder.With(WithHeader(key, value))
+}
+
+// Query adds a query parameter to the request, it can be repeated
+func (builder *Builder) Query(key, value string) *Builder {
+	return builder.With(WithQuery(key, value))
+}
+
+// Authorization sends the given Authorization header (e.g.: BearerAuthorization(token))
+func (builder *Builder) Authorization(authorization string) *Builder {
+	return builder.With(WithAuthorization(authorization))
+}
+
+// Body sends the given payload with the given type, see Options.Payload
+func (builder *Builder) Body(payload interface{}, payloadType string) *Builder {
+	return builder.With(WithPayload(payload, payloadType))
+}
+
+// JSONBody sends the given payload as JSON
+func (builder *Builder) JSONBody(payload interface{}) *Builder {
+	return builder.With(WithJSON(payload))
+}
+
+// FormBody sends the given struct or map as an application/x-www-form-urlencoded form
+func (builder *Builder) FormBody(payload interface{}) *Builder {
+	return builder.With(WithForm(payload))
+}
+
+// Retry sends the request up to attempts times, waiting delay between 2 attempts
+func (builder *Builder) Retry(attempts uint, delay time.Duration) *Builder {
+	return builder.With(WithRetry(attempts, delay))
+}
+
+// Timeout gives up on each attempt after the given timeout
+func (builder *Builder) Timeout(timeout time.Duration) *Builder {
+	return builder.With(WithTimeout(timeout))
+}
+
+// Logger logs the request with the given Logger
+func (builder *Builder) Logger(log *logger.Logger) *Builder {
+	return builder.With(WithLogger(log))
+}
+
+// Expect fails the request if the response status is not one of the given statuses, see Options.ExpectStatus
+func (builder *Builder) Expect(statuses ...int) *Builder {
+	return builder.With(Expect(statuses...))
+}
+
+// Options merges the given Options over the ones built so far, see MergeOptions
+func (builder *Builder) Options(options *Options) *Builder {
+	return builder.With(WithOptions(options))
+}
+
+// Into decodes the response into the given results, see Send
+func (builder *Builder) Into(results interface{}) *Builder {
+	return builder.With(Into(results))
+}
+
+// Do sends the request
+//
+// The Builder is not modified, so the same request can be sent again.
+func (builder *Builder) Do(ctx context.Context) (*Content, error) {
+	return SendWith(ctx, builder.options...)
+}
@@ -0,0 +1,48 @@
+package request_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanSendRequestWithBuilder() {
+	content, err := request.New().
+		Post(suite.Server.URL+"/item").
+		Header("X-Tenant", "acme").
+		JSONBody(stuff{"1234"}).
+		Expect(http.StatusOK).
+		Timeout(5 * time.Second).
+		Logger(suite.Logger).
+		Do(context.Background())
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("1234", string(content.Data))
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsWithBuilder() {
+	results := struct {
+		Code int `json:"code"`
+	}{}
+	builder := request.New().Get(suite.Server.URL + "/results").Logger(suite.Logger).Into(&results)
+	_, err := builder.Do(context.Background())
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal(1234, results.Code)
+
+	results.Code = 0
+	_, err = builder.Do(context.Background())
+	suite.Require().NoError(err, "Failed sending request again, err=%+v", err)
+	suite.Assert().Equal(1234, results.Code)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingRequestWithConflictingBuilder() {
+	_, err := request.New().Post(suite.Server.URL + "/item").JSONBody(stuff{"1234"}).FormBody(stuff{"1234"}).Do(context.Background())
+	suite.Assert().ErrorIs(err, errors.DuplicateFound, "2 bodies should conflict")
+
+	_, err = request.New().Get(suite.Server.URL + "/results").Expect(http.StatusCreated).Logger(suite.Logger).Do(context.Background())
+	suite.Assert().ErrorIs(err, request.UnexpectedStatus)
+}
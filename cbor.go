@@ -0,0 +1,373 @@
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// CBORMediaType is the Content Type of CBOR payloads
+const CBORMediaType = "application/cbor"
+
+// CBOREncoder encodes payloads in CBOR (RFC 8949)
+//
+// The payload is mapped like encoding/json does (json tags, json.Marshaler), so the same types can be sent as JSON or CBOR.
+// Map keys are sorted, so the same payload is always encoded the same way.
+// It is registered for application/cbor by default.
+var CBOREncoder Encoder = EncoderFunc(func(payload interface{}) ([]byte, error) {
+	value, err := jsonValue(payload)
+	if err != nil {
+		return nil, err
+	}
+	buffer := &bytes.Buffer{}
+	if err = writeCBOR(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+})
+
+// CBORDecoder decodes CBOR (RFC 8949) into results
+//
+// The results are mapped like encoding/json does (json tags, json.Unmarshaler). Byte strings are decoded as base64 strings (i.e. into []byte),
+// and epoch-based dates (tag 1) as RFC 3339 strings (i.e. into time.Time). The other tags are ignored and their content is decoded.
+//
+// It is not registered by default, use RegisterDecoder(request.CBORMediaType, 1, request.CBORDecoder) to accept CBOR responses.
+var CBORDecoder Decoder = DecoderFunc(func(data []byte, results interface{}) error {
+	reader := &cborReader{data: data}
+	value, err := reader.read()
+	if err != nil {
+		return err
+	}
+	if reader.offset != len(data) {
+		return CBORInvalid.With("trailing bytes", len(data)-reader.offset)
+	}
+	return unmarshalJSONValue(value, results)
+})
+
+// CBOR major types
+const (
+	cborUnsigned byte = iota << 5
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// writeCBOR writes a value decoded by encoding/json (with UseNumber) in CBOR
+func writeCBOR(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buffer.WriteByte(0xf6)
+	case bool:
+		if value {
+			buffer.WriteByte(0xf5)
+		} else {
+			buffer.WriteByte(0xf4)
+		}
+	case json.Number:
+		if integer, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			if integer >= 0 {
+				writeCBORHeader(buffer, cborUnsigned, uint64(integer))
+			} else {
+				writeCBORHeader(buffer, cborNegative, uint64(-1-integer))
+			}
+		} else if unsigned, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+			writeCBORHeader(buffer, cborUnsigned, unsigned)
+		} else if float, err := value.Float64(); err == nil {
+			buffer.WriteByte(0xfb)
+			_ = binary.Write(buffer, binary.BigEndian, math.Float64bits(float))
+		} else {
+			return errors.ArgumentInvalid.With("number", value)
+		}
+	case string:
+		writeCBORHeader(buffer, cborText, uint64(len(value)))
+		buffer.WriteString(value)
+	case []interface{}:
+		writeCBORHeader(buffer, cborArray, uint64(len(value)))
+		for _, item := range value {
+			if err := writeCBOR(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		// shorter keys first, like the deterministic encoding of RFC 8949 for text keys
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		writeCBORHeader(buffer, cborMap, uint64(len(value)))
+		for _, key := range keys {
+			_ = writeCBOR(buffer, key)
+			if err := writeCBOR(buffer, value[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.ArgumentInvalid.With("value", fmt.Sprintf("%T", value))
+	}
+	return nil
+}
+
+// writeCBORHeader writes the major type and its argument in its shortest form
+func writeCBORHeader(buffer *bytes.Buffer, major byte, argument uint64) {
+	switch {
+	case argument < 24:
+		buffer.WriteByte(major | byte(argument))
+	case argument <= math.MaxUint8:
+		buffer.Write([]byte{major | 24, byte(argument)})
+	case argument <= math.MaxUint16:
+		buffer.WriteByte(major | 25)
+		_ = binary.Write(buffer, binary.BigEndian, uint16(argument))
+	case argument <= math.MaxUint32:
+		buffer.WriteByte(major | 26)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(argument))
+	default:
+		buffer.WriteByte(major | 27)
+		_ = binary.Write(buffer, binary.BigEndian, argument)
+	}
+}
+
+// cborMaxDepth is the maximum nesting depth of arrays, maps, and tags a cborReader accepts
+const cborMaxDepth = 1000
+
+// cborReader reads CBOR values as values encoding/json can marshal
+type cborReader struct {
+	data   []byte
+	offset int
+	depth  int
+}
+
+// cborBreak is the value read for the "break" stop code of indefinite length items
+var cborBreak = struct{}{}
+
+func (reader *cborReader) next(length uint64) ([]byte, error) {
+	if length > uint64(len(reader.data)-reader.offset) {
+		return nil, CBORInvalid.With("unexpected end at offset", reader.offset)
+	}
+	chunk := reader.data[reader.offset : reader.offset+int(length)]
+	reader.offset += int(length)
+	return chunk, nil
+}
+
+// header reads the major type, the additional information, and the argument of the next item
+//
+// The additional information is 31 when the item has an indefinite length (or is a break).
+func (reader *cborReader) header() (major byte, info byte, argument uint64, err error) {
+	chunk, err := reader.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = chunk[0]&0xe0, chunk[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		if chunk, err = reader.next(1 << (info - 24)); err != nil {
+			return 0, 0, 0, err
+		}
+		switch len(chunk) {
+		case 1:
+			argument = uint64(chunk[0])
+		case 2:
+			argument = uint64(binary.BigEndian.Uint16(chunk))
+		case 4:
+			argument = uint64(binary.BigEndian.Uint32(chunk))
+		default:
+			argument = binary.BigEndian.Uint64(chunk)
+		}
+		return major, info, argument, nil
+	case info == 31 && major != cborUnsigned && major != cborNegative && major != cborTag:
+		return major, info, 0, nil
+	}
+	return 0, 0, 0, CBORInvalid.With("additional information", info)
+}
+
+func (reader *cborReader) read() (interface{}, error) {
+	value, err := reader.readItem()
+	if err == nil && value == cborBreak {
+		return nil, CBORInvalid.With("unexpected break at offset", reader.offset-1)
+	}
+	return value, err
+}
+
+func (reader *cborReader) readItem() (interface{}, error) {
+	if reader.depth >= cborMaxDepth {
+		return nil, CBORInvalid.With("depth", reader.depth+1)
+	}
+	reader.depth++
+	defer func() { reader.depth-- }()
+	major, info, argument, err := reader.header()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == 31
+	switch major {
+	case cborUnsigned:
+		return argument, nil
+	case cborNegative:
+		if argument > math.MaxInt64 {
+			return nil, CBORInvalid.With("negative integer overflow", argument)
+		}
+		return -1 - int64(argument), nil
+	case cborBytes, cborText:
+		var data []byte
+		if indefinite {
+			if data, err = reader.readChunks(major); err != nil {
+				return nil, err
+			}
+		} else if data, err = reader.next(argument); err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(data), nil
+		}
+		return data, nil
+	case cborArray:
+		if !indefinite && argument > uint64(len(reader.data)-reader.offset) {
+			return nil, CBORInvalid.With("array length", argument)
+		}
+		items := []interface{}{}
+		for i := uint64(0); indefinite || i < argument; i++ {
+			item, err := reader.readItem()
+			if err != nil {
+				return nil, err
+			}
+			if item == cborBreak {
+				if indefinite {
+					break
+				}
+				return nil, CBORInvalid.With("unexpected break at offset", reader.offset-1)
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		if !indefinite && argument > uint64(len(reader.data)-reader.offset) {
+			return nil, CBORInvalid.With("map length", argument)
+		}
+		items := map[string]interface{}{}
+		for i := uint64(0); indefinite || i < argument; i++ {
+			key, err := reader.readItem()
+			if err != nil {
+				return nil, err
+			}
+			if key == cborBreak {
+				if indefinite {
+					break
+				}
+				return nil, CBORInvalid.With("unexpected break at offset", reader.offset-1)
+			}
+			value, err := reader.read()
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := key.(string); ok {
+				items[name] = value
+			} else {
+				items[fmt.Sprint(key)] = value
+			}
+		}
+		return items, nil
+	case cborTag:
+		value, err := reader.read()
+		if err != nil {
+			return nil, err
+		}
+		if argument == 1 { // epoch-based date/time
+			switch epoch := value.(type) {
+			case uint64:
+				return time.Unix(int64(epoch), 0).UTC(), nil
+			case int64:
+				return time.Unix(epoch, 0).UTC(), nil
+			case float64:
+				seconds, fraction := math.Modf(epoch)
+				return time.Unix(int64(seconds), int64(fraction*1e9)).UTC(), nil
+			}
+		}
+		return value, nil
+	default: // cborSimple
+		return reader.readSimple(info, argument)
+	}
+}
+
+// readChunks reads the chunks of an indefinite length byte or text string
+func (reader *cborReader) readChunks(major byte) ([]byte, error) {
+	data := []byte{}
+	for {
+		chunkMajor, info, argument, err := reader.header()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == cborSimple && info == 31 {
+			return data, nil // break
+		}
+		if chunkMajor != major || info == 31 {
+			return nil, CBORInvalid.With("invalid chunk at offset", reader.offset-1)
+		}
+		chunk, err := reader.next(argument)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+	}
+}
+
+// readSimple reads a simple value, a float, or a break
+func (reader *cborReader) readSimple(info byte, argument uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		return float16(uint16(argument)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(argument))), nil
+	case 27:
+		return math.Float64frombits(argument), nil
+	case 31:
+		return cborBreak, nil
+	}
+	return nil, CBORInvalid.With("simple value", argument)
+}
+
+// float16 converts a half-precision float
+func float16(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+package request_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanEncodeCBOR(t *testing.T) {
+	// Examples from RFC 8949, Appendix A
+	for _, test := range []struct {
+		Value    interface{}
+		Expected string
+	}{
+		{0, "00"},
+		{23, "17"},
+		{1000000, "1a000f4240"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{-1000, "3903e7"},
+		{1.5, "fb3ff8000000000000"},
+		{nil, "f6"},
+		{true, "f5"},
+		{"IETF", "6449455446"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]interface{}{"a": 1, "b": []int{2, 3}}, "a26161016162820203"},
+		{map[string]string{"aa": "x", "b": "y"}, "a2616261796261616178"},
+	} {
+		data, err := request.CBOREncoder.Encode(test.Value)
+		require.NoError(t, err, "Failed to encode %v", test.Value)
+		assert.Equal(t, test.Expected, hex.EncodeToString(data), "Failed to encode %v", test.Value)
+	}
+}
+
+func TestCanDecodeCBOR(t *testing.T) {
+	// Examples from RFC 8949, Appendix A
+	for _, test := range []struct {
+		Data     string
+		Expected interface{}
+	}{
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"fa47c35000", 100000.0},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9fff", []interface{}{}},
+		{"bf61610161629f0203ffff", map[string]interface{}{"a": 1.0, "b": []interface{}{2.0, 3.0}}},
+		{"d74401020304", "AQIDBA=="}, // tag 23 (expected base16) of h'01020304'
+		{"5f42010243030405ff", "AQIDBAU="},
+	} {
+		var actual interface{}
+		data, _ := hex.DecodeString(test.Data)
+		require.NoError(t, request.CBORDecoder.Decode(data, &actual), "Failed to decode %s", test.Data)
+		assert.Equal(t, test.Expected, actual, "Failed to decode %s", test.Data)
+	}
+}
+
+func TestCanDecodeCBORDate(t *testing.T) {
+	var results struct {
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	// {"createdAt": 1(1363896240)}
+	data, _ := hex.DecodeString("a169637265617465644174c11a514b67b0")
+	require.NoError(t, request.CBORDecoder.Decode(data, &results), "Failed to decode")
+	assert.Equal(t, time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), results.CreatedAt)
+}
+
+func TestCanEncodeAndDecodeCBOR(t *testing.T) {
+	expected := msgPackStuff{
+		ID:       strings.Repeat("a", 300),
+		Count:    70000,
+		Negative: -200,
+		Big:      math.MaxUint64,
+		Ratio:    3.14,
+		Enabled:  true,
+		Tags:     []string{"one", "two"},
+		Labels:   map[string]string{"env": "test"},
+		Data:     []byte{0x00, 0x01, 0xff},
+	}
+	data, err := request.CBOREncoder.Encode(expected)
+	require.NoError(t, err, "Failed to encode")
+
+	var actual msgPackStuff
+	require.NoError(t, request.CBORDecoder.Decode(data, &actual), "Failed to decode")
+	assert.Equal(t, expected, actual)
+}
+
+func TestShouldFailDecodingInvalidCBOR(t *testing.T) {
+	var results interface{}
+	for _, data := range []string{"1c", "62", "82", "ff", "8201ff", "3bffffffffffffffff", "0101", "5f6161ff", "f818"} {
+		payload, _ := hex.DecodeString(data)
+		err := request.CBORDecoder.Decode(payload, &results)
+		assert.ErrorIs(t, err, request.CBORInvalid, "Decoding %s should have failed", data)
+	}
+}
+
+func (suite *RequestSuite) TestCanSendAndReceiveCBOR() {
+	suite.Require().NoError(request.RegisterDecoder(request.CBORMediaType, 1, request.CBORDecoder))
+	defer request.UnregisterDecoder(request.CBORMediaType)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received stuff
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != request.CBORMediaType || request.CBORDecoder.Decode(body, &received) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := request.CBOREncoder.Encode(stuff{ID: received.ID + "-" + r.Header.Get("Accept")})
+		w.Header().Set("Content-Type", request.CBORMediaType)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	var results stuff
+	_, err := request.Send(&request.Options{
+		URL:         serverURL,
+		Payload:     stuff{ID: "1234"},
+		PayloadType: request.CBORMediaType,
+		Logger:      suite.Logger,
+	}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("1234-application/json, application/cbor", results.ID)
+}
+
+func TestShouldFailDecodingTooDeeplyNestedCBOR(t *testing.T) {
+	var results interface{}
+	for _, prefix := range [][]byte{
+		{0x81},            // [[[...[null]...]]]
+		{0x9f},            // [_ [_ [_ ...null
+		{0xa1, 0x61, 'a'}, // {"a": {"a": ... {"a": null} ... }}
+		{0xc1},            // 1(1(...1(null)...))
+	} {
+		payload := append(bytes.Repeat(prefix, 1000000), 0xf6)
+		err := request.CBORDecoder.Decode(payload, &results)
+		assert.ErrorIs(t, err, request.CBORInvalid, "Decoding %x nested a million times should have failed", prefix)
+	}
+}
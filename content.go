@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gildas/go-core"
 	"github.com/gildas/go-errors"
@@ -26,12 +27,35 @@ type Content struct {
 	Data    []byte         `json:"Data"`
 	Headers http.Header    `json:"headers,omitempty"`
 	Cookies []*http.Cookie `json:"-"`
+
+	StatusCode int     `json:"statusCode,omitempty"` // HTTP status code of the response this Content was read from
+	Proto      string  `json:"proto,omitempty"`      // protocol of the response this Content was read from (e.g.: "HTTP/1.1")
+	Timing     *Timing `json:"timing,omitempty"`     // durations of the phases of the request this Content was read from
+
+	Connection *ConnectionInfo `json:"connection,omitempty"` // connection of the request this Content was read from
+
+	ServerTiming []ServerTimingMetric `json:"serverTiming,omitempty"` // metrics of the Server-Timing header of the response this Content was read from
+
+	Metadata map[string]string `json:"metadata,omitempty"` // caller annotations (correlation ids, provenance, ...), never sent nor read on the wire
+
+	Trailer http.Header `json:"trailer,omitempty"` // trailers of the response this Content was read from
+
+	Encoding string `json:"encoding,omitempty"` // Content-Encoding of the data as it was received (e.g.: "gzip")
+	RawData  []byte `json:"rawData,omitempty"`  // data as it was received, when it was decoded into Data and KeepRawData was given
 }
 
+// keepEncoding tells ContentWithData to keep the data as it is, even if it is compressed
+type keepEncoding bool
+
+// KeepRawData tells ContentWithData to keep the data as it was received in Content.RawData when it decodes it
+type KeepRawData bool
+
 // ContentWithData instantiates a Content from a simple byte array
 func ContentWithData(data []byte, options ...interface{}) *Content {
 	log := logger.Create("REQUEST", &logger.NilStream{})
 	content := &Content{}
+	keep := false
+	keepRaw := false
 	content.Data = data
 	for _, raw := range options {
 		switch option := raw.(type) {
@@ -53,13 +77,21 @@ func ContentWithData(data []byte, options ...interface{}) *Content {
 			content.Headers = option
 		case []*http.Cookie:
 			content.Cookies = option
+		case keepEncoding:
+			keep = bool(option)
+		case KeepRawData:
+			keepRaw = bool(option)
 		}
 	}
-	if content.Headers.Get("Content-Encoding") == "gzip" {
+	content.Encoding = content.Headers.Get("Content-Encoding")
+	if content.Encoding == "gzip" && !keep {
 		log.Tracef("Content is gzipped (%d bytes)", len(content.Data))
 		buffer := bytes.NewBuffer(content.Data)
 		if reader, err := gzip.NewReader(buffer); err == nil {
 			if uncompressed, err := io.ReadAll(reader); err == nil {
+				if keepRaw {
+					content.RawData = content.Data
+				}
 				content.Data = uncompressed
 				content.Length = uint64(len(uncompressed))
 				log.Tracef("Uncompressed data (%d bytes)", content.Length)
@@ -99,14 +131,47 @@ func (content *Content) ReadCloser() io.ReadCloser {
 	return io.NopCloser(bytes.NewReader(content.Data))
 }
 
+// ETag gets the ETag header of the response this Content was read from
+func (content Content) ETag() string {
+	return content.Headers.Get("ETag")
+}
+
+// LastModified gets the Last-Modified header of the response this Content was read from
+//
+// The boolean is false if the header is missing or invalid.
+func (content Content) LastModified() (time.Time, bool) {
+	lastModified, err := http.ParseTime(content.Headers.Get("Last-Modified"))
+	return lastModified, err == nil
+}
+
 // UnmarshalContentJSON unmarshals its Data into JSON
+//
+// UTF-8 BOMs and leading whitespaces are ignored
 func (content Content) UnmarshalContentJSON(v interface{}) (err error) {
-	if err = json.Unmarshal(content.Data, &v); err != nil {
+	if err = json.Unmarshal(jsonData(content.Data), &v); err != nil {
 		return errors.JSONUnmarshalError.WrapIfNotMe(err)
 	}
 	return nil
 }
 
+// utf8BOM is the UTF-8 Byte Order Mark some servers prepend to their payloads
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// jsonData gets the given data without UTF-8 BOM, anti-XSSI prefix, and leading whitespaces
+func jsonData(data []byte) []byte {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	if bytes.HasPrefix(data, []byte(")]}'")) {
+		data = bytes.TrimLeft(data[4:], ", \t\r\n")
+	}
+	return data
+}
+
+// looksLikeJSON tells if the given data is a JSON object or array
+func looksLikeJSON(data []byte) bool {
+	data = jsonData(data)
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[') && json.Valid(data)
+}
+
 // LogString generates a string suitable for logging
 func (content Content) LogString(maxSize uint64) string {
 	sb := strings.Builder{}
@@ -0,0 +1,135 @@
+package request
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"strings"
+)
+
+// MD5 gets the MD5 checksum of the Content's data
+func (content Content) MD5() []byte {
+	checksum := md5.Sum(content.Data)
+	return checksum[:]
+}
+
+// SHA256 gets the SHA-256 checksum of the Content's data
+func (content Content) SHA256() []byte {
+	checksum := sha256.Sum256(content.Data)
+	return checksum[:]
+}
+
+// VerifyChecksum verifies the Content's data against the checksums given in its Headers
+//
+// The supported headers are Content-MD5, x-amz-checksum-crc32, x-amz-checksum-crc32c, x-amz-checksum-sha1, x-amz-checksum-sha256,
+// Digest (RFC 3230), and Content-Digest (RFC 9530). Unknown algorithms are ignored.
+//
+// Note: the checksums are computed over the Data, if the Content was decompressed, they will not match.
+//
+// returns nil if all checksums match or if there is no checksum, ChecksumMismatch otherwise.
+func (content Content) VerifyChecksum() error {
+	verifier := newChecksumVerifier(content.Headers)
+	if verifier == nil {
+		return nil
+	}
+	_, _ = verifier.Write(content.Data)
+	return verifier.Verify()
+}
+
+type checksum struct {
+	header   string
+	expected string // base64 encoded
+	hash     hash.Hash
+}
+
+// checksumVerifier computes the checksums announced in HTTP headers while the data is written to it
+type checksumVerifier struct {
+	checksums []checksum
+}
+
+// newChecksumVerifier instantiates a checksumVerifier for the checksums in the headers
+//
+// returns nil if the headers do not contain any supported checksum
+func newChecksumVerifier(headers http.Header) *checksumVerifier {
+	verifier := checksumVerifier{}
+	add := func(header, algorithm, expected string) {
+		if newHash := checksumHash(algorithm); newHash != nil && len(expected) > 0 {
+			verifier.checksums = append(verifier.checksums, checksum{header: header, expected: expected, hash: newHash()})
+		}
+	}
+	add("Content-MD5", "md5", strings.TrimSpace(headers.Get("Content-MD5")))
+	for _, algorithm := range []string{"crc32", "crc32c", "sha1", "sha256"} {
+		add("x-amz-checksum-"+algorithm, algorithm, strings.TrimSpace(headers.Get("x-amz-checksum-"+algorithm)))
+	}
+	// Digest: sha-256=X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=,md5=...
+	for _, value := range headers.Values("Digest") {
+		for _, digest := range strings.Split(value, ",") {
+			if algorithm, expected, found := strings.Cut(strings.TrimSpace(digest), "="); found {
+				add("Digest", algorithm, expected)
+			}
+		}
+	}
+	// Content-Digest: sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:
+	for _, value := range headers.Values("Content-Digest") {
+		for _, digest := range strings.Split(value, ",") {
+			if algorithm, expected, found := strings.Cut(strings.TrimSpace(digest), "="); found {
+				add("Content-Digest", algorithm, strings.Trim(expected, ":"))
+			}
+		}
+	}
+	if len(verifier.checksums) == 0 {
+		return nil
+	}
+	return &verifier
+}
+
+// checksumHash gets the hash constructor for the algorithm, nil if it is not supported
+func checksumHash(algorithm string) func() hash.Hash {
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		return md5.New
+	case "sha", "sha1", "sha-1":
+		return sha1.New
+	case "sha256", "sha-256":
+		return sha256.New
+	case "sha512", "sha-512":
+		return sha512.New
+	case "crc32":
+		return func() hash.Hash { return crc32.NewIEEE() }
+	case "crc32c":
+		return func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) }
+	}
+	return nil
+}
+
+// Write computes the checksums of the data
+//
+// implements io.Writer
+func (verifier *checksumVerifier) Write(data []byte) (int, error) {
+	for _, checksum := range verifier.checksums {
+		checksum.hash.Write(data)
+	}
+	return len(data), nil
+}
+
+// Verify verifies the computed checksums match the expected ones
+//
+// A nil verifier has nothing to verify.
+func (verifier *checksumVerifier) Verify() error {
+	if verifier == nil {
+		return nil
+	}
+	for _, checksum := range verifier.checksums {
+		expected, err := base64.StdEncoding.DecodeString(checksum.expected)
+		if err != nil || !bytes.Equal(expected, checksum.hash.Sum(nil)) {
+			return ChecksumMismatch.With(checksum.header, checksum.expected)
+		}
+	}
+	return nil
+}
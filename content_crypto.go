@@ -3,22 +3,27 @@ package request
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 
 	"github.com/gildas/go-errors"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 type CryptoAlgorithm uint
 
 const (
-	NONE CryptoAlgorithm = iota
-	AESCTR
+	NONE             CryptoAlgorithm = iota
+	AESCTR                           // Deprecated: uses a zero IV, which is insecure when a key is reused. Use AESCTRIV, AESGCM, or CHACHA20POLY1305 for new data
+	AESGCM                           // authenticated, the random nonce is stored before the encrypted data
+	CHACHA20POLY1305                 // authenticated, the random nonce is stored before the encrypted data
+	AESCTRIV                         // not authenticated, the random IV is stored before the encrypted data
 )
 
 func (algorithm CryptoAlgorithm) String() string {
-	algorithms := [...]string{"NONE", "AESCTR"}
-	if int(algorithm) > len(algorithms) {
+	algorithms := [...]string{"NONE", "AESCTR", "AESGCM", "CHACHA20POLY1305", "AESCTRIV"}
+	if int(algorithm) >= len(algorithms) {
 		return fmt.Sprintf("Unknown %d", algorithm)
 	}
 	return algorithms[algorithm]
@@ -30,6 +35,12 @@ func CryptoAlgorithmFromString(algorithm string) (CryptoAlgorithm, error) {
 		return NONE, nil
 	case "AESCTR":
 		return AESCTR, nil
+	case "AESGCM":
+		return AESGCM, nil
+	case "CHACHA20POLY1305":
+		return CHACHA20POLY1305, nil
+	case "AESCTRIV":
+		return AESCTRIV, nil
 	}
 	return NONE, errors.ArgumentInvalid.With("algorithm", algorithm)
 }
@@ -53,29 +64,34 @@ func (content Content) Decrypt(algorithm CryptoAlgorithm, key []byte) (*Content,
 		return &content, nil
 	case AESCTR:
 		return content.DecryptWithAESCTR(key)
+	case AESGCM:
+		return content.DecryptWithAESGCM(key)
+	case CHACHA20POLY1305:
+		return content.DecryptWithChaCha20Poly1305(key)
+	case AESCTRIV:
+		return content.DecryptWithAESCTRIV(key)
 	}
 	return nil, errors.InvalidType.With(algorithm.String())
 }
 
+// DecryptWithAESCTR decrypts the Content encrypted with AES-CTR and a zero IV
+//
+// Deprecated: a zero IV is insecure when a key is reused, use DecryptWithAESCTRIV for new data.
 func (content Content) DecryptWithAESCTR(key []byte) (*Content, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", key), err)
-	}
+	return content.xorWithAESCTR(key, make([]byte, aes.BlockSize), content.Data, nil)
+}
 
-	decrypted := Content{
-		Type:    content.Type,
-		Name:    content.Name,
-		URL:     content.URL,
-		Headers: content.Headers,
-		Cookies: content.Cookies,
-		Length:  content.Length,
-		Data:    make([]byte, len(content.Data)),
+// DecryptWithAESCTRIV decrypts the Content encrypted with AES-CTR
+//
+// The IV is read from the beginning of the data.
+func (content Content) DecryptWithAESCTRIV(key []byte) (*Content, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", len(key)), err)
 	}
-
-	stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
-	stream.XORKeyStream(decrypted.Data, content.Data)
-	return &decrypted, nil
+	if len(content.Data) < aes.BlockSize {
+		return nil, DecryptionFailed.With(AESCTRIV.String())
+	}
+	return content.xorWithAESCTR(key, content.Data[:aes.BlockSize], content.Data[aes.BlockSize:], nil)
 }
 
 func (content Content) Encrypt(algorithm CryptoAlgorithm, key []byte) (*Content, error) {
@@ -84,27 +100,179 @@ func (content Content) Encrypt(algorithm CryptoAlgorithm, key []byte) (*Content,
 		return &content, nil
 	case AESCTR:
 		return content.EncryptWithAESCTR(key)
+	case AESGCM:
+		return content.EncryptWithAESGCM(key)
+	case CHACHA20POLY1305:
+		return content.EncryptWithChaCha20Poly1305(key)
+	case AESCTRIV:
+		return content.EncryptWithAESCTRIV(key)
 	}
 	return nil, errors.InvalidType.With(algorithm.String())
 }
 
+// EncryptWithAESCTR encrypts the Content with AES-CTR and a zero IV
+//
+// Deprecated: a zero IV is insecure when a key is reused, use EncryptWithAESCTRIV.
 func (content Content) EncryptWithAESCTR(key []byte) (*Content, error) {
+	return content.xorWithAESCTR(key, make([]byte, aes.BlockSize), content.Data, nil)
+}
+
+// EncryptWithAESCTRIV encrypts the Content with AES-CTR and a random IV
+//
+// The IV is stored before the encrypted data.
+func (content Content) EncryptWithAESCTRIV(key []byte) (*Content, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return content.EncryptWithAESCTRAndIV(key, iv)
+}
+
+// EncryptWithAESCTRAndIV encrypts the Content with AES-CTR and the given IV
+//
+// The IV must be 16 bytes long and never be reused with the same key. It is stored before the encrypted data.
+func (content Content) EncryptWithAESCTRAndIV(key, iv []byte) (*Content, error) {
+	if len(iv) != aes.BlockSize {
+		return nil, errors.ArgumentInvalid.With("iv", len(iv))
+	}
+	return content.xorWithAESCTR(key, iv, content.Data, iv)
+}
+
+// xorWithAESCTR encrypts or decrypts the data with AES-CTR, the result is appended to prefix
+func (content Content) xorWithAESCTR(key, iv, data, prefix []byte) (*Content, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", key), err)
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", len(key)), err)
+	}
+
+	result := make([]byte, len(prefix)+len(data))
+	copy(result, prefix)
+	stream := cipher.NewCTR(block, iv)
+	stream.XORKeyStream(result[len(prefix):], data)
+	return &Content{
+		Type:     content.Type,
+		Name:     content.Name,
+		URL:      content.URL,
+		Headers:  content.Headers,
+		Cookies:  content.Cookies,
+		Metadata: content.Metadata,
+		Length:   uint64(len(result)),
+		Data:     result,
+	}, nil
+}
+
+// EncryptWithAESGCM encrypts the Content with AES-GCM
+//
+// The key must be 16, 24, or 32 bytes long. The random nonce is stored before the encrypted data.
+func (content Content) EncryptWithAESGCM(key []byte) (*Content, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
 	}
+	return content.seal(aead)
+}
 
-	encrypted := Content{
-		Type:    content.Type,
-		Name:    content.Name,
-		URL:     content.URL,
-		Headers: content.Headers,
-		Cookies: content.Cookies,
-		Length:  content.Length,
-		Data:    make([]byte, len(content.Data)),
+// DecryptWithAESGCM decrypts the Content encrypted with AES-GCM
+func (content Content) DecryptWithAESGCM(key []byte) (*Content, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
 	}
+	return content.open(aead, AESGCM)
+}
 
-	stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
-	stream.XORKeyStream(encrypted.Data, content.Data)
-	return &encrypted, nil
+// EncryptWithChaCha20Poly1305 encrypts the Content with ChaCha20-Poly1305
+//
+// The key must be 32 bytes long. The random nonce is stored before the encrypted data.
+func (content Content) EncryptWithChaCha20Poly1305(key []byte) (*Content, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", len(key)), err)
+	}
+	return content.seal(aead)
+}
+
+// DecryptWithChaCha20Poly1305 decrypts the Content encrypted with ChaCha20-Poly1305
+func (content Content) DecryptWithChaCha20Poly1305(key []byte) (*Content, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", len(key)), err)
+	}
+	return content.open(aead, CHACHA20POLY1305)
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", len(key)), err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return aead, nil
+}
+
+// seal encrypts the Content with the AEAD, the data is made of the nonce followed by the ciphertext
+func (content Content) seal(aead cipher.AEAD) (*Content, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(content.Data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	data := aead.Seal(nonce, nonce, content.Data, nil)
+	return &Content{
+		Type:     content.Type,
+		Name:     content.Name,
+		URL:      content.URL,
+		Headers:  content.Headers,
+		Cookies:  content.Cookies,
+		Metadata: content.Metadata,
+		Length:   uint64(len(data)),
+		Data:     data,
+	}, nil
+}
+
+// open decrypts the Content sealed with the AEAD
+func (content Content) open(aead cipher.AEAD, algorithm CryptoAlgorithm) (*Content, error) {
+	if len(content.Data) < aead.NonceSize()+aead.Overhead() {
+		return nil, DecryptionFailed.With(algorithm.String())
+	}
+	nonce, ciphertext := content.Data[:aead.NonceSize()], content.Data[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.WrapErrors(DecryptionFailed.With(algorithm.String()), err)
+	}
+	return &Content{
+		Type:     content.Type,
+		Name:     content.Name,
+		URL:      content.URL,
+		Headers:  content.Headers,
+		Cookies:  content.Cookies,
+		Metadata: content.Metadata,
+		Length:   uint64(len(data)),
+		Data:     data,
+	}, nil
+}
+
+// ContentEncryptionHeader is the HTTP header that tells which CryptoAlgorithm encrypted the body
+const ContentEncryptionHeader = "X-Content-Encryption"
+
+// Encryption describes how a Content is encrypted
+type Encryption struct {
+	Algorithm CryptoAlgorithm
+	Key       []byte
+}
+
+// decryptResponse decrypts a response Content
+//
+// The algorithm is read from the ContentEncryptionHeader of the response, the Encryption's Algorithm is used if it is missing.
+func decryptResponse(encryption *Encryption, content *Content) (*Content, error) {
+	algorithm := encryption.Algorithm
+	if value := content.Headers.Get(ContentEncryptionHeader); len(value) > 0 {
+		var err error
+		if algorithm, err = CryptoAlgorithmFromString(value); err != nil {
+			return nil, err
+		}
+	}
+	return content.Decrypt(algorithm, encryption.Key)
 }
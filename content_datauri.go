@@ -0,0 +1,60 @@
+package request
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// DataURI gets the Data URI of this Content (RFC 2397)
+//
+// The data is always base64 encoded. If the Content has no Type, "application/octet-stream" is used.
+func (content Content) DataURI() string {
+	contentType := content.Type
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	sb := strings.Builder{}
+	sb.WriteString("data:")
+	sb.WriteString(contentType)
+	sb.WriteString(";base64,")
+	sb.WriteString(base64.StdEncoding.EncodeToString(content.Data))
+	return sb.String()
+}
+
+// ContentFromDataURI instantiates a Content from a Data URI (RFC 2397)
+//
+// If the Data URI does not mention a media type, "text/plain;charset=US-ASCII" is used.
+func ContentFromDataURI(dataURI string) (*Content, error) {
+	if !strings.HasPrefix(dataURI, "data:") {
+		return nil, errors.ArgumentInvalid.With("dataURI", dataURI)
+	}
+	header, payload, found := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	if !found {
+		return nil, errors.ArgumentInvalid.With("dataURI", dataURI)
+	}
+
+	var data []byte
+	var err error
+
+	if mediaType, found := strings.CutSuffix(header, ";base64"); found {
+		header = mediaType
+		if data, err = base64.StdEncoding.DecodeString(payload); err != nil {
+			return nil, errors.WrapErrors(errors.ArgumentInvalid.With("dataURI", dataURI), err)
+		}
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, errors.WrapErrors(errors.ArgumentInvalid.With("dataURI", dataURI), err)
+		}
+		data = []byte(unescaped)
+	}
+	if len(header) == 0 {
+		header = "text/plain;charset=US-ASCII"
+	} else if strings.HasPrefix(header, ";") {
+		header = "text/plain" + header
+	}
+	return ContentWithData(data, header), nil
+}
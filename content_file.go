@@ -0,0 +1,45 @@
+package request
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gildas/go-errors"
+)
+
+// ContentFromFile instantiates a Content from a file
+//
+// The Content's Name is the file's base name and its Type is given by the file's extension,
+// or sniffed from the first 512 bytes of the file when the extension is unknown.
+func ContentFromFile(path string) (*Content, error) {
+	if len(path) == 0 {
+		return nil, errors.ArgumentMissing.With("path")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound.With("file", path)
+		}
+		return nil, errors.WithStack(err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if len(contentType) == 0 {
+		contentType = http.DetectContentType(data)
+	}
+	content := ContentWithData(data, contentType)
+	content.Name = filepath.Base(path)
+	return content, nil
+}
+
+// WriteToFile writes the Content's data to the given file with the given permissions
+//
+// The data is written to a temporary file in the same directory, which is then renamed,
+// so the file is either fully written or left untouched.
+func (content Content) WriteToFile(path string, perm os.FileMode) error {
+	return writeFileAtomically(path, perm, func(file *os.File) error {
+		_, err := file.Write(content.Data)
+		return errors.WithStack(err)
+	})
+}
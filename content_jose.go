@@ -0,0 +1,418 @@
+package request
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// JOSE algorithms supported by SignJWS, VerifyJWS, EncryptJWE, and DecryptJWE
+const (
+	JWSHS256      = "HS256"        // HMAC with SHA-256, the key is a []byte
+	JWSRS256      = "RS256"        // RSASSA-PKCS1-v1_5 with SHA-256, the key is an *rsa.PrivateKey (sign) or *rsa.PublicKey (verify)
+	JWSES256      = "ES256"        // ECDSA P-256 with SHA-256, the key is an *ecdsa.PrivateKey (sign) or *ecdsa.PublicKey (verify)
+	JWERSAOAEP    = "RSA-OAEP"     // RSAES OAEP with SHA-1, the key is an *rsa.PublicKey (encrypt) or *rsa.PrivateKey (decrypt)
+	JWERSAOAEP256 = "RSA-OAEP-256" // RSAES OAEP with SHA-256, the key is an *rsa.PublicKey (encrypt) or *rsa.PrivateKey (decrypt)
+	JWEECDHES     = "ECDH-ES"      // ECDH Ephemeral Static key agreement, the key is an *ecdsa.PublicKey (encrypt) or *ecdsa.PrivateKey (decrypt)
+	JWEA256GCM    = "A256GCM"      // AES-GCM with a 256-bit key, the content encryption of EncryptJWE
+)
+
+// JOSEMediaType is the Content Type of JWS and JWE in compact serialization
+const JOSEMediaType = "application/jose"
+
+// joseHeader is the protected header of a JWS or a JWE
+type joseHeader struct {
+	Algorithm    string   `json:"alg"`
+	Type         string   `json:"typ,omitempty"`
+	Encryption   string   `json:"enc,omitempty"`
+	ContentType  string   `json:"cty,omitempty"`
+	EphemeralKey *joseJWK `json:"epk,omitempty"`
+}
+
+// joseJWK is the JSON Web Key of an ephemeral EC public key
+type joseJWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+var joseEncoding = base64.RawURLEncoding
+
+// SignJWS signs the Content as a JWS in compact serialization
+//
+// The Content Type is stored in the "cty" header and restored by VerifyJWS.
+func (content Content) SignJWS(algorithm string, key interface{}) (*Content, error) {
+	header, err := json.Marshal(joseHeader{Algorithm: algorithm, ContentType: content.Type})
+	if err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	input := joseEncoding.EncodeToString(header) + "." + joseEncoding.EncodeToString(content.Data)
+	signature, err := joseSign(algorithm, key, []byte(input))
+	if err != nil {
+		return nil, err
+	}
+	return content.joseContent([]byte(input + "." + joseEncoding.EncodeToString(signature))), nil
+}
+
+// VerifyJWS verifies the JWS in compact serialization of the Content and returns its payload
+//
+// The algorithm of the JWS must match the type of the key, "none" is never accepted.
+func (content Content) VerifyJWS(key interface{}) (*Content, error) {
+	segments := strings.Split(string(content.Data), ".")
+	if len(segments) != 3 {
+		return nil, errors.ArgumentInvalid.With("content", "not a JWS compact serialization")
+	}
+	header, err := decodeJOSEHeader(segments[0])
+	if err != nil {
+		return nil, err
+	}
+	signature, err := joseEncoding.DecodeString(segments[2])
+	if err != nil {
+		return nil, errors.WrapErrors(JWSSignatureInvalid.With(header.Algorithm), err)
+	}
+	if err = joseVerify(header.Algorithm, key, []byte(segments[0]+"."+segments[1]), signature); err != nil {
+		return nil, err
+	}
+	payload, err := joseEncoding.DecodeString(segments[1])
+	if err != nil {
+		return nil, errors.ArgumentInvalid.With("payload", segments[1])
+	}
+	return content.payloadContent(header.ContentType, payload), nil
+}
+
+// EncryptJWE encrypts the Content as a JWE in compact serialization
+//
+// The content is encrypted with A256GCM and the content encryption key is managed with the given algorithm.
+// The Content Type is stored in the "cty" header and restored by DecryptJWE.
+func (content Content) EncryptJWE(algorithm string, key interface{}) (*Content, error) {
+	header := joseHeader{Algorithm: algorithm, Encryption: JWEA256GCM, ContentType: content.Type}
+	var cek, encryptedKey []byte
+
+	switch algorithm {
+	case JWERSAOAEP, JWERSAOAEP256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		cek = make([]byte, 32)
+		if _, err := rand.Read(cek); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var err error
+		if encryptedKey, err = rsa.EncryptOAEP(joseOAEPHash(algorithm), rand.Reader, publicKey, cek, nil); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case JWEECDHES:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		recipient, err := publicKey.ECDH()
+		if err != nil {
+			return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key)), err)
+		}
+		ephemeral, err := recipient.Curve().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		shared, err := ephemeral.ECDH(recipient)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		header.EphemeralKey = newJOSEJWK(publicKey.Curve, ephemeral.PublicKey())
+		cek = joseConcatKDF(shared, JWEA256GCM, 256)
+	default:
+		return nil, errors.ArgumentInvalid.With("algorithm", algorithm)
+	}
+
+	protected, err := json.Marshal(header)
+	if err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	encodedHeader := joseEncoding.EncodeToString(protected)
+	aead, err := newAESGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sealed := aead.Seal(nil, iv, content.Data, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return content.joseContent([]byte(strings.Join([]string{
+		encodedHeader,
+		joseEncoding.EncodeToString(encryptedKey),
+		joseEncoding.EncodeToString(iv),
+		joseEncoding.EncodeToString(ciphertext),
+		joseEncoding.EncodeToString(tag),
+	}, "."))), nil
+}
+
+// DecryptJWE decrypts the JWE in compact serialization of the Content
+func (content Content) DecryptJWE(key interface{}) (*Content, error) {
+	segments := strings.Split(string(content.Data), ".")
+	if len(segments) != 5 {
+		return nil, errors.ArgumentInvalid.With("content", "not a JWE compact serialization")
+	}
+	header, err := decodeJOSEHeader(segments[0])
+	if err != nil {
+		return nil, err
+	}
+	if header.Encryption != JWEA256GCM {
+		return nil, errors.ArgumentInvalid.With("enc", header.Encryption)
+	}
+	var cek []byte
+
+	switch header.Algorithm {
+	case JWERSAOAEP, JWERSAOAEP256:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		encryptedKey, err := joseEncoding.DecodeString(segments[1])
+		if err != nil {
+			return nil, errors.WrapErrors(DecryptionFailed.With(header.Algorithm), err)
+		}
+		if cek, err = rsa.DecryptOAEP(joseOAEPHash(header.Algorithm), nil, privateKey, encryptedKey, nil); err != nil {
+			return nil, errors.WrapErrors(DecryptionFailed.With(header.Algorithm), err)
+		}
+	case JWEECDHES:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		recipient, err := privateKey.ECDH()
+		if err != nil {
+			return nil, errors.WrapErrors(errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key)), err)
+		}
+		if header.EphemeralKey == nil {
+			return nil, errors.ArgumentMissing.With("epk")
+		}
+		ephemeral, err := header.EphemeralKey.publicKey(recipient.Curve())
+		if err != nil {
+			return nil, err
+		}
+		shared, err := recipient.ECDH(ephemeral)
+		if err != nil {
+			return nil, errors.WrapErrors(DecryptionFailed.With(header.Algorithm), err)
+		}
+		cek = joseConcatKDF(shared, header.Encryption, 256)
+	default:
+		return nil, errors.ArgumentInvalid.With("alg", header.Algorithm)
+	}
+
+	aead, err := newAESGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := joseEncoding.DecodeString(segments[2])
+	if err != nil || len(iv) != aead.NonceSize() {
+		return nil, DecryptionFailed.With(header.Encryption)
+	}
+	ciphertext, err := joseEncoding.DecodeString(segments[3])
+	if err != nil {
+		return nil, DecryptionFailed.With(header.Encryption)
+	}
+	tag, err := joseEncoding.DecodeString(segments[4])
+	if err != nil {
+		return nil, DecryptionFailed.With(header.Encryption)
+	}
+	data, err := aead.Open(nil, iv, append(ciphertext, tag...), []byte(segments[0]))
+	if err != nil {
+		return nil, errors.WrapErrors(DecryptionFailed.With(header.Encryption), err)
+	}
+	return content.payloadContent(header.ContentType, data), nil
+}
+
+// joseContent creates the Content holding a JOSE compact serialization of this Content
+func (content Content) joseContent(data []byte) *Content {
+	return &Content{
+		Type:     JOSEMediaType,
+		Name:     content.Name,
+		URL:      content.URL,
+		Headers:  content.Headers,
+		Cookies:  content.Cookies,
+		Metadata: content.Metadata,
+		Length:   uint64(len(data)),
+		Data:     data,
+	}
+}
+
+// payloadContent creates the Content holding the payload of a JOSE compact serialization
+func (content Content) payloadContent(contentType string, data []byte) *Content {
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	return &Content{
+		Type:     contentType,
+		Name:     content.Name,
+		URL:      content.URL,
+		Headers:  content.Headers,
+		Cookies:  content.Cookies,
+		Metadata: content.Metadata,
+		Length:   uint64(len(data)),
+		Data:     data,
+	}
+}
+
+func decodeJOSEHeader(segment string) (*joseHeader, error) {
+	payload, err := joseEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, errors.ArgumentInvalid.With("header", segment)
+	}
+	var header joseHeader
+	if err = json.Unmarshal(payload, &header); err != nil {
+		return nil, errors.JSONUnmarshalError.Wrap(err)
+	}
+	return &header, nil
+}
+
+func joseSign(algorithm string, key interface{}, input []byte) ([]byte, error) {
+	switch algorithm {
+	case JWSHS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case JWSRS256:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		digest := sha256.Sum256(input)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+		return signature, errors.WithStack(err)
+	case JWSES256:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || privateKey.Curve != elliptic.P256() {
+			return nil, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	}
+	return nil, errors.ArgumentInvalid.With("algorithm", algorithm)
+}
+
+func joseVerify(algorithm string, key interface{}, input, signature []byte) error {
+	switch algorithm {
+	case JWSHS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return JWSSignatureInvalid.With(algorithm)
+		}
+		return nil
+	case JWSRS256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		digest := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.WrapErrors(JWSSignatureInvalid.With(algorithm), err)
+		}
+		return nil
+	case JWSES256:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || publicKey.Curve != elliptic.P256() {
+			return errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", key))
+		}
+		if len(signature) != 64 {
+			return JWSSignatureInvalid.With(algorithm)
+		}
+		digest := sha256.Sum256(input)
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return JWSSignatureInvalid.With(algorithm)
+		}
+		return nil
+	}
+	return errors.ArgumentInvalid.With("alg", algorithm)
+}
+
+func joseOAEPHash(algorithm string) hash.Hash {
+	if algorithm == JWERSAOAEP {
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// joseConcatKDF derives a key from the ECDH shared secret (RFC 7518, §4.6.2)
+func joseConcatKDF(shared []byte, algorithmID string, keyBits uint32) []byte {
+	info := make([]byte, 0, 4+len(algorithmID)+4+4+4)
+	info = binary.BigEndian.AppendUint32(info, uint32(len(algorithmID)))
+	info = append(info, algorithmID...)
+	info = binary.BigEndian.AppendUint32(info, 0) // PartyUInfo
+	info = binary.BigEndian.AppendUint32(info, 0) // PartyVInfo
+	info = binary.BigEndian.AppendUint32(info, keyBits)
+
+	key := make([]byte, 0, keyBits/8)
+	for counter := uint32(1); len(key) < int(keyBits/8); counter++ {
+		digest := sha256.New()
+		_ = binary.Write(digest, binary.BigEndian, counter)
+		digest.Write(shared)
+		digest.Write(info)
+		key = digest.Sum(key)
+	}
+	return key[:keyBits/8]
+}
+
+func newJOSEJWK(curve elliptic.Curve, publicKey *ecdh.PublicKey) *joseJWK {
+	point := publicKey.Bytes() // uncompressed: 0x04 || X || Y
+	size := (len(point) - 1) / 2
+	return &joseJWK{
+		KeyType: "EC",
+		Curve:   curve.Params().Name,
+		X:       joseEncoding.EncodeToString(point[1 : 1+size]),
+		Y:       joseEncoding.EncodeToString(point[1+size:]),
+	}
+}
+
+func (jwk joseJWK) publicKey(curve ecdh.Curve) (*ecdh.PublicKey, error) {
+	x, err := joseEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, errors.ArgumentInvalid.With("epk.x", jwk.X)
+	}
+	y, err := joseEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, errors.ArgumentInvalid.With("epk.y", jwk.Y)
+	}
+	point := append(append([]byte{4}, x...), y...)
+	publicKey, err := curve.NewPublicKey(point)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.ArgumentInvalid.With("epk", jwk), err)
+	}
+	return publicKey, nil
+}
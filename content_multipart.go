@@ -0,0 +1,61 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// IsMultipart tells if this Content is a multipart Content (multipart/mixed, multipart/related, etc)
+func (content Content) IsMultipart() bool {
+	mediaType, _, err := mime.ParseMediaType(content.Type)
+	return err == nil && strings.HasPrefix(mediaType, "multipart/")
+}
+
+// Parts gets the parts of a multipart Content
+//
+// Each part is returned as a Content with its own Type, Headers, and Data.
+// The Name of each part is its file name or its form name, if any.
+func (content Content) Parts() ([]Content, error) {
+	mediaType, params, err := mime.ParseMediaType(content.Type)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.InvalidType.With(content.Type, "multipart/*"), err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, errors.InvalidType.With(content.Type, "multipart/*")
+	}
+	boundary, found := params["boundary"]
+	if !found || len(boundary) == 0 {
+		return nil, errors.ArgumentMissing.With("boundary")
+	}
+
+	parts := []Content{}
+	reader := multipart.NewReader(bytes.NewReader(content.Data), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		partContent := ContentWithData(data, part.Header.Get("Content-Type"), http.Header(part.Header))
+		if name := part.FileName(); len(name) > 0 {
+			partContent.Name = name
+		} else {
+			partContent.Name = part.FormName()
+		}
+		parts = append(parts, *partContent)
+		part.Close()
+	}
+	return parts, nil
+}
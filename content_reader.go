@@ -0,0 +1,106 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gildas/go-errors"
+)
+
+// ContentReader streams the body of a response
+//
+// When a *ContentReader is given as the results of Send, it wraps the live response body
+// instead of reading it in memory. The caller must Close it.
+//
+// Since the body is read after Send returns, Options.Timeout also covers the time spent reading it.
+type ContentReader struct {
+	Type       string         // mime type of the body
+	Name       string         // name of the body, if known
+	URL        *url.URL       // URL the body was read from
+	Length     int64          // length of the body, -1 if unknown
+	Headers    http.Header    // headers of the response
+	Cookies    []*http.Cookie // cookies of the response
+	Trailer    http.Header    // trailers of the response, their values are set once the body is read completely
+	StatusCode int            // HTTP status code of the response
+	Proto      string         // protocol of the response (e.g.: "HTTP/1.1")
+
+	reader io.Reader
+	closer io.Closer
+	verify func() error
+}
+
+// Read reads the body
+//
+// implements io.Reader
+func (reader *ContentReader) Read(data []byte) (int, error) {
+	if reader.reader == nil {
+		return 0, io.EOF
+	}
+	read, err := reader.reader.Read(data)
+	if err == io.EOF && reader.verify != nil {
+		verify := reader.verify
+		reader.verify = nil
+		if verr := verify(); verr != nil {
+			return read, verr
+		}
+	}
+	return read, err
+}
+
+// Close closes the body
+//
+// implements io.Closer
+func (reader *ContentReader) Close() error {
+	if reader.closer == nil {
+		return nil
+	}
+	closer := reader.closer
+	reader.closer = nil
+	return closer.Close()
+}
+
+// ReadContent reads the rest of the body in a Content and closes the ContentReader
+func (reader *ContentReader) ReadContent() (*Content, error) {
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	content := ContentWithData(data, reader.Type, reader.URL, reader.Headers, reader.Cookies)
+	content.Name = reader.Name
+	content.Trailer = reader.Trailer
+	content.StatusCode = reader.StatusCode
+	content.Proto = reader.Proto
+	return content, nil
+}
+
+// ContentReader gets a ContentReader that streams the Data of this Content
+func (content *Content) ContentReader() *ContentReader {
+	return &ContentReader{
+		Type:       content.Type,
+		Name:       content.Name,
+		URL:        content.URL,
+		Length:     int64(len(content.Data)),
+		Headers:    content.Headers,
+		Cookies:    content.Cookies,
+		Trailer:    content.Trailer,
+		StatusCode: content.StatusCode,
+		Proto:      content.Proto,
+		reader:     bytes.NewReader(content.Data),
+	}
+}
+
+// setBody sets the body of the ContentReader
+func (reader *ContentReader) setBody(body io.Reader, closer io.Closer, verify func() error) {
+	reader.reader = body
+	reader.closer = closer
+	reader.verify = verify
+}
+
+// isContentReader tells if the results of Send should be streamed in a ContentReader
+func isContentReader(results interface{}) bool {
+	_, ok := results.(*ContentReader)
+	return ok
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// RedactedValue is the value that replaces redacted header and cookie values
+const RedactedValue = "REDACTED"
+
+// DefaultRedactedHeaders are the headers redacted by Content.Redact when RedactOptions.Headers is empty
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// RedactOptions defines how a Content is redacted before being persisted (logs, queues, caches, etc)
+type RedactOptions struct {
+	MaxDataSize int      // how many bytes of Data and RawData are kept (0 => all of them, <0 => none)
+	Headers     []string // headers and trailers whose values are redacted, by default: DefaultRedactedHeaders, "*" redacts all headers
+	Cookies     bool     // if true, the cookie values are redacted
+}
+
+// Redact gets a copy of this Content with its Data and RawData truncated and its sensitive headers, trailers, and cookies redacted
+//
+// The Length of the copy is the Length of the original Content, so it is possible to know if Data was truncated.
+// The original Content is not modified.
+//
+// Example:
+//
+//	payload, err := json.Marshal(content.Redact(request.RedactOptions{MaxDataSize: 256, Cookies: true}))
+func (content Content) Redact(options RedactOptions) Content {
+	redacted := content
+	if options.MaxDataSize < 0 {
+		redacted.Data = nil
+		redacted.RawData = nil
+	} else if options.MaxDataSize > 0 {
+		if len(content.Data) > options.MaxDataSize {
+			redacted.Data = content.Data[:options.MaxDataSize]
+		}
+		if len(content.RawData) > options.MaxDataSize {
+			redacted.RawData = content.RawData[:options.MaxDataSize]
+		}
+	}
+
+	headers := options.Headers
+	if len(headers) == 0 {
+		headers = DefaultRedactedHeaders
+	}
+	redacted.Headers = redactHeader(content.Headers, headers)
+	redacted.Trailer = redactHeader(content.Trailer, headers)
+
+	if options.Cookies && len(content.Cookies) > 0 {
+		redacted.Cookies = make([]*http.Cookie, len(content.Cookies))
+		for i, cookie := range content.Cookies {
+			redactedCookie := *cookie
+			redactedCookie.Value = RedactedValue
+			redacted.Cookies[i] = &redactedCookie
+		}
+	}
+	return redacted
+}
+
+// redactHeader gets a copy of the given header with the values of the given headers redacted
+func redactHeader(header http.Header, headers []string) http.Header {
+	if header == nil {
+		return nil
+	}
+	redacted := http.Header{}
+	for key, values := range header {
+		if shouldRedactHeader(key, headers) {
+			redacted[key] = []string{RedactedValue}
+		} else {
+			redacted[key] = append([]string{}, values...)
+		}
+	}
+	return redacted
+}
+
+func shouldRedactHeader(key string, headers []string) bool {
+	for _, header := range headers {
+		if header == "*" || strings.EqualFold(textproto.CanonicalMIMEHeaderKey(header), textproto.CanonicalMIMEHeaderKey(key)) {
+			return true
+		}
+	}
+	return false
+}
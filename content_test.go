@@ -2,12 +2,20 @@ package request_test
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"reflect"
 	"strings"
@@ -216,6 +224,26 @@ func (suite *ContentSuite) TestCanUnmarshallData() {
 	suite.Assert().Equal(data.ID, value.ID)
 }
 
+func (suite *ContentSuite) TestCanUnmarshallDataWithBOM() {
+	content := request.ContentWithData([]byte("\xEF\xBB\xBF \r\n{\"ID\": \"12345\"}"), "application/json")
+	suite.Require().NotNil(content, "Content should not be nil")
+
+	value := stuff{}
+	err := content.UnmarshalContentJSON(&value)
+	suite.Require().NoErrorf(err, "Content failed unmarshaling, err=%+v", err)
+	suite.Assert().Equal("12345", value.ID)
+}
+
+func (suite *ContentSuite) TestCanUnmarshallDataWithXSSIPrefix() {
+	content := request.ContentWithData([]byte(")]}'\n{\"ID\": \"12345\"}"), "application/json")
+	suite.Require().NotNil(content, "Content should not be nil")
+
+	value := stuff{}
+	err := content.UnmarshalContentJSON(&value)
+	suite.Require().NoErrorf(err, "Content failed unmarshaling, err=%+v", err)
+	suite.Assert().Equal("12345", value.ID)
+}
+
 func (suite *ContentSuite) TestShouldFailUnmarshallContentWithBogusData() {
 	content := request.ContentWithData([]byte(`{"ID": 1234}`), "application/json")
 	data := stuff{}
@@ -400,6 +428,77 @@ func (suite *ContentSuite) TestShouldFailUnmarshallWithBogusData() {
 	suite.Assert().ErrorIs(err, errors.JSONUnmarshalError, "Error should be a JSON Unmarshal Error")
 }
 
+func (suite *ContentSuite) TestCanMarshalAndUnmarshalMetadata() {
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+	content.Metadata = map[string]string{"correlation-id": "1234", "source": "queue"}
+
+	payload, err := json.Marshal(content)
+	suite.Require().NoErrorf(err, "Failed to marshal content, error: %s", err)
+	suite.Assert().Contains(string(payload), `"metadata":{"correlation-id":"1234","source":"queue"}`)
+
+	unmarshaled := request.Content{}
+	err = json.Unmarshal(payload, &unmarshaled)
+	suite.Require().NoErrorf(err, "Failed to unmarshal content, error: %s", err)
+	suite.Assert().Equal(content.Metadata, unmarshaled.Metadata)
+	suite.Assert().Equal("Hello, World!", string(unmarshaled.Data))
+}
+
+func (suite *ContentSuite) TestShouldKeepMetadataWhenEncrypting() {
+	key, _ := hex.DecodeString("FE400803E0BA6A4B3D611305C1A2EDE263A4D599C96F2BA49C837FD4E193C76D")
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+	content.Metadata = map[string]string{"correlation-id": "1234"}
+
+	encrypted, err := content.Encrypt(request.AESGCM, key)
+	suite.Require().NoError(err, "Failed to encrypt content")
+	suite.Assert().Equal("1234", encrypted.Metadata["correlation-id"])
+
+	decrypted, err := encrypted.Decrypt(request.AESGCM, key)
+	suite.Require().NoError(err, "Failed to decrypt content")
+	suite.Assert().Equal("1234", decrypted.Metadata["correlation-id"])
+}
+
+func (suite *ContentSuite) TestCanConvertContentToContentReader() {
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+	content.Name = "hello.txt"
+	reader := content.ContentReader()
+	suite.Require().NotNil(reader, "ContentReader should not be nil")
+	suite.Assert().Equal("text/plain", reader.Type)
+	suite.Assert().Equal("hello.txt", reader.Name)
+	suite.Assert().Equal(int64(13), reader.Length)
+
+	converted, err := reader.ReadContent()
+	suite.Require().NoError(err, "Failed to read the ContentReader, err=%+v", err)
+	suite.Assert().Equal(content.Data, converted.Data)
+	suite.Assert().Equal("text/plain", converted.Type)
+	suite.Assert().Equal("hello.txt", converted.Name)
+	suite.Assert().Equal(uint64(13), converted.Length)
+}
+
+func (suite *ContentSuite) TestCanKeepRawDataOfGzippedContent() {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	_, _ = writer.Write([]byte("Hello, World!"))
+	suite.Require().NoError(writer.Close())
+	compressed := buffer.Bytes()
+	header := http.Header{}
+	header.Set("Content-Encoding", "gzip")
+
+	content := request.ContentWithData(compressed, "text/plain", header, request.KeepRawData(true))
+	suite.Assert().Equal("Hello, World!", string(content.Data))
+	suite.Assert().Equal(uint64(13), content.Length)
+	suite.Assert().Equal("gzip", content.Encoding)
+	suite.Assert().Equal(compressed, content.RawData, "RawData should contain the compressed bytes")
+
+	content = request.ContentWithData(compressed, "text/plain", header)
+	suite.Assert().Equal("Hello, World!", string(content.Data))
+	suite.Assert().Equal("gzip", content.Encoding)
+	suite.Assert().Nil(content.RawData, "RawData should be nil when it was not asked for")
+
+	content = request.ContentWithData([]byte("Hello, World!"), "text/plain")
+	suite.Assert().Empty(content.Encoding)
+	suite.Assert().Nil(content.RawData, "RawData should be nil when the data was not decoded")
+}
+
 func (suite *ContentSuite) TestCanMarshalCryptoAlgorithm() {
 	algorithm := request.NONE
 	payload, err := json.Marshal(algorithm)
@@ -517,6 +616,52 @@ func (suite *ContentSuite) TestCanEncryptWithAESCTR() {
 	suite.Assert().Equal(encrypted, encryptedContent.Data, "Encrypted content is incorrect")
 }
 
+func (suite *ContentSuite) TestCanEncryptWithAESCTRIV() {
+	key, _ := hex.DecodeString("FE400803E0BA6A4B3D611305C1A2EDE263A4D599C96F2BA49C837FD4E193C76D")
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+
+	suite.Assert().Equal("AESCTRIV", request.AESCTRIV.String())
+	encrypted, err := content.Encrypt(request.AESCTRIV, key)
+	suite.Require().NoError(err, "Failed to encrypt content")
+	suite.Assert().Len(encrypted.Data, 16+13, "Encrypted data should contain the IV and the ciphertext")
+	suite.Assert().Equal(uint64(16+13), encrypted.Length)
+
+	again, err := content.Encrypt(request.AESCTRIV, key)
+	suite.Require().NoError(err, "Failed to encrypt content")
+	suite.Assert().NotEqual(encrypted.Data, again.Data, "IVs should be random")
+
+	decrypted, err := encrypted.Decrypt(request.AESCTRIV, key)
+	suite.Require().NoError(err, "Failed to decrypt content")
+	suite.Assert().Equal("Hello, World!", string(decrypted.Data))
+	suite.Assert().Equal(uint64(13), decrypted.Length)
+}
+
+func (suite *ContentSuite) TestCanEncryptWithAESCTRAndIV() {
+	decrypted := []byte{0x17, 0x07, 0x26, 0x56, 0xd4, 0x11, 0x16, 0x9d, 0x4d, 0xe5, 0x0a, 0xb9, 0x08, 0xd7, 0xb3, 0x3b}
+	key, _ := hex.DecodeString("FE400803E0BA6A4B3D611305C1A2EDE263A4D599C96F2BA49C837FD4E193C76D")
+	iv, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F")
+	content := request.Content{
+		Type:   "image/jpeg",
+		Length: uint64(len(decrypted)),
+		Data:   decrypted,
+	}
+
+	encrypted, err := content.EncryptWithAESCTRAndIV(key, iv)
+	suite.Require().NoError(err, "Failed to encrypt content")
+	suite.Assert().Equal(iv, encrypted.Data[:16], "Encrypted data should start with the IV")
+	legacy, _ := content.Encrypt(request.AESCTR, key)
+	suite.Assert().NotEqual(legacy.Data, encrypted.Data[16:], "The IV should be used")
+
+	decryptedContent, err := encrypted.DecryptWithAESCTRIV(key)
+	suite.Require().NoError(err, "Failed to decrypt content")
+	suite.Assert().Equal(decrypted, decryptedContent.Data, "Decrypted content is incorrect")
+
+	_, err = content.EncryptWithAESCTRAndIV(key, []byte{1, 2, 3})
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "IV should be 16 bytes long")
+	_, err = request.Content{Data: []byte{1, 2, 3}}.Decrypt(request.AESCTRIV, key)
+	suite.Assert().ErrorIs(err, request.DecryptionFailed, "Data without IV should not be decrypted")
+}
+
 func (suite *ContentSuite) TestShouldFailDecryptWithInvalidAlgorithm() {
 	_, err := request.Content{}.Decrypt(request.CryptoAlgorithm(10), []byte{})
 	suite.Require().Error(err)
@@ -536,12 +681,29 @@ func (suite *ContentSuite) TestShouldFailDecryptWithInvalidKey() {
 	var details errors.Error
 	suite.Require().ErrorAs(err, &details)
 	suite.Assert().Equal("key", details.What)
-	suite.Assert().Equal(key, details.Value.([]byte))
+	suite.Assert().Equal(len(key), details.Value, "The error should only tell the length of the key")
 	suite.Assert().ErrorIs(err, aes.KeySizeError(len(key)), "Error should be a KeySizeError")
 	suite.Require().NotNil(details.Unwrap(), "Error should have a cause")
 	suite.Assert().Equal("crypto/aes: invalid key size 5", details.Unwrap().Error())
 }
 
+func (suite *ContentSuite) TestShouldNotLeakKeysInCryptoErrors() {
+	key := []byte("ThisIsASecret")
+	content := request.ContentWithData(make([]byte, 64), "application/octet-stream")
+	for _, algorithm := range []request.CryptoAlgorithm{request.AESCTRIV, request.AESGCM, request.CHACHA20POLY1305} {
+		_, err := content.Encrypt(algorithm, key)
+		suite.Require().ErrorIs(err, errors.ArgumentInvalid, "Encrypting with %s should have failed", algorithm)
+		var details errors.Error
+		suite.Require().ErrorAs(err, &details)
+		suite.Assert().Equal(len(key), details.Value, "The error of %s should only tell the length of the key", algorithm)
+
+		_, err = content.Decrypt(algorithm, key)
+		suite.Require().ErrorIs(err, errors.ArgumentInvalid, "Decrypting with %s should have failed", algorithm)
+		suite.Require().ErrorAs(err, &details)
+		suite.Assert().Equal(len(key), details.Value, "The error of %s should only tell the length of the key", algorithm)
+	}
+}
+
 func (suite *ContentSuite) TestShouldFailEncryptWithInvalidAlgorithm() {
 	_, err := request.Content{}.Encrypt(request.CryptoAlgorithm(10), []byte{})
 	suite.Require().Error(err)
@@ -561,8 +723,364 @@ func (suite *ContentSuite) TestShouldFailEncryptWithInvalidKey() {
 	var details errors.Error
 	suite.Require().ErrorAs(err, &details)
 	suite.Assert().Equal("key", details.What)
-	suite.Assert().Equal(key, details.Value.([]byte))
+	suite.Assert().Equal(len(key), details.Value, "The error should only tell the length of the key")
 	suite.Assert().ErrorIs(err, aes.KeySizeError(len(key)), "Error should be a KeySizeError")
 	suite.Require().NotNil(details.Unwrap(), "Error should have a cause")
 	suite.Assert().Equal("crypto/aes: invalid key size 5", details.Unwrap().Error())
 }
+
+func (suite *ContentSuite) TestCanConvertToDataURI() {
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+	suite.Assert().Equal("data:text/plain;base64,SGVsbG8sIFdvcmxkIQ==", content.DataURI())
+}
+
+func (suite *ContentSuite) TestCanConvertToDataURIWithoutType() {
+	content := request.ContentWithData([]byte{1, 2, 3})
+	suite.Assert().Equal("data:application/octet-stream;base64,AQID", content.DataURI())
+}
+
+func (suite *ContentSuite) TestCanCreateFromDataURI() {
+	content, err := request.ContentFromDataURI("data:image/png;base64," + base64.StdEncoding.EncodeToString(smallPNG()))
+	suite.Require().NoError(err, "Failed to create content from data URI, err=%+v", err)
+	suite.Assert().Equal("image/png", content.Type)
+	suite.Assert().Equal(smallPNG(), content.Data)
+	suite.Assert().Equal(uint64(len(smallPNG())), content.Length)
+}
+
+func (suite *ContentSuite) TestCanCreateFromDataURIWithoutBase64() {
+	content, err := request.ContentFromDataURI("data:,Hello%2C%20World!")
+	suite.Require().NoError(err, "Failed to create content from data URI, err=%+v", err)
+	suite.Assert().Equal("text/plain;charset=US-ASCII", content.Type)
+	suite.Assert().Equal("Hello, World!", string(content.Data))
+}
+
+func (suite *ContentSuite) TestCanRoundTripDataURI() {
+	expected := request.ContentWithData(smallPNG(), "image/png")
+	content, err := request.ContentFromDataURI(expected.DataURI())
+	suite.Require().NoError(err, "Failed to create content from data URI, err=%+v", err)
+	suite.Assert().Equal(expected.Type, content.Type)
+	suite.Assert().Equal(expected.Data, content.Data)
+}
+
+func (suite *ContentSuite) TestShouldFailCreateFromInvalidDataURI() {
+	_, err := request.ContentFromDataURI("https://www.acme.com/image.png")
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "Error should be an Argument Invalid Error")
+
+	_, err = request.ContentFromDataURI("data:image/png;base64")
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "Error should be an Argument Invalid Error")
+
+	_, err = request.ContentFromDataURI("data:image/png;base64,!!!")
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "Error should be an Argument Invalid Error")
+}
+
+func (suite *ContentSuite) TestCanGetPartsFromMultipartContent() {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-ID", "<part1>")
+	part, _ := writer.CreatePart(header)
+	_, _ = part.Write([]byte(`{"ID": "1234"}`))
+	header = textproto.MIMEHeader{}
+	header.Set("Content-Type", "image/png")
+	header.Set("Content-Disposition", `attachment; filename="image.png"`)
+	part, _ = writer.CreatePart(header)
+	_, _ = part.Write(smallPNG())
+	_ = writer.Close()
+
+	content := request.ContentWithData(body.Bytes(), "multipart/related; boundary="+writer.Boundary())
+	suite.Require().True(content.IsMultipart(), "Content should be multipart")
+	parts, err := content.Parts()
+	suite.Require().NoError(err, "Failed to get parts, err=%+v", err)
+	suite.Require().Len(parts, 2)
+	suite.Assert().Equal("application/json", parts[0].Type)
+	suite.Assert().Equal("<part1>", parts[0].Headers.Get("Content-ID"))
+	suite.Assert().Equal(`{"ID": "1234"}`, string(parts[0].Data))
+	suite.Assert().Equal("image/png", parts[1].Type)
+	suite.Assert().Equal("image.png", parts[1].Name)
+	suite.Assert().Equal(smallPNG(), parts[1].Data)
+	suite.Assert().Equal(uint64(len(smallPNG())), parts[1].Length)
+}
+
+func (suite *ContentSuite) TestShouldFailGettingPartsFromNonMultipartContent() {
+	content := request.ContentWithData([]byte(`{}`), "application/json")
+	suite.Assert().False(content.IsMultipart(), "Content should not be multipart")
+	_, err := content.Parts()
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.InvalidType, "Error should be an Invalid Type Error")
+}
+
+func (suite *ContentSuite) TestShouldFailGettingPartsWithoutBoundary() {
+	content := request.ContentWithData([]byte(`{}`), "multipart/mixed")
+	_, err := content.Parts()
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing, "Error should be an Argument Missing Error")
+}
+
+func (suite *ContentSuite) TestCanRedactContent() {
+	content := request.ContentWithData(
+		[]byte(`{"card": "4035 5010 0000 0008"}`),
+		"application/json",
+		http.Header{"Authorization": {"Bearer ThisIsAToken"}, "X-Custom": {"value"}},
+		[]*http.Cookie{{Name: "session", Value: "1234"}},
+	)
+	redacted := content.Redact(request.RedactOptions{MaxDataSize: 10, Cookies: true})
+	suite.Assert().Equal(`{"card": "`, string(redacted.Data))
+	suite.Assert().Equal(content.Length, redacted.Length)
+	suite.Assert().Equal(request.RedactedValue, redacted.Headers.Get("Authorization"))
+	suite.Assert().Equal("value", redacted.Headers.Get("X-Custom"))
+	suite.Require().Len(redacted.Cookies, 1)
+	suite.Assert().Equal("session", redacted.Cookies[0].Name)
+	suite.Assert().Equal(request.RedactedValue, redacted.Cookies[0].Value)
+
+	// The original Content should not be modified
+	suite.Assert().Equal(`{"card": "4035 5010 0000 0008"}`, string(content.Data))
+	suite.Assert().Equal("Bearer ThisIsAToken", content.Headers.Get("Authorization"))
+	suite.Assert().Equal("1234", content.Cookies[0].Value)
+
+	payload, err := json.Marshal(redacted)
+	suite.Require().NoError(err, "Failed to marshal redacted content, err=%+v", err)
+	suite.Assert().NotContains(string(payload), "ThisIsAToken")
+	suite.Assert().NotContains(string(payload), "4035")
+}
+
+func (suite *ContentSuite) TestCanRedactContentWithoutData() {
+	content := request.ContentWithData([]byte(`secret`), "text/plain", http.Header{"X-Custom": {"value"}})
+	redacted := content.Redact(request.RedactOptions{MaxDataSize: -1, Headers: []string{"*"}})
+	suite.Assert().Nil(redacted.Data)
+	suite.Assert().Equal(uint64(6), redacted.Length)
+	suite.Assert().Equal(request.RedactedValue, redacted.Headers.Get("X-Custom"))
+}
+
+func (suite *ContentSuite) TestCanRedactGzippedContent() {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	_, _ = writer.Write([]byte(`{"card": "4035 5010 0000 0008"}`))
+	suite.Require().NoError(writer.Close())
+	header := http.Header{}
+	header.Set("Content-Encoding", "gzip")
+	content := request.ContentWithData(buffer.Bytes(), "application/json", header, request.KeepRawData(true))
+	content.Trailer = http.Header{"Authorization": {"Bearer ThisIsAToken"}, "X-Checksum": {"1234"}}
+	suite.Require().NotEmpty(content.RawData)
+
+	redacted := content.Redact(request.RedactOptions{MaxDataSize: 10})
+	suite.Assert().Equal(`{"card": "`, string(redacted.Data))
+	suite.Assert().Equal(content.RawData[:10], redacted.RawData, "RawData should be truncated")
+	suite.Assert().Equal(request.RedactedValue, redacted.Trailer.Get("Authorization"))
+	suite.Assert().Equal("1234", redacted.Trailer.Get("X-Checksum"))
+	suite.Assert().Equal("Bearer ThisIsAToken", content.Trailer.Get("Authorization"), "The original Content should not be modified")
+
+	payload, err := json.Marshal(redacted)
+	suite.Require().NoError(err, "Failed to marshal redacted content, err=%+v", err)
+	suite.Assert().NotContains(string(payload), "ThisIsAToken")
+	suite.Assert().NotContains(string(payload), base64.StdEncoding.EncodeToString(content.RawData))
+
+	redacted = content.Redact(request.RedactOptions{MaxDataSize: -1})
+	suite.Assert().Nil(redacted.Data)
+	suite.Assert().Nil(redacted.RawData)
+}
+
+func (suite *ContentSuite) TestCanComputeChecksums() {
+	content := request.ContentWithData([]byte("body"), "text/plain")
+	suite.Assert().Equal("841a2d689ad86bd1611447453c22c6fc", hex.EncodeToString(content.MD5()))
+	suite.Assert().Equal("230d8358dc8e8890b4c58deeb62912ee2f20357ae92a5cc861b98e68fe31acb5", hex.EncodeToString(content.SHA256()))
+}
+
+func (suite *ContentSuite) TestCanVerifyChecksums() {
+	content := request.ContentWithData([]byte("body"), "text/plain", http.Header{
+		"Content-Md5":           {"hBotaJrYa9FhFEdFPCLG/A=="},
+		"X-Amz-Checksum-Sha256": {"Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU="},
+		"Content-Digest":        {"sha-256=:Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU=:, unknown=:1234:"},
+	})
+	suite.Assert().NoError(content.VerifyChecksum())
+
+	content = request.ContentWithData([]byte("body"), "text/plain")
+	suite.Assert().NoError(content.VerifyChecksum(), "A Content without checksums should be verified")
+}
+
+func (suite *ContentSuite) TestShouldFailVerifyingWrongChecksums() {
+	content := request.ContentWithData([]byte("corrupted body"), "text/plain", http.Header{
+		"Digest": {"md5=hBotaJrYa9FhFEdFPCLG/A=="},
+	})
+	err := content.VerifyChecksum()
+	suite.Require().Error(err, "Checksum should not match")
+	suite.Assert().ErrorIs(err, request.ChecksumMismatch)
+	var details errors.Error
+	suite.Require().True(errors.As(err, &details), "Error chain should contain an errors.Error")
+	suite.Assert().Equal("Digest", details.What)
+}
+
+func (suite *ContentSuite) TestCanEncryptAndDecryptWithAuthenticatedAlgorithms() {
+	key, _ := hex.DecodeString("FE400803E0BA6A4B3D611305C1A2EDE263A4D599C96F2BA49C837FD4E193C76D")
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+
+	for _, algorithm := range []request.CryptoAlgorithm{request.AESGCM, request.CHACHA20POLY1305} {
+		encrypted, err := content.Encrypt(algorithm, key)
+		suite.Require().NoError(err, "Failed to encrypt content with %s", algorithm)
+		suite.Assert().Equal(uint64(len(encrypted.Data)), encrypted.Length)
+		suite.Assert().Equal(12+13+16, len(encrypted.Data), "Encrypted data should contain the nonce, the ciphertext, and the tag (%s)", algorithm)
+		suite.Assert().NotContains(string(encrypted.Data), "Hello", "Data should be encrypted with %s", algorithm)
+
+		again, err := content.Encrypt(algorithm, key)
+		suite.Require().NoError(err, "Failed to encrypt content with %s", algorithm)
+		suite.Assert().NotEqual(encrypted.Data, again.Data, "Nonces should be random with %s", algorithm)
+
+		decrypted, err := encrypted.Decrypt(algorithm, key)
+		suite.Require().NoError(err, "Failed to decrypt content with %s", algorithm)
+		suite.Assert().Equal("Hello, World!", string(decrypted.Data))
+		suite.Assert().Equal(uint64(13), decrypted.Length)
+		suite.Assert().Equal("text/plain", decrypted.Type)
+	}
+}
+
+func (suite *ContentSuite) TestShouldFailDecryptingTamperedContent() {
+	key, _ := hex.DecodeString("FE400803E0BA6A4B3D611305C1A2EDE263A4D599C96F2BA49C837FD4E193C76D")
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+
+	for _, algorithm := range []request.CryptoAlgorithm{request.AESGCM, request.CHACHA20POLY1305} {
+		encrypted, err := content.Encrypt(algorithm, key)
+		suite.Require().NoError(err, "Failed to encrypt content with %s", algorithm)
+		encrypted.Data[len(encrypted.Data)-1] ^= 0xFF
+		_, err = encrypted.Decrypt(algorithm, key)
+		suite.Require().Error(err, "Should have failed to decrypt tampered content with %s", algorithm)
+		suite.Assert().ErrorIs(err, request.DecryptionFailed)
+
+		_, err = request.Content{Data: []byte{1, 2, 3}}.Decrypt(algorithm, key)
+		suite.Assert().ErrorIs(err, request.DecryptionFailed, "Should have failed to decrypt too short content with %s", algorithm)
+	}
+}
+
+func (suite *ContentSuite) TestCanMarshalAuthenticatedCryptoAlgorithms() {
+	for _, algorithm := range []request.CryptoAlgorithm{request.AESGCM, request.CHACHA20POLY1305, request.AESCTRIV} {
+		payload, err := json.Marshal(algorithm)
+		suite.Require().NoErrorf(err, "Failed to marshal algorithm, error: %s", err)
+		var unmarshaled request.CryptoAlgorithm
+		err = json.Unmarshal(payload, &unmarshaled)
+		suite.Require().NoErrorf(err, "Failed to unmarshal algorithm, error: %s", err)
+		suite.Assert().Equal(algorithm, unmarshaled)
+	}
+	payload, _ := json.Marshal(request.CHACHA20POLY1305)
+	suite.Assert().Equal(`"CHACHA20POLY1305"`, string(payload))
+}
+
+func (suite *ContentSuite) TestCanSignAndVerifyJWS() {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	secret := []byte("my-shared-secret-my-shared-secret")
+	content := request.ContentWithData([]byte(`{"hello":"world"}`), "application/json")
+
+	tests := []struct {
+		algorithm string
+		signKey   interface{}
+		verifyKey interface{}
+	}{
+		{request.JWSHS256, secret, secret},
+		{request.JWSRS256, rsaKey, &rsaKey.PublicKey},
+		{request.JWSES256, ecKey, &ecKey.PublicKey},
+	}
+	for _, test := range tests {
+		signed, err := content.SignJWS(test.algorithm, test.signKey)
+		suite.Require().NoError(err, "Failed to sign with %s", test.algorithm)
+		suite.Assert().Equal(request.JOSEMediaType, signed.Type)
+		suite.Assert().Len(strings.Split(string(signed.Data), "."), 3, "JWS should have 3 segments with %s", test.algorithm)
+
+		verified, err := signed.VerifyJWS(test.verifyKey)
+		suite.Require().NoError(err, "Failed to verify with %s", test.algorithm)
+		suite.Assert().Equal(`{"hello":"world"}`, string(verified.Data))
+		suite.Assert().Equal("application/json", verified.Type)
+	}
+}
+
+func (suite *ContentSuite) TestShouldFailVerifyingTamperedJWS() {
+	secret := []byte("my-shared-secret-my-shared-secret")
+	signed, err := request.ContentWithData([]byte("Hello, World!"), "text/plain").SignJWS(request.JWSHS256, secret)
+	suite.Require().NoError(err)
+
+	segments := strings.Split(string(signed.Data), ".")
+	segments[1] = base64.RawURLEncoding.EncodeToString([]byte("Hello, Mars!"))
+	_, err = request.ContentWithData([]byte(strings.Join(segments, "."))).VerifyJWS(secret)
+	suite.Require().Error(err, "Should have failed to verify a tampered JWS")
+	suite.Assert().ErrorIs(err, request.JWSSignatureInvalid)
+
+	_, err = signed.VerifyJWS([]byte("another-secret"))
+	suite.Assert().ErrorIs(err, request.JWSSignatureInvalid, "Should have failed to verify with the wrong secret")
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = signed.VerifyJWS(&ecKey.PublicKey)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "Should not accept a key that does not match the algorithm")
+
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + segments[1] + "."
+	_, err = request.ContentWithData([]byte(none)).VerifyJWS(secret)
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid, "Should not accept the none algorithm")
+}
+
+func (suite *ContentSuite) TestCanEncryptAndDecryptJWE() {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+
+	tests := []struct {
+		algorithm  string
+		encryptKey interface{}
+		decryptKey interface{}
+	}{
+		{request.JWERSAOAEP, &rsaKey.PublicKey, rsaKey},
+		{request.JWERSAOAEP256, &rsaKey.PublicKey, rsaKey},
+		{request.JWEECDHES, &ecKey.PublicKey, ecKey},
+	}
+	for _, test := range tests {
+		encrypted, err := content.EncryptJWE(test.algorithm, test.encryptKey)
+		suite.Require().NoError(err, "Failed to encrypt with %s", test.algorithm)
+		suite.Assert().Equal(request.JOSEMediaType, encrypted.Type)
+		suite.Assert().Len(strings.Split(string(encrypted.Data), "."), 5, "JWE should have 5 segments with %s", test.algorithm)
+		suite.Assert().NotContains(string(encrypted.Data), "Hello", "Data should be encrypted with %s", test.algorithm)
+
+		decrypted, err := encrypted.DecryptJWE(test.decryptKey)
+		suite.Require().NoError(err, "Failed to decrypt with %s", test.algorithm)
+		suite.Assert().Equal("Hello, World!", string(decrypted.Data))
+		suite.Assert().Equal("text/plain", decrypted.Type)
+	}
+}
+
+func (suite *ContentSuite) TestShouldNotLeakKeysInJOSEErrors() {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	content := request.ContentWithData([]byte("Hello, World!"), "text/plain")
+
+	_, err = content.SignJWS(request.JWSES256, rsaKey)
+	suite.Require().ErrorIs(err, errors.ArgumentInvalid)
+	var details errors.Error
+	suite.Require().ErrorAs(err, &details)
+	suite.Assert().Equal("key", details.What)
+	suite.Assert().Equal("*rsa.PrivateKey", details.Value, "The error should only tell the type of the key")
+
+	_, err = content.EncryptJWE(request.JWEECDHES, []byte("ThisIsASecret"))
+	suite.Require().ErrorIs(err, errors.ArgumentInvalid)
+	suite.Assert().NotContains(err.Error(), "ThisIsASecret")
+	suite.Require().ErrorAs(err, &details)
+	suite.Assert().Equal("[]uint8", details.Value)
+}
+
+func (suite *ContentSuite) TestShouldFailDecryptingTamperedJWE() {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	encrypted, err := request.ContentWithData([]byte("Hello, World!"), "text/plain").EncryptJWE(request.JWEECDHES, &ecKey.PublicKey)
+	suite.Require().NoError(err)
+
+	segments := strings.Split(string(encrypted.Data), ".")
+	segments[3] = base64.RawURLEncoding.EncodeToString([]byte("Hello, Mars!!"))
+	_, err = request.ContentWithData([]byte(strings.Join(segments, "."))).DecryptJWE(ecKey)
+	suite.Require().Error(err, "Should have failed to decrypt a tampered JWE")
+	suite.Assert().ErrorIs(err, request.DecryptionFailed)
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = encrypted.DecryptJWE(otherKey)
+	suite.Assert().ErrorIs(err, request.DecryptionFailed, "Should have failed to decrypt with the wrong key")
+}
@@ -0,0 +1,266 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// Decoder decodes the body of a response into the results given to Send
+type Decoder interface {
+	Decode(data []byte, results interface{}) error
+}
+
+// DecoderFunc is a function that implements Decoder
+type DecoderFunc func(data []byte, results interface{}) error
+
+// Decode decodes the data into the results
+func (decoder DecoderFunc) Decode(data []byte, results interface{}) error {
+	return decoder(data, results)
+}
+
+// JSONDecoder decodes JSON, UTF-8 BOMs, anti-XSSI prefixes, and leading whitespaces are ignored
+//
+// It is registered for application/json by default.
+var JSONDecoder Decoder = DecoderFunc(func(data []byte, results interface{}) error {
+	if err := json.Unmarshal(jsonData(data), results); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	return nil
+})
+
+// XMLDecoder decodes XML
+//
+// It is not registered by default, use RegisterDecoder("application/xml", 0.9, request.XMLDecoder) to accept XML responses.
+var XMLDecoder Decoder = DecoderFunc(func(data []byte, results interface{}) error {
+	if err := xml.Unmarshal(data, results); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+})
+
+// FormDecoder decodes application/x-www-form-urlencoded bodies (e.g.: some OAuth token responses)
+//
+// The results can be a url.Values, a map, or a struct whose fields are matched with their "url" or "form" tags, or their names.
+// It is used for application/x-www-form-urlencoded responses unless another Decoder is registered for that media type,
+// but it is not registered by default so the Accept header does not ask for it.
+var FormDecoder Decoder = DecoderFunc(func(data []byte, results interface{}) error {
+	values, err := url.ParseQuery(strings.TrimSpace(string(data)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return decodeForm(values, results)
+})
+
+// unmarshalJSONValue unmarshals a value made of maps, slices, and primitives into the results like encoding/json does
+//
+// Decoders of JSON-like formats use it to map their values to Go values.
+func unmarshalJSONValue(value interface{}, results interface{}) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return errors.JSONMarshalError.Wrap(err)
+	}
+	if err = json.Unmarshal(payload, results); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	return nil
+}
+
+// registeredDecoder is a Decoder registered for a media type
+type registeredDecoder struct {
+	mediaType string
+	quality   float64
+	decoder   Decoder
+}
+
+// decoders are the registered Decoders, in the order they were registered
+var decoders = struct {
+	entries []registeredDecoder
+	lock    sync.RWMutex
+}{
+	entries: []registeredDecoder{{mediaType: "application/json", quality: 1, decoder: JSONDecoder}},
+}
+
+// RegisterDecoder registers a Decoder for a media type
+//
+// The quality (between 0 and 1) ranks the media type in the Accept header computed by AcceptFor.
+// Registering a media type again replaces its Decoder and its quality.
+func RegisterDecoder(mediaType string, quality float64, decoder Decoder) error {
+	if len(mediaType) == 0 {
+		return errors.ArgumentMissing.With("mediaType")
+	}
+	if decoder == nil {
+		return errors.ArgumentMissing.With("decoder")
+	}
+	if quality <= 0 || quality > 1 {
+		return errors.ArgumentInvalid.With("quality", quality)
+	}
+	mediaType = strings.ToLower(mediaType)
+	decoders.lock.Lock()
+	defer decoders.lock.Unlock()
+	for index, entry := range decoders.entries {
+		if entry.mediaType == mediaType {
+			decoders.entries[index] = registeredDecoder{mediaType: mediaType, quality: quality, decoder: decoder}
+			return nil
+		}
+	}
+	decoders.entries = append(decoders.entries, registeredDecoder{mediaType: mediaType, quality: quality, decoder: decoder})
+	return nil
+}
+
+// UnregisterDecoder removes the Decoder registered for a media type
+func UnregisterDecoder(mediaType string) {
+	mediaType = strings.ToLower(mediaType)
+	decoders.lock.Lock()
+	defer decoders.lock.Unlock()
+	for index, entry := range decoders.entries {
+		if entry.mediaType == mediaType {
+			decoders.entries = append(decoders.entries[:index], decoders.entries[index+1:]...)
+			return
+		}
+	}
+}
+
+// AcceptFor gets the Accept header Send uses for the given results
+//
+// Results that are decoded accept the media types of the registered Decoders, ranked by quality
+// (e.g.: "application/json, application/xml;q=0.9").
+// Results that are streamed (io.Writer, ContentReader) or absent accept any media type.
+func AcceptFor(results interface{}) string {
+	switch results.(type) {
+	case nil, io.Writer, []io.Writer, *ContentReader:
+		return "*"
+	}
+	decoders.lock.RLock()
+	entries := append([]registeredDecoder{}, decoders.entries...)
+	decoders.lock.RUnlock()
+	if len(entries) == 0 {
+		return "*"
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].quality > entries[j].quality })
+	accept := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.quality < 1 {
+			accept = append(accept, fmt.Sprintf("%s;q=%s", entry.mediaType, strconv.FormatFloat(entry.quality, 'f', -1, 64)))
+		} else {
+			accept = append(accept, entry.mediaType)
+		}
+	}
+	return strings.Join(accept, ", ")
+}
+
+// decoderFor gets the Decoder registered for the given content type
+//
+// Structured syntax suffixes (e.g.: application/problem+json) use the Decoder of their base type (e.g.: application/json).
+// If no Decoder matches, the FormDecoder is used for application/x-www-form-urlencoded and the JSONDecoder for anything else.
+func decoderFor(contentType string) Decoder {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return JSONDecoder
+	}
+	candidates := []string{mediaType}
+	if index := strings.LastIndex(mediaType, "+"); index > 0 {
+		if slash := strings.Index(mediaType, "/"); slash > 0 {
+			candidates = append(candidates, mediaType[:slash+1]+mediaType[index+1:])
+		}
+	}
+	decoders.lock.RLock()
+	defer decoders.lock.RUnlock()
+	for _, candidate := range candidates {
+		for _, entry := range decoders.entries {
+			if entry.mediaType == candidate {
+				return entry.decoder
+			}
+		}
+	}
+	if mediaType == "application/x-www-form-urlencoded" {
+		return FormDecoder
+	}
+	return JSONDecoder
+}
+
+// decodeResults decodes the Data of a Content into the results with the Decoder registered for its Type
+//
+// If the ResultPath of the options is not empty, only the value at that path of the decoded document is unmarshaled into the results.
+// If the KeyTranslation of the options is set, the keys of the decoded document are translated before being unmarshaled into the results.
+// ResultPath and KeyTranslation require a JSON-like media type (JSON, MessagePack, CBOR, forms, etc), XML is not supported.
+func decodeResults(content *Content, results interface{}, options *Options) error {
+	decoder := decoderFor(content.Type)
+	if len(options.ResultPath) == 0 && options.KeyTranslation == NoKeyTranslation {
+		return decoder.Decode(content.Data, results)
+	}
+	if isXMLMediaType(content.Type) {
+		return errors.Unsupported.With("media type with ResultPath or KeyTranslation", content.Type)
+	}
+	var document json.RawMessage
+	if err := decoder.Decode(content.Data, &document); err != nil {
+		return err // err is already decorated
+	}
+	if len(options.ResultPath) > 0 {
+		value, err := extractResultPath(document, options.ResultPath)
+		if err != nil {
+			return err
+		}
+		document = value
+	}
+	if options.KeyTranslation != NoKeyTranslation {
+		return options.KeyTranslation.unmarshal(document, results)
+	}
+	if err := json.Unmarshal(document, results); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	return nil
+}
+
+// isXMLMediaType tells if the given content type is XML, whose documents cannot be decoded as JSON documents
+func isXMLMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false // decoded with the JSONDecoder
+	}
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// extractResultPath gets the value at the given dot-separated path of a JSON document
+//
+// The path is made of object keys and array indexes (e.g.: data.items.0.id).
+func extractResultPath(document json.RawMessage, path string) (json.RawMessage, error) {
+	value := document
+	for _, segment := range strings.Split(path, ".") {
+		switch trimmed := bytes.TrimLeft(value, " \t\r\n"); {
+		case len(trimmed) > 0 && trimmed[0] == '{':
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(trimmed, &object); err != nil {
+				return nil, errors.JSONUnmarshalError.WrapIfNotMe(err)
+			}
+			item, found := object[segment]
+			if !found {
+				return nil, ResultPathNotFound.With(path, segment)
+			}
+			value = item
+		case len(trimmed) > 0 && trimmed[0] == '[':
+			var array []json.RawMessage
+			if err := json.Unmarshal(trimmed, &array); err != nil {
+				return nil, errors.JSONUnmarshalError.WrapIfNotMe(err)
+			}
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(array) {
+				return nil, ResultPathNotFound.With(path, segment)
+			}
+			value = array[index]
+		default:
+			return nil, ResultPathNotFound.With(path, segment)
+		}
+	}
+	return value, nil
+}
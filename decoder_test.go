@@ -0,0 +1,218 @@
+package request_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanGetAcceptForResults(t *testing.T) {
+	assert.Equal(t, "*", request.AcceptFor(nil))
+	assert.Equal(t, "*", request.AcceptFor(&bytes.Buffer{}))
+	assert.Equal(t, "*", request.AcceptFor([]io.Writer{&bytes.Buffer{}}))
+	assert.Equal(t, "*", request.AcceptFor(&request.ContentReader{}))
+	assert.Equal(t, "application/json", request.AcceptFor(&stuff{}))
+}
+
+func TestCanGetAcceptForResultsWithRegisteredDecoders(t *testing.T) {
+	require.NoError(t, request.RegisterDecoder("application/xml", 0.9, request.XMLDecoder))
+	defer request.UnregisterDecoder("application/xml")
+	require.NoError(t, request.RegisterDecoder("text/csv", 0.5, request.DecoderFunc(func([]byte, interface{}) error { return nil })))
+	defer request.UnregisterDecoder("text/csv")
+
+	assert.Equal(t, "application/json, application/xml;q=0.9, text/csv;q=0.5", request.AcceptFor(&stuff{}))
+
+	require.NoError(t, request.RegisterDecoder("text/csv", 1, request.DecoderFunc(func([]byte, interface{}) error { return nil })))
+	assert.Equal(t, "application/json, text/csv, application/xml;q=0.9", request.AcceptFor(&stuff{}), "Registering a media type again should replace its quality")
+}
+
+func TestShouldFailRegisteringInvalidDecoder(t *testing.T) {
+	assert.ErrorIs(t, request.RegisterDecoder("", 1, request.XMLDecoder), errors.ArgumentMissing)
+	assert.ErrorIs(t, request.RegisterDecoder("application/xml", 1, nil), errors.ArgumentMissing)
+	assert.ErrorIs(t, request.RegisterDecoder("application/xml", 0, request.XMLDecoder), errors.ArgumentInvalid)
+	assert.ErrorIs(t, request.RegisterDecoder("application/xml", 1.5, request.XMLDecoder), errors.ArgumentInvalid)
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsWithRegisteredDecoder() {
+	suite.Require().NoError(request.RegisterDecoder("application/xml", 0.9, request.XMLDecoder))
+	defer request.UnregisterDecoder("application/xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		_, _ = w.Write([]byte(`<stuff><ID>1234</ID></stuff>`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	results := struct {
+		XMLName xml.Name `xml:"stuff"`
+		ID      string   `xml:"ID"`
+	}{}
+	content, err := request.Send(&request.Options{
+		URL:    serverURL,
+		Logger: suite.Logger,
+	}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("application/json, application/xml;q=0.9", content.Headers.Get("X-Accept"))
+	suite.Assert().Equal("1234", results.ID)
+}
+
+func CreateResultPathTestServer(suite *RequestSuite) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"items": [{"id": "1"}, {"id": "2"}], "count": 2}}`))
+	}))
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsAtResultPath() {
+	server := CreateResultPathTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	var items []stuff
+	_, err := request.Send(&request.Options{URL: serverURL, ResultPath: "data.items", Logger: suite.Logger}, &items)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal([]stuff{{ID: "1"}, {ID: "2"}}, items)
+
+	var item stuff
+	_, err = request.Send(&request.Options{URL: serverURL, ResultPath: "data.items.1", Logger: suite.Logger}, &item)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("2", item.ID)
+}
+
+func (suite *RequestSuite) TestShouldFailReceivingResultsAtMissingResultPath() {
+	server := CreateResultPathTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	for _, path := range []string{"data.users", "data.items.2", "data.items.first", "data.count.value"} {
+		var results interface{}
+		_, err := request.Send(&request.Options{URL: serverURL, ResultPath: path, Logger: suite.Logger}, &results)
+		suite.Require().Error(err, "Should have failed with path %s", path)
+		suite.Assert().ErrorIs(err, request.ResultPathNotFound, "Wrong error with path %s", path)
+	}
+}
+
+func (suite *RequestSuite) TestShouldFailReceivingXMLResultsAtResultPath() {
+	suite.Require().NoError(request.RegisterDecoder("application/xml", 0.9, request.XMLDecoder))
+	defer request.UnregisterDecoder("application/xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(`<data><items><ID>1234</ID></items></data>`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	var results interface{}
+	_, err := request.Send(&request.Options{URL: serverURL, ResultPath: "data.items", Logger: suite.Logger}, &results)
+	suite.Require().Error(err, "Should have failed with an XML response")
+	suite.Assert().ErrorIs(err, errors.Unsupported)
+	suite.Assert().Contains(err.Error(), "ResultPath")
+
+	_, err = request.Send(&request.Options{URL: serverURL, KeyTranslation: request.SnakeCaseKeys, Logger: suite.Logger}, &results)
+	suite.Require().Error(err, "Should have failed with an XML response")
+	suite.Assert().ErrorIs(err, errors.Unsupported)
+}
+
+func TestCanDecodeForm(t *testing.T) {
+	results := struct {
+		AccessToken string        `url:"access_token"`
+		ExpiresIn   int           `form:"expires_in"`
+		Scope       []string      `url:"scope"`
+		Refreshable *bool         `url:"refreshable"`
+		IssuedAt    time.Time     `url:"issued_at,unix"`
+		Timeout     time.Duration `url:"timeout"`
+		TokenType   string
+		Ignored     string `url:"-"`
+	}{}
+	err := request.FormDecoder.Decode([]byte("access_token=ABCD&expires_in=3600&scope=read&scope=write&refreshable=true&issued_at=1700000000&timeout=30s&token_type=bearer&tokentype=Bearer&Ignored=no"), &results)
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD", results.AccessToken)
+	assert.Equal(t, 3600, results.ExpiresIn)
+	assert.Equal(t, []string{"read", "write"}, results.Scope)
+	require.NotNil(t, results.Refreshable)
+	assert.True(t, *results.Refreshable)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), results.IssuedAt)
+	assert.Equal(t, 30*time.Second, results.Timeout)
+	assert.Equal(t, "Bearer", results.TokenType, "Fields without tags should match their name regardless of case")
+	assert.Empty(t, results.Ignored)
+}
+
+func TestCanDecodeFormIntoMaps(t *testing.T) {
+	data := []byte("access_token=ABCD&scope=read&scope=write")
+
+	values := url.Values{}
+	require.NoError(t, request.FormDecoder.Decode(data, &values))
+	assert.Equal(t, []string{"read", "write"}, values["scope"])
+
+	firsts := map[string]string{}
+	require.NoError(t, request.FormDecoder.Decode(data, &firsts))
+	assert.Equal(t, map[string]string{"access_token": "ABCD", "scope": "read"}, firsts)
+
+	items := map[string]interface{}{}
+	require.NoError(t, request.FormDecoder.Decode(data, &items))
+	assert.Equal(t, map[string]interface{}{"access_token": "ABCD", "scope": []interface{}{"read", "write"}}, items)
+}
+
+func TestShouldFailDecodingInvalidForm(t *testing.T) {
+	results := struct {
+		ExpiresIn int `url:"expires_in"`
+	}{}
+	err := request.FormDecoder.Decode([]byte("expires_in=soon"), &results)
+	assert.ErrorIs(t, err, errors.ArgumentInvalid)
+
+	err = request.FormDecoder.Decode([]byte("expires_in=3600"), results)
+	assert.ErrorIs(t, err, errors.ArgumentInvalid, "Results must be a pointer")
+
+	err = request.FormDecoder.Decode([]byte("expires_in=%zz"), &results)
+	assert.Error(t, err)
+}
+
+func (suite *RequestSuite) TestCanReceiveFormResults() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		_, _ = w.Write([]byte(`access_token=ABCD&token_type=bearer&expires_in=3600&scope=repo%2Cuser`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	token := struct {
+		AccessToken string `url:"access_token"`
+		TokenType   string `url:"token_type"`
+		ExpiresIn   int64  `url:"expires_in"`
+		Scope       string `url:"scope"`
+	}{}
+	_, err := request.Send(&request.Options{
+		URL:    serverURL,
+		Logger: suite.Logger,
+	}, &token)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("ABCD", token.AccessToken)
+	suite.Assert().Equal("bearer", token.TokenType)
+	suite.Assert().Equal(int64(3600), token.ExpiresIn)
+	suite.Assert().Equal("repo,user", token.Scope)
+
+	translated := struct {
+		AccessToken string
+		ExpiresIn   string
+	}{}
+	_, err = request.Send(&request.Options{
+		URL:            serverURL,
+		KeyTranslation: request.SnakeCaseKeys,
+		Logger:         suite.Logger,
+	}, &translated)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("ABCD", translated.AccessToken)
+	suite.Assert().Equal("3600", translated.ExpiresIn)
+}
@@ -0,0 +1,63 @@
+package request
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// IPPreference tells which IP versions are used to connect to servers
+type IPPreference int
+
+const (
+	// IPAny connects with IPv4 or IPv6, whichever answers first (Happy Eyeballs)
+	IPAny IPPreference = iota
+	// IPv4Only connects with IPv4 only
+	IPv4Only
+	// IPv6Only connects with IPv6 only
+	IPv6Only
+)
+
+// String gets a string representation of this IPPreference
+//
+// implements fmt.Stringer
+func (preference IPPreference) String() string {
+	switch preference {
+	case IPv4Only:
+		return "v4"
+	case IPv6Only:
+		return "v6"
+	default:
+		return "any"
+	}
+}
+
+// network gets the network to dial for the given network with this IPPreference
+func (preference IPPreference) network(network string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch preference {
+	case IPv4Only:
+		return "tcp4"
+	case IPv6Only:
+		return "tcp6"
+	default:
+		return network
+	}
+}
+
+// dialContext wraps the dial func of a Transport with a connection timeout and an IPPreference
+func dialContext(dial func(ctx context.Context, network, address string) (net.Conn, error), timeout time.Duration, preference IPPreference) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return dial(ctx, preference.network(network), address)
+	}
+}
@@ -17,7 +17,7 @@ Examples:
 	data := struct{Data string}{}
 	err := res.UnmarshalContentJSON(&data)
 
-Here we send an HTTP GET request and unmarshal the response (a ContentReader).
+Here we send an HTTP GET request and unmarshal the response (a Content).
 
 It is also possible to let request.Send do the unmarshal for us:
 
@@ -95,31 +95,33 @@ To send an x-www-form, use a map in the payload:
 		return err
 	}
 
-To send a multipart form with an attachment, use a map, an attachment, and one of the key must start with "">":
+To send a multipart form with files, give them in Attachments, the fields of the Payload (a map, a struct, or nil) are sent first:
 
-	attachment := request.ContentWithData(myReadFile(), "image/png")
 	data := struct{Data string}{}
 	_, err := request.Send(&request.Options{
 		Method:  http.MethodPut,
 		URL:     myURL,
 		Payload: map[string]string{
-			"ID":    "1234",
-			"Kind":  "stuff,"
-			">file": "image.png",
+			"ID":   "1234",
+			"Kind": "stuff,"
+		},
+		Attachments: []request.Attachment{
+			{FieldName: "file", FileName: "image.png", ContentType: "image/png", Reader: bytes.NewReader(myReadFile())},
 		},
-		Attachment: attachment.Reader(),
 	}, &data)
 	if err != nil {
 		return err
 	}
 
+The previous convention, a map key that starts with ">" to send the Attachment, is deprecated.
+
 Notes
 
 - if the PayloadType is not mentioned, it is calculated when processing the Payload.
 
-- if the payload is a ContentReader or a Content, it is used directly.
+- if the payload is a Content or a *Content, it is used directly. Use ContentFromReader to turn an io.Reader into a Content, and Content.Reader to stream a Content back.
 
-- if the payload is a map[string]xxx where *xxx* is not string, the fmt.Stringer is used whenever possible to get the string version of the values.
+- if the payload is a map[string]xxx where *xxx* is not string, primitive values (int, bool, float64, etc) are formatted, slices (like map[string][]string) give repeated fields, and the fmt.Stringer is used whenever possible to get the string version of the other values.
 
 - if the payload is a struct or a pointer to struct, the body is sent as application/json and marshaled.
 
@@ -131,8 +133,6 @@ Notes
 
 TODO
 
-- Support other kinds of map in the payload, like map[string]int, etc.
-
 - Maybe have an interface for the Payload to allow users to provide the logic of building the payload themselves.
 
 */
@@ -0,0 +1,67 @@
+package request
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gildas/go-errors"
+)
+
+// SaveToFile saves the Content's data to the given file
+//
+// The data is written to a temporary file in the same directory, which is then renamed,
+// so the file is either fully written or left untouched.
+func (content Content) SaveToFile(path string) error {
+	return content.WriteToFile(path, 0644)
+}
+
+// Download sends the request and streams the response body to the given file
+//
+// The body is written to a temporary file in the same directory, which is renamed once the body is fully read and verified.
+// The length is verified against the Content-Length header, and the checksums too if options.VerifyChecksum is true.
+// On failure, the file is left untouched.
+//
+// The returned Content has no data, its other properties are valid (like the size, mime type, etc).
+func Download(options *Options, path string) (content *Content, err error) {
+	err = writeFileAtomically(path, 0644, func(file *os.File) error {
+		if content, err = Send(options, file); err != nil {
+			return err
+		}
+		if expected, err := strconv.ParseUint(content.Headers.Get("Content-Length"), 10, 64); err == nil && expected != content.Length {
+			return ContentLengthMismatch.With(strconv.FormatUint(content.Length, 10), expected)
+		}
+		return nil
+	})
+	return
+}
+
+// writeFileAtomically writes a temporary file with the given func and renames it to path with the given permissions on success
+func writeFileAtomically(path string, perm os.FileMode, write func(file *os.File) error) error {
+	if len(path) == 0 {
+		return errors.ArgumentMissing.With("path")
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tempname := file.Name()
+	defer os.Remove(tempname) // no-op once renamed
+
+	if err = write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Chmod(perm); err != nil {
+		file.Close()
+		return errors.WithStack(err)
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return errors.WithStack(err)
+	}
+	if err = file.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tempname, path))
+}
@@ -0,0 +1,225 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// DefaultDownloadSegments defines the number of segments downloaded in parallel by default
+const DefaultDownloadSegments = 4
+
+// DefaultMinSegmentSize defines the minimum size of a download segment by default
+const DefaultMinSegmentSize = 1024 * 1024
+
+// DownloadManager downloads files in a directory, splitting large files into byte-range segments fetched in parallel
+//
+// Segments are used only when the server supports byte ranges (Accept-Ranges: bytes),
+// otherwise the file is downloaded like Download does.
+type DownloadManager struct {
+	Directory      string // where the files are downloaded
+	Segments       int    // how many segments are downloaded in parallel, by default: 4
+	MinSegmentSize int64  // the minimum size of a segment, by default: 1MB
+}
+
+// Download downloads the file at options.URL in the Directory
+//
+// If filename is empty, the last element of the URL path is used.
+//
+// The progress of all segments is written to options.ProgressWriter, its maximum is set to the file size.
+// The file is written atomically, and its length and checksums (if options.VerifyChecksum is true) are verified.
+//
+// The returned Content has no data, its other properties are valid (like the size, mime type, etc).
+func (manager DownloadManager) Download(options *Options, filename string) (*Content, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	options = options.Clone()
+	if options.URL == nil && len(options.URLString) > 0 {
+		var err error
+		if options.URL, err = parseURL(options.URLString); err != nil {
+			return nil, err
+		}
+	}
+	if len(filename) == 0 && options.URL != nil {
+		filename = path.Base(options.URL.Path)
+	}
+	if len(filename) == 0 || filename == "/" || filename == "." {
+		return nil, errors.ArgumentMissing.With("filename")
+	}
+	destination := filepath.Join(manager.Directory, filename)
+
+	headOptions := *options
+	headOptions.Method = http.MethodHead
+	headOptions.Payload = nil
+	headOptions.ProgressWriter = nil
+	headOptions.VerifyChecksum = false
+	head, err := Send(&headOptions, nil)
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseInt(head.Headers.Get("Content-Length"), 10, 64)
+	segments := manager.segments(size)
+	if err != nil || head.Headers.Get("Accept-Ranges") != "bytes" || segments < 2 {
+		headOptions.Logger.Debugf("Downloading %s without segments (size: %d, ranges: %s)", options.URL, size, head.Headers.Get("Accept-Ranges"))
+		return Download(options, destination)
+	}
+
+	if options.ProgressFunc != nil {
+		// All segments report to the same ProgressFunc
+		reporter := newProgressReporter(options.ProgressWriter, options.ProgressFunc, options.ProgressInterval)
+		reporter.setTotal(size)
+		options.ProgressWriter = reporter
+	}
+	if options.ProgressWriter != nil {
+		if options.ProgressSetMaxFunc != nil {
+			options.ProgressSetMaxFunc(size)
+		} else if maxSetter, ok := options.ProgressWriter.(ProgressBarMaxSetter); ok {
+			maxSetter.SetMax64(size)
+		} else if maxChanger, ok := options.ProgressWriter.(ProgressBarMaxChanger); ok {
+			maxChanger.ChangeMax64(size)
+		}
+		if progressCloser, ok := options.ProgressWriter.(io.Closer); ok {
+			defer progressCloser.Close()
+		}
+	}
+
+	err = writeFileAtomically(destination, 0644, func(file *os.File) error {
+		if err := file.Truncate(size); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := manager.downloadSegments(options, file, size, segments); err != nil {
+			return err
+		}
+		if options.VerifyChecksum {
+			if checksums := newChecksumVerifier(head.Headers); checksums != nil {
+				if _, err := io.Copy(checksums, io.NewSectionReader(file, 0, size)); err != nil {
+					return errors.WithStack(err)
+				}
+				return checksums.Verify()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := ContentWithData([]byte{}, head.Type, uint64(size), head.Headers, head.Cookies)
+	content.StatusCode = head.StatusCode
+	content.Proto = head.Proto
+	return content, nil
+}
+
+// segments gets the number of segments to use for the given size
+func (manager DownloadManager) segments(size int64) int {
+	segments := manager.Segments
+	if segments <= 0 {
+		segments = DefaultDownloadSegments
+	}
+	minSegmentSize := manager.MinSegmentSize
+	if minSegmentSize <= 0 {
+		minSegmentSize = DefaultMinSegmentSize
+	}
+	if maxSegments := size / minSegmentSize; int64(segments) > maxSegments {
+		segments = int(maxSegments)
+	}
+	return segments
+}
+
+// downloadSegments downloads the segments in parallel, each one is written at its offset in the file
+//
+// The first failing segment cancels the others.
+func (manager DownloadManager) downloadSegments(options *Options, file *os.File, size int64, segments int) error {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var progress io.Writer
+	if options.ProgressWriter != nil {
+		progress = &lockedWriter{Writer: options.ProgressWriter}
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	segmentSize := size / int64(segments)
+	for segment := 0; segment < segments; segment++ {
+		start := int64(segment) * segmentSize
+		end := start + segmentSize - 1
+		if segment == segments-1 {
+			end = size - 1
+		}
+		segmentOptions := *options
+		segmentOptions.Context = ctx
+		segmentOptions.Method = http.MethodGet
+		segmentOptions.VerifyChecksum = false
+		segmentOptions.ProgressWriter = progress
+		segmentOptions.ProgressSetMaxFunc = nil
+		segmentOptions.ProgressFunc = nil
+		segmentOptions.RequestID = ""
+		segmentOptions.Headers = map[string]string{}
+		for key, value := range options.Headers {
+			segmentOptions.Headers[key] = value
+		}
+		if options.Header != nil {
+			segmentOptions.Header = options.Header.Clone()
+			segmentOptions.Header.Del("Range")
+		}
+		segmentOptions.Headers["Range"] = "bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := downloadSegment(&segmentOptions, io.NewOffsetWriter(file, start), end-start+1)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// downloadSegment downloads a segment and verifies the server sent the requested range
+func downloadSegment(options *Options, writer io.Writer, length int64) error {
+	content, err := Send(options, writer)
+	if err != nil {
+		return err
+	}
+	if content.StatusCode != http.StatusPartialContent {
+		return errors.ArgumentInvalid.With("StatusCode", content.StatusCode)
+	}
+	if int64(content.Length) != length {
+		return ContentLengthMismatch.With(strconv.FormatUint(content.Length, 10), length)
+	}
+	if contentRange := content.Headers.Get("Content-Range"); !strings.HasPrefix(contentRange, strings.Replace(options.Headers["Range"], "=", " ", 1)+"/") {
+		return errors.ArgumentInvalid.With("Content-Range", contentRange)
+	}
+	return nil
+}
+
+// lockedWriter serializes the writes of parallel segments
+type lockedWriter struct {
+	io.Writer
+	lock sync.Mutex
+}
+
+func (writer *lockedWriter) Write(data []byte) (int, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	return writer.Writer.Write(data)
+}
@@ -0,0 +1,90 @@
+package request_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/gildas/go-request"
+)
+
+func createDownloadData(size int) []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), size/16)
+}
+
+func (suite *RequestSuite) TestCanDownloadWithSegments() {
+	data := createDownloadData(64 * 1024)
+	requests := atomic.Int32{}
+	server := CreateRangeTestServer(data, true, &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/files/data.bin")
+
+	folder := suite.T().TempDir()
+	manager := request.DownloadManager{Directory: folder, Segments: 4, MinSegmentSize: 1024}
+	progress := &progressWriter2{}
+	content, err := manager.Download(&request.Options{
+		URL:            serverURL,
+		ProgressWriter: progress,
+		Logger:         suite.Logger,
+	}, "")
+	suite.Require().NoError(err, "Failed downloading, err=%+v", err)
+	suite.Assert().Equal(uint64(len(data)), content.Length)
+	suite.Assert().Equal(int32(5), requests.Load(), "There should be 1 HEAD and 4 segment requests")
+	suite.Assert().Equal(int64(len(data)), progress.Max)
+	suite.Assert().Equal(int64(len(data)), progress.Total)
+
+	downloaded, err := os.ReadFile(filepath.Join(folder, "data.bin"))
+	suite.Require().NoError(err, "Failed reading the downloaded file")
+	suite.Assert().True(bytes.Equal(data, downloaded), "The downloaded file should be the same as the served data")
+}
+
+func (suite *RequestSuite) TestCanDownloadWithSegmentsAndVerifyChecksum() {
+	data := createDownloadData(64 * 1024)
+	checksum := sha256.Sum256(data)
+	requests := atomic.Int32{}
+	server := CreateRangeTestServer(data, true, &requests)
+	defer server.Close()
+	checksumServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("x-amz-checksum-sha256", base64.StdEncoding.EncodeToString(checksum[:]))
+		server.Config.Handler.ServeHTTP(res, req)
+	}))
+	defer checksumServer.Close()
+	serverURL, _ := url.Parse(checksumServer.URL)
+
+	folder := suite.T().TempDir()
+	manager := request.DownloadManager{Directory: folder, MinSegmentSize: 1024}
+	_, err := manager.Download(&request.Options{
+		URL:            serverURL,
+		VerifyChecksum: true,
+		Logger:         suite.Logger,
+	}, "data.bin")
+	suite.Require().NoError(err, "Failed downloading, err=%+v", err)
+	suite.Assert().Equal(int32(1+request.DefaultDownloadSegments), requests.Load())
+}
+
+func (suite *RequestSuite) TestCanDownloadWithoutSegmentsWhenRangesAreNotSupported() {
+	data := createDownloadData(64 * 1024)
+	requests := atomic.Int32{}
+	server := CreateRangeTestServer(data, false, &requests)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	folder := suite.T().TempDir()
+	manager := request.DownloadManager{Directory: folder, MinSegmentSize: 1024}
+	content, err := manager.Download(&request.Options{
+		URL:    serverURL,
+		Logger: suite.Logger,
+	}, "data.bin")
+	suite.Require().NoError(err, "Failed downloading, err=%+v", err)
+	suite.Assert().Equal(uint64(len(data)), content.Length)
+	suite.Assert().Equal(int32(2), requests.Load(), "There should be 1 HEAD and 1 GET requests")
+	downloaded, err := os.ReadFile(filepath.Join(folder, "data.bin"))
+	suite.Require().NoError(err, "Failed reading the downloaded file")
+	suite.Assert().True(bytes.Equal(data, downloaded), "The downloaded file should be the same as the served data")
+}
@@ -0,0 +1,97 @@
+package request_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanDownloadToFile() {
+	folder := suite.T().TempDir()
+	path := filepath.Join(folder, "data.txt")
+	serverURL, _ := url.Parse(suite.Server.URL)
+	serverURL, _ = serverURL.Parse("/checksum")
+	content, err := request.Download(&request.Options{
+		URL:            serverURL,
+		VerifyChecksum: true,
+		Logger:         suite.Logger,
+	}, path)
+	suite.Require().NoError(err, "Failed downloading, err=%+v", err)
+	suite.Assert().Equal(uint64(4), content.Length)
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err, "Failed reading the downloaded file")
+	suite.Assert().Equal("body", string(data))
+	entries, _ := os.ReadDir(folder)
+	suite.Assert().Len(entries, 1, "The temporary file should have been renamed")
+}
+
+func (suite *RequestSuite) TestShouldNotOverwriteFileWhenDownloadFails() {
+	folder := suite.T().TempDir()
+	path := filepath.Join(folder, "data.txt")
+	suite.Require().NoError(os.WriteFile(path, []byte("previous"), 0644))
+	serverURL, _ := url.Parse(suite.Server.URL)
+	serverURL, _ = serverURL.Parse("/checksum_mismatch")
+	_, err := request.Download(&request.Options{
+		URL:            serverURL,
+		VerifyChecksum: true,
+		Logger:         suite.Logger,
+	}, path)
+	suite.Require().Error(err, "Should have failed downloading")
+	suite.Assert().ErrorIs(err, request.ChecksumMismatch)
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err, "Failed reading the file")
+	suite.Assert().Equal("previous", string(data), "The file should not have been overwritten")
+	entries, _ := os.ReadDir(folder)
+	suite.Assert().Len(entries, 1, "The temporary file should have been removed")
+}
+
+func (suite *RequestSuite) TestShouldFailDownloadingWithoutPath() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	_, err := request.Download(&request.Options{URL: serverURL, Logger: suite.Logger}, "")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
+
+func (suite *RequestSuite) TestCanSaveContentToFile() {
+	path := filepath.Join(suite.T().TempDir(), "data.txt")
+	content := request.ContentWithData([]byte("body"), "text/plain")
+	err := content.SaveToFile(path)
+	suite.Require().NoError(err, "Failed saving the content, err=%+v", err)
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err, "Failed reading the saved file")
+	suite.Assert().Equal("body", string(data))
+}
+
+func (suite *RequestSuite) TestCanWriteContentToFileWithPermissions() {
+	path := filepath.Join(suite.T().TempDir(), "secret.txt")
+	content := request.ContentWithData([]byte("secret"), "text/plain")
+	err := content.WriteToFile(path, 0600)
+	suite.Require().NoError(err, "Failed writing the content, err=%+v", err)
+	info, err := os.Stat(path)
+	suite.Require().NoError(err, "Failed to stat the written file")
+	suite.Assert().Equal(os.FileMode(0600), info.Mode().Perm())
+}
+
+func (suite *RequestSuite) TestCanCreateContentFromFile() {
+	folder := suite.T().TempDir()
+	path := filepath.Join(folder, "data.json")
+	suite.Require().NoError(os.WriteFile(path, []byte(`{"ID":"1234"}`), 0600))
+	content, err := request.ContentFromFile(path)
+	suite.Require().NoError(err, "Failed reading the file, err=%+v", err)
+	suite.Assert().Equal("data.json", content.Name)
+	suite.Assert().Equal("application/json", content.Type)
+	suite.Assert().Equal(uint64(13), content.Length)
+	suite.Assert().Equal(`{"ID":"1234"}`, string(content.Data))
+
+	path = filepath.Join(folder, "image.unknown")
+	suite.Require().NoError(os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n"), 0600))
+	content, err = request.ContentFromFile(path)
+	suite.Require().NoError(err, "Failed reading the file, err=%+v", err)
+	suite.Assert().Equal("image/png", content.Type, "Content Type should be sniffed")
+
+	_, err = request.ContentFromFile(filepath.Join(folder, "missing.txt"))
+	suite.Assert().ErrorIs(err, errors.NotFound)
+}
@@ -0,0 +1,87 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// Encoder encodes the Payload of a request
+type Encoder interface {
+	Encode(payload interface{}) ([]byte, error)
+}
+
+// EncoderFunc is a function that implements Encoder
+type EncoderFunc func(payload interface{}) ([]byte, error)
+
+// Encode encodes the payload
+func (encoder EncoderFunc) Encode(payload interface{}) ([]byte, error) {
+	return encoder(payload)
+}
+
+// jsonValue gets the payload as encoding/json would decode it in an interface{} (with json.Number for numbers)
+//
+// Encoders of JSON-like formats use it to map Go values like encoding/json does.
+func jsonValue(payload interface{}) (interface{}, error) {
+	data, err := marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&value); err != nil {
+		return nil, errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	return value, nil
+}
+
+// encoders are the registered Encoders, by media type
+var encoders = struct {
+	entries map[string]Encoder
+	lock    sync.RWMutex
+}{
+	entries: map[string]Encoder{
+		MsgPackMediaType:        MsgPackEncoder,
+		"application/x-msgpack": MsgPackEncoder,
+		CBORMediaType:           CBOREncoder,
+	},
+}
+
+// RegisterEncoder registers an Encoder for a media type
+//
+// When the PayloadType of a request is a registered media type, its Payload is encoded with that Encoder.
+// Registering a media type again replaces its Encoder.
+func RegisterEncoder(mediaType string, encoder Encoder) error {
+	if len(mediaType) == 0 {
+		return errors.ArgumentMissing.With("mediaType")
+	}
+	if encoder == nil {
+		return errors.ArgumentMissing.With("encoder")
+	}
+	encoders.lock.Lock()
+	defer encoders.lock.Unlock()
+	encoders.entries[strings.ToLower(mediaType)] = encoder
+	return nil
+}
+
+// UnregisterEncoder removes the Encoder registered for a media type
+func UnregisterEncoder(mediaType string) {
+	encoders.lock.Lock()
+	defer encoders.lock.Unlock()
+	delete(encoders.entries, strings.ToLower(mediaType))
+}
+
+// encoderFor gets the Encoder registered for the given media type
+func encoderFor(mediaType string) (Encoder, bool) {
+	if len(mediaType) == 0 {
+		return nil, false
+	}
+	encoders.lock.RLock()
+	defer encoders.lock.RUnlock()
+	encoder, found := encoders.entries[strings.ToLower(mediaType)]
+	return encoder, found
+}
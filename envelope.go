@@ -0,0 +1,163 @@
+package request
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// ResponseEnvelope detects errors carried in the body of responses, even successful ones
+//
+// Check is called with the Content of the response before the results are decoded.
+// It returns nil if the Content does not carry an error.
+type ResponseEnvelope interface {
+	Check(content *Content) error
+}
+
+// ResponseEnvelopeFunc is a function that implements ResponseEnvelope
+type ResponseEnvelopeFunc func(content *Content) error
+
+// Check checks the Content of the response
+func (envelope ResponseEnvelopeFunc) Check(content *Content) error {
+	return envelope(content)
+}
+
+// JSONErrorEnvelope detects the errors of JSON responses shaped like:
+//
+//	{"error": {"code": 404, "message": "Not found", "status": "NOT_FOUND"}}
+//	{"error": "invalid_grant", "error_description": "The code has expired"}
+//	{"success": false, "code": "E42", "message": "Not found"}
+type JSONErrorEnvelope struct {
+	ErrorField   string // the member that carries the error, by default: error
+	SuccessField string // the boolean member that tells if the request succeeded, by default: success
+}
+
+// EnvelopeError describes an error carried in the body of a response
+//
+// Send returns it as the error when the ResponseEnvelope of the Options finds one:
+//
+//	var envelope *request.EnvelopeError
+//	if errors.As(err, &envelope) {
+//	    log.Errorf("%s: %s", envelope.Code, envelope.Message)
+//	}
+type EnvelopeError struct {
+	Code       string                 // the code of the error, if any
+	Message    string                 // the message of the error, if any
+	Status     string                 // the status of the error, if any (e.g.: NOT_FOUND)
+	StatusCode int                    // the HTTP status of the response
+	Details    map[string]interface{} // the members of the error
+	Cause      error                  // the error of the HTTP status of the response or of the Code, if any
+}
+
+// Check checks the Content of the response
+//
+// implements ResponseEnvelope
+func (envelope JSONErrorEnvelope) Check(content *Content) error {
+	if content == nil || !looksLikeJSON(content.Data) {
+		return nil
+	}
+	errorField := envelope.ErrorField
+	if len(errorField) == 0 {
+		errorField = "error"
+	}
+	successField := envelope.SuccessField
+	if len(successField) == 0 {
+		successField = "success"
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(jsonData(content.Data), &members); err != nil {
+		return nil // not an object, there is no envelope
+	}
+	envelopeError := EnvelopeError{StatusCode: content.StatusCode}
+	switch value := members[errorField].(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			break
+		}
+		envelopeError.Details = value
+		envelopeError.Code = envelopeString(value["code"])
+		envelopeError.Message = envelopeString(value["message"])
+		envelopeError.Status = envelopeString(value["status"])
+		return envelopeError.withCause()
+	case string:
+		if len(value) == 0 {
+			break
+		}
+		envelopeError.Details = members
+		if description := envelopeString(members[errorField+"_description"]); len(description) > 0 {
+			envelopeError.Code = value
+			envelopeError.Message = description
+		} else {
+			envelopeError.Message = value
+		}
+		return envelopeError.withCause()
+	}
+	if success, ok := members[successField].(bool); ok && !success {
+		envelopeError.Details = members
+		envelopeError.Code = envelopeString(members["code"])
+		envelopeError.Message = envelopeString(members["message"])
+		envelopeError.Status = envelopeString(members["status"])
+		return envelopeError.withCause()
+	}
+	return nil
+}
+
+// withCause sets the Cause of the EnvelopeError from its Code or its StatusCode
+func (envelopeError EnvelopeError) withCause() *EnvelopeError {
+	if code, err := strconv.Atoi(envelopeError.Code); err == nil && code >= 400 && code < 600 {
+		envelopeError.Cause = errors.FromHTTPStatusCode(code)
+	} else if envelopeError.StatusCode >= 400 {
+		envelopeError.Cause = errors.FromHTTPStatusCode(envelopeError.StatusCode)
+	}
+	return &envelopeError
+}
+
+// envelopeString gets the string version of a member of an envelope
+func envelopeString(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		payload, _ := json.Marshal(value)
+		return string(payload)
+	}
+}
+
+// checkEnvelope checks the Content with the ResponseEnvelope, if any
+func checkEnvelope(envelope ResponseEnvelope, content *Content) error {
+	if envelope == nil {
+		return nil
+	}
+	return envelope.Check(content)
+}
+
+// Error returns the string version of this error
+//
+// implements error
+func (envelopeError EnvelopeError) Error() string {
+	sb := strings.Builder{}
+	if len(envelopeError.Message) > 0 {
+		sb.WriteString(envelopeError.Message)
+	} else if envelopeError.Cause != nil {
+		sb.WriteString(envelopeError.Cause.Error())
+	} else {
+		sb.WriteString("Error in the response")
+	}
+	if len(envelopeError.Code) > 0 {
+		sb.WriteString(" (")
+		sb.WriteString(envelopeError.Code)
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the HTTP status
+func (envelopeError EnvelopeError) Unwrap() error {
+	return envelopeError.Cause
+}
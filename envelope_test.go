@@ -0,0 +1,92 @@
+package request_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateEnvelopeTestServer(suite *RequestSuite) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/google":
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "No row found for id invalid-deadbeef", "status": "NOT_FOUND"}}`))
+		case "/oauth":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "The code has expired"}`))
+		case "/failure":
+			_, _ = w.Write([]byte(`{"ok": false, "code": "E42", "message": "Unknown user"}`))
+		default:
+			_, _ = w.Write([]byte(`{"error": null, "success": true, "id": "1234"}`))
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsWithoutEnvelopeError() {
+	server := CreateEnvelopeTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	results := stuff{}
+	_, err := request.Send(&request.Options{URL: serverURL, ResponseEnvelope: request.JSONErrorEnvelope{}, Logger: suite.Logger}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("1234", results.ID)
+}
+
+func (suite *RequestSuite) TestShouldFailReceivingResultsWithEnvelopeError() {
+	server := CreateEnvelopeTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/google")
+
+	results := stuff{}
+	content, err := request.Send(&request.Options{URL: serverURL, ResponseEnvelope: request.JSONErrorEnvelope{}, Logger: suite.Logger}, &results)
+	suite.Require().Error(err, "Should have failed sending request")
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal(http.StatusOK, content.StatusCode)
+	suite.Assert().ErrorIs(err, errors.HTTPNotFound, "Envelope error should wrap the error of its code")
+
+	var envelope *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelope, "Error should be an EnvelopeError")
+	suite.Assert().Equal("404", envelope.Code)
+	suite.Assert().Equal("NOT_FOUND", envelope.Status)
+	suite.Assert().Equal("No row found for id invalid-deadbeef (404)", envelope.Error())
+
+	// Without a ResponseEnvelope, the body is decoded as usual
+	_, err = request.Send(&request.Options{URL: serverURL, Logger: suite.Logger}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+}
+
+func (suite *RequestSuite) TestShouldFailReceivingResultsWithEnvelopeErrorInStatusError() {
+	server := CreateEnvelopeTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/oauth")
+
+	_, err := request.Send(&request.Options{URL: serverURL, ResponseEnvelope: request.JSONErrorEnvelope{}, Logger: suite.Logger}, nil)
+	suite.Require().Error(err, "Should have failed sending request")
+	suite.Assert().ErrorIs(err, errors.HTTPBadRequest, "Envelope error should wrap the HTTP status error")
+
+	var envelope *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelope, "Error should be an EnvelopeError")
+	suite.Assert().Equal("invalid_grant", envelope.Code)
+	suite.Assert().Equal("The code has expired", envelope.Message)
+}
+
+func (suite *RequestSuite) TestShouldFailReceivingResultsWithCustomSuccessField() {
+	server := CreateEnvelopeTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/failure")
+
+	_, err := request.Send(&request.Options{URL: serverURL, ResponseEnvelope: request.JSONErrorEnvelope{SuccessField: "ok"}, Logger: suite.Logger}, nil)
+	suite.Require().Error(err, "Should have failed sending request")
+
+	var envelope *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelope, "Error should be an EnvelopeError")
+	suite.Assert().Equal("E42", envelope.Code)
+	suite.Assert().Equal("Unknown user (E42)", envelope.Error())
+	suite.Assert().Nil(envelope.Unwrap(), "Envelope error should not have a cause with a 200 status")
+}
@@ -0,0 +1,55 @@
+package request
+
+import (
+	"net/http"
+
+	"github.com/gildas/go-errors"
+)
+
+// AsyncOperationFailed is returned when an asynchronous operation ends with a failed or canceled status
+var AsyncOperationFailed = errors.NewSentinel(http.StatusBadGateway, "error.async.operation.failed", "Asynchronous operation %s failed (%v)")
+
+// CBORInvalid is returned when CBOR data cannot be decoded
+var CBORInvalid = errors.NewSentinel(http.StatusBadRequest, "error.cbor.invalid", "Invalid CBOR data (%s: %v)")
+
+// CertificatePinningFailed is returned when none of the server certificates matches the pinned certificates given in the Options
+var CertificatePinningFailed = errors.NewSentinel(http.StatusBadGateway, "error.tls.pinning.failed", "None of the certificates of %s matches the pinned certificates")
+
+// ChecksumMismatch is returned when the checksum of a response body does not match the one given in its headers
+var ChecksumMismatch = errors.NewSentinel(http.StatusBadGateway, "error.checksum.mismatch", "Checksum mismatch for %s (expected: %v)")
+
+// ContentLengthMismatch is returned when the length of a response body does not match its Content-Length header
+var ContentLengthMismatch = errors.NewSentinel(http.StatusBadGateway, "error.content.length.mismatch", "Content Length mismatch (read: %s, expected: %v)")
+
+// DecryptionFailed is returned when an encrypted Content cannot be decrypted or authenticated
+var DecryptionFailed = errors.NewSentinel(http.StatusBadRequest, "error.crypto.decryption.failed", "Failed to decrypt the Content with %s")
+
+// JWSSignatureInvalid is returned when the signature of a JWS cannot be verified
+var JWSSignatureInvalid = errors.NewSentinel(http.StatusBadRequest, "error.jws.signature.invalid", "Invalid JWS signature with %s")
+
+// MsgPackInvalid is returned when MessagePack data cannot be decoded
+var MsgPackInvalid = errors.NewSentinel(http.StatusBadRequest, "error.msgpack.invalid", "Invalid MessagePack data (%s: %v)")
+
+// PollTimeout is returned when a polled operation is not done before the maximum wait
+var PollTimeout = errors.NewSentinel(http.StatusGatewayTimeout, "error.poll.timeout", "Polling %s did not complete in %v")
+
+// ResponseHeaderInvalid is returned when a response header required by the Options is missing or does not have the required value
+var ResponseHeaderInvalid = errors.NewSentinel(http.StatusBadGateway, "error.http.response.header.invalid", "Response Header %s is missing or invalid (expected: %v)")
+
+// ResponseHeadersTooLarge is returned when the response headers exceed the limits given in the Options or its Transport
+var ResponseHeadersTooLarge = errors.NewSentinel(http.StatusBadGateway, "error.http.response.headers.toolarge", "Response Headers are too large (%s: %v)")
+
+// ResponseSignatureInvalid is returned when the signature of a response cannot be verified
+var ResponseSignatureInvalid = errors.NewSentinel(http.StatusBadGateway, "error.http.response.signature.invalid", "Invalid Response Signature in %s")
+
+// ResultPathNotFound is returned when the ResultPath of the Options does not match the response body
+var ResultPathNotFound = errors.NewSentinel(http.StatusBadGateway, "error.http.response.resultpath.notfound", "Result Path %s not found in the response (missing: %v)")
+
+// RetryBudgetExceeded is returned when the delay before the next attempt goes past the deadline of the request context
+var RetryBudgetExceeded = errors.NewSentinel(http.StatusGatewayTimeout, "error.retry.budget.exceeded", "Retry delay %s exceeds the time left before the deadline (%v)")
+
+// UnsupportedURLScheme is returned when the scheme of the URL is not http, https, or one of the schemes allowed in the Options
+var UnsupportedURLScheme = errors.NewSentinel(http.StatusBadRequest, "error.url.scheme.unsupported", "Unsupported URL Scheme \"%s\" (allowed: %v)")
+
+// UnexpectedStatus is returned when the status of a response is not one of the expected statuses given in the Options
+var UnexpectedStatus = errors.NewSentinel(http.StatusBadGateway, "error.http.status.unexpected", "Unexpected Response Status %s (expected: %v)")
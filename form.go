@@ -0,0 +1,387 @@
+package request
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// FormField is a field of a form
+//
+// When the Payload is a []FormField, the fields are sent in the given order and a field can be repeated (e.g.: several "tags").
+// This matters to servers that require some order, like S3 POST policy uploads that require the file last.
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// formFields collects the fields of a form payload
+//
+// The payload can be nil, a []FormField, a map (sorted by key), or a struct (in the order of its fields, see encodeFormFields).
+func formFields(log *logger.Logger, payload interface{}) ([]FormField, error) {
+	if payload == nil {
+		return []FormField{}, nil
+	}
+	if fields, ok := payload.([]FormField); ok {
+		log.Tracef("Payload is a list of FormFields")
+		return fields, nil
+	}
+	if stringMap, ok := payload.(map[string]string); ok {
+		log.Tracef("Payload is a StringMap")
+		attributes := url.Values{}
+		for key, value := range stringMap {
+			attributes.Set(key, value)
+		}
+		return valuesFields(attributes), nil
+	}
+	items := reflect.ValueOf(payload)
+	if items.Kind() == reflect.Map { // traverse the map, formatting primitives, slices, and Stringer values. Note: This can be slow...
+		log.Tracef("Payload is a Map")
+		attributes := url.Values{}
+		for _, item := range items.MapKeys() {
+			attributes[fmt.Sprint(item.Interface())] = formatFormValue(items.MapIndex(item), "", false)
+		}
+		return valuesFields(attributes), nil
+	}
+	log.Tracef("Payload is a Struct, encoding it as form fields")
+	return encodeFormFields(payload)
+}
+
+// encodeForm encodes the exported fields of a struct into url.Values
+//
+// See encodeFormFields for the struct tags.
+func encodeForm(payload interface{}) (url.Values, error) {
+	fields, err := encodeFormFields(payload)
+	if err != nil {
+		return nil, err
+	}
+	return fieldsValues(fields), nil
+}
+
+// encodeFormFields encodes the exported fields of a struct into FormFields, in the order of the struct
+//
+// The field names are given by the "url" or "form" struct tags, the field name is used otherwise.
+// The tag options are:
+//   - "-" to skip the field
+//   - "omitempty" to skip the field when it has its zero value
+//   - "unix" to format a time.Time as Unix seconds
+//
+// time.Time fields are formatted as RFC 3339 unless a "layout" struct tag is provided.
+// Slices and arrays are encoded as repeated fields.
+func encodeFormFields(payload interface{}) ([]FormField, error) {
+	value := reflect.Indirect(reflect.ValueOf(payload))
+	if value.Kind() != reflect.Struct {
+		return nil, errors.ArgumentInvalid.With("payload", fmt.Sprintf("%T", payload))
+	}
+	fields := []FormField{}
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, found := field.Tag.Lookup("url")
+		if !found {
+			tag = field.Tag.Get("form")
+		}
+		name, tagOptions, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		fieldValue := value.Field(i)
+		if strings.Contains(tagOptions, "omitempty") && fieldValue.IsZero() {
+			continue
+		}
+		for _, formatted := range formatFormValue(fieldValue, field.Tag.Get("layout"), strings.Contains(tagOptions, "unix")) {
+			fields = append(fields, FormField{Name: name, Value: formatted})
+		}
+	}
+	return fields, nil
+}
+
+// encodeFields encodes FormFields as an application/x-www-form-urlencoded form, in their order
+func encodeFields(fields []FormField) string {
+	var encoded strings.Builder
+	for index, field := range fields {
+		if index > 0 {
+			encoded.WriteByte('&')
+		}
+		encoded.WriteString(url.QueryEscape(field.Name))
+		encoded.WriteByte('=')
+		encoded.WriteString(url.QueryEscape(field.Value))
+	}
+	return encoded.String()
+}
+
+// fieldsValues gets the url.Values of FormFields
+func fieldsValues(fields []FormField) url.Values {
+	values := url.Values{}
+	for _, field := range fields {
+		values.Add(field.Name, field.Value)
+	}
+	return values
+}
+
+// valuesFields gets the FormFields of url.Values, sorted by key
+func valuesFields(values url.Values) []FormField {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := []FormField{}
+	for _, key := range keys {
+		for _, value := range values[key] {
+			fields = append(fields, FormField{Name: key, Value: value})
+		}
+	}
+	return fields
+}
+
+// EncodeQuery encodes the exported fields of a struct into query values
+//
+// The field names are given by the "url" struct tags, see Options.QueryValues.
+// The tag options are "-", "omitempty", and "unix". time.Time fields are formatted as RFC 3339 unless a "layout" struct tag is provided.
+// Slices and arrays are encoded as repeated keys.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	return encodeForm(v)
+}
+
+// formatFormValue formats a value for a form or a query
+//
+// Slices and arrays give as many strings as they have items, nil pointers give no string.
+func formatFormValue(value reflect.Value, layout string, unix bool) []string {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return []string{}
+		}
+		value = value.Elem()
+	}
+	if timestamp, ok := value.Interface().(time.Time); ok {
+		switch {
+		case unix:
+			return []string{strconv.FormatInt(timestamp.Unix(), 10)}
+		case len(layout) > 0:
+			return []string{timestamp.Format(layout)}
+		default:
+			return []string{timestamp.Format(time.RFC3339)}
+		}
+	}
+	if stringer, ok := value.Interface().(fmt.Stringer); ok {
+		return []string{stringer.String()}
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return []string{value.String()}
+	case reflect.Bool:
+		return []string{strconv.FormatBool(value.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(value.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(value.Uint(), 10)}
+	case reflect.Float32:
+		return []string{strconv.FormatFloat(value.Float(), 'f', -1, 32)}
+	case reflect.Float64:
+		return []string{strconv.FormatFloat(value.Float(), 'f', -1, 64)}
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 && value.Kind() == reflect.Slice {
+			return []string{string(value.Bytes())}
+		}
+		values := []string{}
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, formatFormValue(value.Index(i), layout, unix)...)
+		}
+		return values
+	}
+	return []string{fmt.Sprint(value.Interface())}
+}
+
+// decodeForm decodes form values into the results
+//
+// The results can be a pointer to url.Values, map[string][]string, map[string]string (first values),
+// map[string]interface{} (a string, or a []interface{} for repeated keys), json.RawMessage (a JSON object like the map), or a struct.
+//
+// Struct fields are matched with the "url" or "form" struct tags, or with their name (case-insensitive).
+// Their values are parsed according to their type (strings, numbers, booleans, time.Time with the "layout" and "unix" tags,
+// encoding.TextUnmarshaler, slices for repeated keys, and pointers to these).
+func decodeForm(values url.Values, results interface{}) error {
+	switch typed := results.(type) {
+	case *url.Values:
+		*typed = values
+		return nil
+	case *map[string][]string:
+		*typed = values
+		return nil
+	case *map[string]string:
+		*typed = make(map[string]string, len(values))
+		for key := range values {
+			(*typed)[key] = values.Get(key)
+		}
+		return nil
+	case *map[string]interface{}:
+		*typed = formValuesMap(values)
+		return nil
+	case *json.RawMessage:
+		payload, err := json.Marshal(formValuesMap(values))
+		if err != nil {
+			return errors.JSONMarshalError.Wrap(err)
+		}
+		*typed = payload
+		return nil
+	}
+	value := reflect.ValueOf(results)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.ArgumentInvalid.With("results", fmt.Sprintf("%T", results))
+	}
+	value = value.Elem()
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, found := field.Tag.Lookup("url")
+		if !found {
+			tag = field.Tag.Get("form")
+		}
+		name, tagOptions, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		fieldValues, found := values[name]
+		if len(name) == 0 {
+			for key, keyValues := range values {
+				if strings.EqualFold(key, field.Name) {
+					fieldValues, found = keyValues, true
+					break
+				}
+			}
+			name = field.Name
+		}
+		if !found || len(fieldValues) == 0 {
+			continue
+		}
+		if err := parseFormValue(value.Field(i), fieldValues, field.Tag.Get("layout"), strings.Contains(tagOptions, "unix")); err != nil {
+			return errors.WrapErrors(errors.ArgumentInvalid.With(name, fieldValues[0]), err)
+		}
+	}
+	return nil
+}
+
+// formValuesMap gets the values of a form as a map of strings, or []interface{} for repeated keys
+func formValuesMap(values url.Values) map[string]interface{} {
+	items := make(map[string]interface{}, len(values))
+	for key, keyValues := range values {
+		if len(keyValues) == 1 {
+			items[key] = keyValues[0]
+			continue
+		}
+		list := make([]interface{}, 0, len(keyValues))
+		for _, keyValue := range keyValues {
+			list = append(list, keyValue)
+		}
+		items[key] = list
+	}
+	return items
+}
+
+// parseFormValue parses form values into a value, this is the reverse of formatFormValue
+func parseFormValue(value reflect.Value, values []string, layout string, unix bool) error {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return parseFormValue(value.Elem(), values, layout, unix)
+	}
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for index, item := range values {
+			if err := parseFormValue(slice.Index(index), []string{item}, layout, unix); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+	text := values[0]
+	if _, ok := value.Interface().(time.Time); ok {
+		var timestamp time.Time
+		if unix {
+			seconds, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			timestamp = time.Unix(seconds, 0).UTC()
+		} else {
+			if len(layout) == 0 {
+				layout = time.RFC3339
+			}
+			parsed, err := time.Parse(layout, text)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			timestamp = parsed
+		}
+		value.Set(reflect.ValueOf(timestamp))
+		return nil
+	}
+	if unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return errors.WithStack(unmarshaler.UnmarshalText([]byte(text)))
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Slice: // []byte
+		value.SetBytes([]byte(text))
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if _, ok := value.Interface().(time.Duration); ok {
+			if duration, err := time.ParseDuration(text); err == nil {
+				value.SetInt(int64(duration))
+				return nil
+			}
+		}
+		parsed, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		value.SetFloat(parsed)
+	case reflect.Interface:
+		if value.NumMethod() > 0 {
+			return errors.ArgumentInvalid.With("type", value.Type().String())
+		}
+		value.Set(reflect.ValueOf(text))
+	default:
+		return errors.ArgumentInvalid.With("type", value.Type().String())
+	}
+	return nil
+}
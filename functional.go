@@ -0,0 +1,207 @@
+package request
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// Option configures a request sent by SendWith
+//
+// Options are applied in order, they return an error when they conflict with an Option applied before (e.g.: 2 payloads).
+type Option func(call *functionalCall) error
+
+// functionalCall collects the Options and the results of a request sent by SendWith
+type functionalCall struct {
+	options    Options
+	results    interface{}
+	hasURL     bool
+	hasPayload bool
+	hasResults bool
+}
+
+// SendWith sends an HTTP request configured by the given Options
+//
+// It is an alternative to Send that builds the Options struct from functional options:
+//
+//	user := User{}
+//	_, err := request.SendWith(ctx,
+//	    request.To("https://api.acme.com/users"),
+//	    request.WithJSON(newUser),
+//	    request.WithRetry(3, time.Second),
+//	    request.Into(&user),
+//	)
+func SendWith(ctx context.Context, options ...Option) (*Content, error) {
+	call := functionalCall{options: Options{Context: ctx}}
+	for _, option := range options {
+		if option == nil {
+			continue
+		}
+		if err := option(&call); err != nil {
+			return nil, err
+		}
+	}
+	return Send(&call.options, call.results)
+}
+
+// To sends the request to the given URL
+func To(rawURL string) Option {
+	return func(call *functionalCall) error {
+		if len(rawURL) == 0 {
+			return errors.ArgumentMissing.With("URL")
+		}
+		target, err := parseURL(rawURL)
+		if err != nil {
+			return err
+		}
+		return ToURL(target)(call)
+	}
+}
+
+// ToURL sends the request to the given URL
+func ToURL(target *url.URL) Option {
+	return func(call *functionalCall) error {
+		if target == nil {
+			return errors.ArgumentMissing.With("URL")
+		}
+		if call.hasURL {
+			return errors.DuplicateFound.With("Option", "URL")
+		}
+		call.options.URL = target
+		call.hasURL = true
+		return nil
+	}
+}
+
+// WithMethod sends the request with the given HTTP method, by default: GET, or POST if there is a payload
+func WithMethod(method string) Option {
+	return func(call *functionalCall) error {
+		call.options.Method = method
+		return nil
+	}
+}
+
+// WithHeader adds a header to the request
+func WithHeader(key, value string) Option {
+	return func(call *functionalCall) error {
+		if call.options.Headers == nil {
+			call.options.Headers = map[string]string{}
+		}
+		call.options.Headers[key] = value
+		return nil
+	}
+}
+
+// WithQuery adds a query parameter to the request, it can be repeated
+func WithQuery(key, value string) Option {
+	return func(call *functionalCall) error {
+		if call.options.QueryValues == nil {
+			call.options.QueryValues = url.Values{}
+		}
+		call.options.QueryValues.Add(key, value)
+		return nil
+	}
+}
+
+// WithAuthorization sends the given Authorization header (e.g.: BearerAuthorization(token))
+func WithAuthorization(authorization string) Option {
+	return func(call *functionalCall) error {
+		call.options.Authorization = authorization
+		return nil
+	}
+}
+
+// WithPayload sends the given payload with the given type, see Options.Payload
+func WithPayload(payload interface{}, payloadType string) Option {
+	return func(call *functionalCall) error {
+		if payload == nil {
+			return errors.ArgumentMissing.With("payload")
+		}
+		if call.hasPayload {
+			return errors.DuplicateFound.With("Option", "Payload")
+		}
+		call.options.Payload = payload
+		call.options.PayloadType = payloadType
+		call.hasPayload = true
+		return nil
+	}
+}
+
+// WithJSON sends the given payload as JSON
+func WithJSON(payload interface{}) Option {
+	return WithPayload(payload, "application/json")
+}
+
+// WithForm sends the given struct or map as an application/x-www-form-urlencoded form
+func WithForm(payload interface{}) Option {
+	return WithPayload(payload, "application/x-www-form-urlencoded")
+}
+
+// WithRetry sends the request up to attempts times, waiting delay between 2 attempts
+func WithRetry(attempts uint, delay time.Duration) Option {
+	return func(call *functionalCall) error {
+		if attempts == 0 {
+			return errors.ArgumentInvalid.With("attempts", attempts)
+		}
+		call.options.Attempts = attempts
+		call.options.InterAttemptDelay = delay
+		return nil
+	}
+}
+
+// WithTimeout gives up on each attempt after the given timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(call *functionalCall) error {
+		call.options.Timeout = timeout
+		return nil
+	}
+}
+
+// WithLogger logs the request with the given Logger
+func WithLogger(log *logger.Logger) Option {
+	return func(call *functionalCall) error {
+		call.options.Logger = log
+		return nil
+	}
+}
+
+// Expect fails the request if the response status is not one of the given statuses, see Options.ExpectStatus
+func Expect(statuses ...int) Option {
+	return func(call *functionalCall) error {
+		call.options.ExpectStatus = append(call.options.ExpectStatus, statuses...)
+		return nil
+	}
+}
+
+// WithOptions merges the given Options over the ones configured so far, see MergeOptions
+//
+// It gives access to every field of Options (e.g.: a preset like FCMOptions, or a template).
+func WithOptions(options *Options) Option {
+	return func(call *functionalCall) error {
+		if options == nil {
+			return errors.ArgumentMissing.With("options")
+		}
+		call.options = *MergeOptions(&call.options, options)
+		call.hasURL = call.hasURL || options.URL != nil
+		call.hasPayload = call.hasPayload || options.Payload != nil
+		return nil
+	}
+}
+
+// Into decodes the response into the given results, see Send
+func Into(results interface{}) Option {
+	return func(call *functionalCall) error {
+		if results == nil {
+			return errors.ArgumentMissing.With("results")
+		}
+		if call.hasResults {
+			return errors.DuplicateFound.With("Option", "results")
+		}
+		call.results = results
+		call.hasResults = true
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package request_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanSendRequestWithFunctionalOptions() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	serverURL, _ = serverURL.Parse("/query?sort=asc")
+	content, err := request.SendWith(context.Background(),
+		request.ToURL(serverURL),
+		request.WithQuery("id", "1"),
+		request.WithQuery("id", "2"),
+		request.WithHeader("X-Tenant", "acme"),
+		request.WithTimeout(5*time.Second),
+		request.WithLogger(suite.Logger),
+	)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("id=1&id=2&sort=asc", string(content.Data))
+}
+
+func (suite *RequestSuite) TestCanSendJSONWithFunctionalOptions() {
+	content, err := request.SendWith(context.Background(),
+		request.To(suite.Server.URL+"/item"),
+		request.WithJSON(stuff{"1234"}),
+		request.WithRetry(2, time.Second),
+		request.Expect(http.StatusOK),
+		request.WithOptions(&request.Options{RequestID: "1234"}),
+		request.WithLogger(suite.Logger),
+	)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("1234", string(content.Data))
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsWithFunctionalOptions() {
+	results := struct {
+		Code int `json:"code"`
+	}{}
+	_, err := request.SendWith(context.Background(),
+		request.To(suite.Server.URL+"/results"),
+		request.WithLogger(suite.Logger),
+		request.Into(&results),
+	)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal(1234, results.Code)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingRequestWithConflictingFunctionalOptions() {
+	_, err := request.SendWith(context.Background(), request.To(suite.Server.URL), request.To(suite.Server.URL))
+	suite.Assert().ErrorIs(err, errors.DuplicateFound, "2 URLs should conflict")
+
+	_, err = request.SendWith(context.Background(), request.To(suite.Server.URL), request.WithJSON(stuff{"1234"}), request.WithForm(stuff{"1234"}))
+	suite.Assert().ErrorIs(err, errors.DuplicateFound, "2 payloads should conflict")
+
+	results := stuff{}
+	_, err = request.SendWith(context.Background(), request.To(suite.Server.URL), request.Into(&results), request.Into(&results))
+	suite.Assert().ErrorIs(err, errors.DuplicateFound, "2 results should conflict")
+
+	_, err = request.SendWith(context.Background(), request.WithRetry(0, time.Second))
+	suite.Assert().ErrorIs(err, errors.ArgumentInvalid)
+
+	_, err = request.SendWith(context.Background(), request.To(""))
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+
+	_, err = request.SendWith(context.Background(), request.WithLogger(suite.Logger))
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing, "URL should be required")
+}
@@ -0,0 +1,111 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// HawkSigner signs requests with the Hawk HTTP authentication scheme
+//
+// When the request has a payload, its hash is signed as well.
+//
+// implements Signer
+type HawkSigner struct {
+	ID        string
+	Key       string
+	Algorithm string // "sha256" or "sha1", by default: "sha256"
+	Ext       string // optional application specific data
+}
+
+// Sign signs the request with the current time and a random nonce
+//
+// implements Signer
+func (signer HawkSigner) Sign(req *http.Request, payload []byte) error {
+	nonce := make([]byte, 6)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.WithStack(err)
+	}
+	return signer.SignAt(req, payload, time.Now(), hex.EncodeToString(nonce))
+}
+
+// SignAt signs the request as if it was sent at the given time with the given nonce
+func (signer HawkSigner) SignAt(req *http.Request, payload []byte, at time.Time, nonce string) error {
+	if len(signer.ID) == 0 {
+		return errors.ArgumentMissing.With("ID")
+	}
+	if len(signer.Key) == 0 {
+		return errors.ArgumentMissing.With("Key")
+	}
+	var newHash func() hash.Hash
+	switch signer.Algorithm {
+	case "", "sha256":
+		newHash = sha256.New
+	case "sha1":
+		newHash = sha1.New
+	default:
+		return errors.ArgumentInvalid.With("Algorithm", signer.Algorithm)
+	}
+
+	payloadHash := ""
+	if len(payload) > 0 {
+		contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		digest := newHash()
+		digest.Write([]byte("hawk.1.payload\n" + strings.ToLower(contentType) + "\n"))
+		digest.Write(payload)
+		digest.Write([]byte("\n"))
+		payloadHash = base64.StdEncoding.EncodeToString(digest.Sum(nil))
+	}
+
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	hostname, port := host, ""
+	if index := strings.LastIndex(host, ":"); index >= 0 && !strings.HasSuffix(host, "]") {
+		hostname, port = host[:index], host[index+1:]
+	}
+	if len(port) == 0 {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	normalized := strings.Join([]string{
+		"hawk.1.header",
+		timestamp,
+		nonce,
+		strings.ToUpper(req.Method),
+		req.URL.RequestURI(),
+		strings.ToLower(hostname),
+		port,
+		payloadHash,
+		strings.ReplaceAll(strings.ReplaceAll(signer.Ext, "\\", "\\\\"), "\n", "\\n"),
+	}, "\n") + "\n"
+	mac := hmac.New(newHash, []byte(signer.Key))
+	mac.Write([]byte(normalized))
+
+	sb := strings.Builder{}
+	sb.WriteString(`Hawk id="` + signer.ID + `", ts="` + timestamp + `", nonce="` + nonce + `"`)
+	if len(payloadHash) > 0 {
+		sb.WriteString(`, hash="` + payloadHash + `"`)
+	}
+	if len(signer.Ext) > 0 {
+		sb.WriteString(`, ext="` + strings.ReplaceAll(strings.ReplaceAll(signer.Ext, "\\", "\\\\"), `"`, `\"`) + `"`)
+	}
+	sb.WriteString(`, mac="` + base64.StdEncoding.EncodeToString(mac.Sum(nil)) + `"`)
+	req.Header.Set("Authorization", sb.String())
+	return nil
+}
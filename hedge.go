@@ -0,0 +1,112 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gildas/go-logger"
+)
+
+type hedgeResult struct {
+	res   *http.Response
+	err   error
+	index int
+}
+
+// cancelOnClose cancels the context of a hedged request once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body cancelOnClose) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
+
+// isHedgeable tells if the request can be sent twice safely
+func isHedgeable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// doHedged sends the request and, if it has not responded within the delay, sends an identical one
+//
+// The first successful response wins and the other request is cancelled.
+// If the request fails before the delay, or if both requests fail, the last error is returned.
+// Only safe methods (GET, HEAD, OPTIONS) are hedged, other requests are sent once.
+func doHedged(log *logger.Logger, client *http.Client, req *http.Request, delay time.Duration) (*http.Response, error) {
+	if delay <= 0 || !isHedgeable(req) {
+		return client.Do(req)
+	}
+	results := make(chan hedgeResult, 2)
+	cancels := make([]context.CancelFunc, 0, 2)
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		hedge := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				results <- hedgeResult{err: err, index: index}
+				return
+			}
+			hedge.Body = body
+		}
+		go func() {
+			res, err := client.Do(hedge)
+			results <- hedgeResult{res: res, err: err, index: index}
+		}()
+	}
+
+	send()
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var result hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			log.Debugf("No response after %s, sending a hedged request", delay)
+			send()
+			pending++
+			continue
+		case result = <-results:
+			pending--
+		}
+		if result.err == nil || pending == 0 {
+			break
+		}
+		// This request failed, let's wait for the other one
+		log.Debugf("Hedged request failed, waiting for the other one: %s", result.err)
+		cancels[result.index]()
+	}
+
+	// Cancel the loser, if any, and release its response
+	if pending > 0 {
+		for index, cancel := range cancels {
+			if index != result.index {
+				cancel()
+			}
+		}
+		go func() {
+			if loser := <-results; loser.res != nil {
+				loser.res.Body.Close()
+			}
+		}()
+	}
+	if result.err != nil {
+		cancels[result.index]()
+		return nil, result.err
+	}
+	result.res.Body = cancelOnClose{ReadCloser: result.res.Body, cancel: cancels[result.index]}
+	return result.res, nil
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// SendJSONStream sends an HTTP request and decodes the top-level JSON array of the response, element by element, into a channel
+//
+// The response body is streamed, so very large arrays are consumed with constant memory.
+// The out channel is closed when SendJSONStream returns. If the Context of the options is done, SendJSONStream stops sending elements.
+//
+// The returned Content describes the response, its Data is empty.
+func SendJSONStream[T any](options *Options, out chan<- T) (*Content, error) {
+	defer close(out)
+	options = options.Clone()
+	if options != nil && len(options.Accept) == 0 {
+		options.Accept = "application/json"
+	}
+	reader := &ContentReader{}
+	content, err := Send(options, reader)
+	if err != nil {
+		return content, err
+	}
+	defer reader.Close()
+
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	buffered := bufio.NewReader(reader)
+	skipJSONPrefix(buffered)
+	decoder := json.NewDecoder(buffered)
+	token, err := decoder.Token()
+	if err != nil {
+		return content, errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	if delimiter, ok := token.(json.Delim); !ok || delimiter != '[' {
+		return content, errors.JSONUnmarshalError.Wrap(fmt.Errorf("expected a JSON array, got %v", token))
+	}
+	for decoder.More() {
+		var item T
+		if err = decoder.Decode(&item); err != nil {
+			return content, errors.JSONUnmarshalError.WrapIfNotMe(err)
+		}
+		select {
+		case out <- item:
+		case <-ctx.Done():
+			return content, errors.WithStack(ctx.Err())
+		}
+	}
+	if _, err = decoder.Token(); err != nil {
+		return content, errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	// reading the rest of the body verifies its checksums, if any
+	if _, err = io.Copy(io.Discard, io.MultiReader(decoder.Buffered(), buffered)); err != nil {
+		return content, err
+	}
+	return content, nil
+}
+
+// skipJSONPrefix skips the UTF-8 BOM, the anti-XSSI prefix, and the leading whitespaces like jsonData does
+func skipJSONPrefix(reader *bufio.Reader) {
+	if data, err := reader.Peek(len(utf8BOM)); err == nil && bytes.Equal(data, utf8BOM) {
+		_, _ = reader.Discard(len(utf8BOM))
+	}
+	skipBytes(reader, " \t\r\n")
+	if data, err := reader.Peek(4); err == nil && string(data) == ")]}'" {
+		_, _ = reader.Discard(4)
+		skipBytes(reader, ", \t\r\n")
+	}
+}
+
+// skipBytes skips the leading bytes of the reader that are in the given set
+func skipBytes(reader *bufio.Reader, set string) {
+	for {
+		data, err := reader.Peek(1)
+		if err != nil || strings.IndexByte(set, data[0]) < 0 {
+			return
+		}
+		_, _ = reader.Discard(1)
+	}
+}
@@ -0,0 +1,113 @@
+package request_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateJSONStreamTestServer(suite *RequestSuite, count int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/object":
+			_, _ = w.Write([]byte(`{"id": "1234"}`))
+		case "/prefixed":
+			_, _ = w.Write([]byte("\xEF\xBB\xBF)]}',\n[{\"id\": \"1\"}, {\"id\": \"2\"}]"))
+		case "/invalid":
+			_, _ = w.Write([]byte(`[{"id": "1"}, {"id": `))
+		default:
+			_, _ = w.Write([]byte("["))
+			for i := 0; i < count; i++ {
+				if i > 0 {
+					_, _ = w.Write([]byte(","))
+				}
+				_, _ = fmt.Fprintf(w, `{"id": "%d"}`, i)
+			}
+			_, _ = w.Write([]byte("]"))
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendJSONStream() {
+	server := CreateJSONStreamTestServer(suite, 1000)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	out := make(chan stuff)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := request.SendJSONStream(&request.Options{URL: serverURL, Logger: suite.Logger}, out)
+		errs <- err
+	}()
+	count := 0
+	for item := range out {
+		suite.Assert().Equal(fmt.Sprintf("%d", count), item.ID)
+		count++
+	}
+	suite.Require().NoError(<-errs, "Failed streaming")
+	suite.Assert().Equal(1000, count)
+}
+
+func (suite *RequestSuite) TestCanSendJSONStreamWithPrefix() {
+	server := CreateJSONStreamTestServer(suite, 0)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/prefixed")
+
+	out := make(chan stuff, 10)
+	_, err := request.SendJSONStream(&request.Options{URL: serverURL, Logger: suite.Logger}, out)
+	suite.Require().NoError(err, "Failed streaming")
+	items := []stuff{}
+	for item := range out {
+		items = append(items, item)
+	}
+	suite.Assert().Equal([]stuff{{ID: "1"}, {ID: "2"}}, items)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingJSONStreamWithoutArray() {
+	server := CreateJSONStreamTestServer(suite, 0)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/object")
+
+	out := make(chan stuff, 10)
+	_, err := request.SendJSONStream(&request.Options{URL: serverURL, Logger: suite.Logger}, out)
+	suite.Require().Error(err, "Should have failed streaming")
+	suite.Assert().ErrorIs(err, errors.JSONUnmarshalError)
+	_, open := <-out
+	suite.Assert().False(open, "The channel should be closed")
+}
+
+func (suite *RequestSuite) TestShouldFailSendingJSONStreamWithInvalidJSON() {
+	server := CreateJSONStreamTestServer(suite, 0)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/invalid")
+
+	out := make(chan stuff, 10)
+	_, err := request.SendJSONStream(&request.Options{URL: serverURL, Logger: suite.Logger}, out)
+	suite.Require().Error(err, "Should have failed streaming")
+	suite.Assert().ErrorIs(err, errors.JSONUnmarshalError)
+	suite.Assert().Equal("1", (<-out).ID)
+}
+
+func (suite *RequestSuite) TestCanCancelJSONStream() {
+	server := CreateJSONStreamTestServer(suite, 1000)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan stuff)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := request.SendJSONStream(&request.Options{Context: ctx, URL: serverURL, Logger: suite.Logger}, out)
+		errs <- err
+	}()
+	suite.Assert().Equal("0", (<-out).ID)
+	cancel()
+	suite.Assert().ErrorIs(<-errs, context.Canceled)
+}
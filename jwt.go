@@ -0,0 +1,120 @@
+package request
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// DefaultJWTLifetime defines how long the JWTs minted by a JWTProvider are valid
+const DefaultJWTLifetime = 1 * time.Hour
+
+// DefaultJWTRefreshBefore defines how long before its expiry a JWT is minted again
+const DefaultJWTRefreshBefore = 1 * time.Minute
+
+// JWTProvider mints JWT bearer tokens and caches them until they are about to expire
+//
+// implements TokenProvider
+type JWTProvider struct {
+	Claims        map[string]interface{} // the "iat" and "exp" claims are set when the JWT is minted
+	Algorithm     string                 // JWSHS256, JWSRS256, or JWSES256, by default: given by the type of the Key
+	Key           interface{}            // a []byte, an *rsa.PrivateKey, or an *ecdsa.PrivateKey
+	Lifetime      time.Duration          // how long the JWT is valid, by default: 1 hour
+	RefreshBefore time.Duration          // how long before its expiry the JWT is minted again, by default: 1 minute
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// JWTAuthorization creates a JWTProvider that signs the claims with the signingKey
+//
+// The algorithm is given by the type of the signingKey: HS256 for a []byte, RS256 for an *rsa.PrivateKey, ES256 for an *ecdsa.PrivateKey.
+//
+// Use it as the Options.TokenProvider:
+//
+//	options.TokenProvider = request.JWTAuthorization(map[string]interface{}{"iss": appID}, privateKey)
+func JWTAuthorization(claims map[string]interface{}, signingKey interface{}) *JWTProvider {
+	return &JWTProvider{Claims: claims, Key: signingKey}
+}
+
+// Authorization provides the Bearer Authorization with a valid JWT
+//
+// implements TokenProvider
+func (provider *JWTProvider) Authorization(context context.Context) (string, error) {
+	token, err := provider.Token()
+	if err != nil {
+		return "", err
+	}
+	return BearerAuthorization(token), nil
+}
+
+// Token gets the current JWT, a new one is minted if it is about to expire
+func (provider *JWTProvider) Token() (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	refreshBefore := provider.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultJWTRefreshBefore
+	}
+	if len(provider.token) > 0 && time.Now().Add(refreshBefore).Before(provider.expires) {
+		return provider.token, nil
+	}
+	token, expires, err := provider.mint()
+	if err != nil {
+		return "", err
+	}
+	provider.token, provider.expires = token, expires
+	return token, nil
+}
+
+// mint creates a new signed JWT
+func (provider *JWTProvider) mint() (string, time.Time, error) {
+	algorithm := provider.Algorithm
+	if len(algorithm) == 0 {
+		switch provider.Key.(type) {
+		case []byte:
+			algorithm = JWSHS256
+		case *rsa.PrivateKey:
+			algorithm = JWSRS256
+		case *ecdsa.PrivateKey:
+			algorithm = JWSES256
+		default:
+			return "", time.Time{}, errors.ArgumentInvalid.With("key", fmt.Sprintf("%T", provider.Key))
+		}
+	}
+	lifetime := provider.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultJWTLifetime
+	}
+	now := time.Now()
+	expires := now.Add(lifetime)
+	claims := make(map[string]interface{}, len(provider.Claims)+2)
+	for key, value := range provider.Claims {
+		claims[key] = value
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = expires.Unix()
+
+	header, err := json.Marshal(joseHeader{Algorithm: algorithm, Type: "JWT"})
+	if err != nil {
+		return "", time.Time{}, errors.JSONMarshalError.Wrap(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, errors.JSONMarshalError.Wrap(err)
+	}
+	input := joseEncoding.EncodeToString(header) + "." + joseEncoding.EncodeToString(payload)
+	signature, err := joseSign(algorithm, provider.Key, []byte(input))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return input + "." + joseEncoding.EncodeToString(signature), expires, nil
+}
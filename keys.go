@@ -0,0 +1,313 @@
+package request
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gildas/go-errors"
+)
+
+// KeyTranslation tells how the keys of JSON objects are translated between Go structs and the server
+//
+// Only the keys of struct fields without a name in their json tag are translated,
+// the keys of maps and the names given in json tags are sent and received as they are.
+type KeyTranslation int
+
+const (
+	// NoKeyTranslation sends and receives the keys as encoding/json does
+	NoKeyTranslation KeyTranslation = iota
+	// SnakeCaseKeys sends and receives the keys in snake_case (e.g.: UserID is sent as user_id)
+	SnakeCaseKeys
+	// CamelCaseKeys sends and receives the keys in camelCase (e.g.: UserID is sent as userID)
+	CamelCaseKeys
+)
+
+// String gets a string representation of this KeyTranslation
+//
+// implements fmt.Stringer
+func (translation KeyTranslation) String() string {
+	switch translation {
+	case SnakeCaseKeys:
+		return "snake"
+	case CamelCaseKeys:
+		return "camel"
+	default:
+		return "none"
+	}
+}
+
+// Translate translates the name of a Go struct field into the key sent to the server
+func (translation KeyTranslation) Translate(name string) string {
+	switch translation {
+	case SnakeCaseKeys:
+		return snakeCase(name)
+	case CamelCaseKeys:
+		return camelCase(name)
+	default:
+		return name
+	}
+}
+
+// marshal marshals the payload into JSON with its keys translated
+func (translation KeyTranslation) marshal(payload interface{}) ([]byte, error) {
+	data, err := marshal(payload)
+	if err != nil || translation == NoKeyTranslation {
+		return data, err
+	}
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&value); err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	return marshal(translation.translateOut(value, reflect.ValueOf(payload)))
+}
+
+// unmarshal unmarshals the JSON data into the results with its keys translated
+func (translation KeyTranslation) unmarshal(data []byte, results interface{}) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	if resultsType := reflect.TypeOf(results); resultsType != nil {
+		value = translation.translateIn(value, resultsType)
+	}
+	return unmarshalJSONValue(value, results)
+}
+
+// translateOut translates the keys of the JSON value marshaled from the given Go value
+func (translation KeyTranslation) translateOut(value interface{}, source reflect.Value) interface{} {
+	for source.Kind() == reflect.Ptr || source.Kind() == reflect.Interface {
+		if source.IsNil() {
+			return value
+		}
+		source = source.Elem()
+	}
+	if !source.IsValid() || hasCustomJSON(source.Type()) {
+		return value
+	}
+	switch source.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		translated := make(map[string]interface{}, len(object))
+		for _, field := range jsonFields(source.Type()) {
+			member, found := object[field.name]
+			if !found {
+				continue
+			}
+			delete(object, field.name)
+			key := field.name
+			if !field.tagged {
+				key = translation.Translate(field.name)
+			}
+			if fieldValue, err := source.FieldByIndexErr(field.index); err == nil {
+				translated[key] = translation.translateOut(member, fieldValue)
+			} else {
+				translated[key] = member
+			}
+		}
+		for key, member := range object {
+			translated[key] = member
+		}
+		return translated
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok || len(items) != source.Len() {
+			return value
+		}
+		for index := range items {
+			items[index] = translation.translateOut(items[index], source.Index(index))
+		}
+		return items
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for _, key := range source.MapKeys() {
+			var name string
+			switch key.Kind() {
+			case reflect.String:
+				name = key.String()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				name = strconv.FormatInt(key.Int(), 10)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				name = strconv.FormatUint(key.Uint(), 10)
+			default:
+				return value
+			}
+			if member, found := object[name]; found {
+				object[name] = translation.translateOut(member, source.MapIndex(key))
+			}
+		}
+		return object
+	}
+	return value
+}
+
+// translateIn translates the keys of the JSON value that will be unmarshaled into the given Go type
+func (translation KeyTranslation) translateIn(value interface{}, target reflect.Type) interface{} {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	if hasCustomJSON(target) {
+		return value
+	}
+	switch target.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		translated := make(map[string]interface{}, len(object))
+		for _, field := range jsonFields(target) {
+			key := field.name
+			if !field.tagged {
+				key = translation.Translate(field.name)
+			}
+			member, found := object[key]
+			if !found {
+				continue
+			}
+			delete(object, key)
+			translated[field.name] = translation.translateIn(member, field.fieldType)
+		}
+		for key, member := range object {
+			if _, found := translated[key]; !found {
+				translated[key] = member
+			}
+		}
+		return translated
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		for index := range items {
+			items[index] = translation.translateIn(items[index], target.Elem())
+		}
+		return items
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for key, member := range object {
+			object[key] = translation.translateIn(member, target.Elem())
+		}
+		return object
+	}
+	return value
+}
+
+// jsonField is a struct field as encoding/json sees it
+type jsonField struct {
+	name      string
+	tagged    bool
+	index     []int
+	fieldType reflect.Type
+}
+
+// jsonFields gets the fields of a struct type as encoding/json sees them, with the fields of embedded structs
+func jsonFields(structType reflect.Type) []jsonField {
+	fields := []jsonField{}
+	embedded := []jsonField{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && len(name) == 0 {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				for _, inner := range jsonFields(fieldType) {
+					inner.index = append([]int{i}, inner.index...)
+					embedded = append(embedded, inner)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if len(name) > 0 {
+			fields = append(fields, jsonField{name: name, tagged: true, index: []int{i}, fieldType: field.Type})
+		} else {
+			fields = append(fields, jsonField{name: field.Name, index: []int{i}, fieldType: field.Type})
+		}
+	}
+	for _, field := range embedded { // fields of embedded structs are hidden by the fields with the same name
+		hidden := false
+		for _, other := range fields {
+			if other.name == field.name {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// hasCustomJSON tells if the type marshals or unmarshals itself (e.g.: time.Time)
+func hasCustomJSON(valueType reflect.Type) bool {
+	pointerType := reflect.PointerTo(valueType)
+	for _, candidate := range []reflect.Type{valueType, pointerType} {
+		if candidate.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) ||
+			candidate.Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) ||
+			candidate.Implements(reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()) ||
+			candidate.Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeCase converts a Go name into snake_case (e.g.: UserID => user_id, HTTPServer => http_server)
+func snakeCase(name string) string {
+	runes := []rune(name)
+	sb := strings.Builder{}
+	for index, r := range runes {
+		if unicode.IsUpper(r) {
+			if index > 0 {
+				previous := runes[index-1]
+				nextIsLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+				if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+					sb.WriteRune('_')
+				}
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// camelCase converts a Go name into camelCase (e.g.: UserID => userID, HTTPServer => httpServer)
+func camelCase(name string) string {
+	runes := []rune(name)
+	for index := 0; index < len(runes) && unicode.IsUpper(runes[index]); index++ {
+		if index > 0 && index+1 < len(runes) && unicode.IsLower(runes[index+1]) {
+			break // the last upper case letter starts the next word
+		}
+		runes[index] = unicode.ToLower(runes[index])
+	}
+	return string(runes)
+}
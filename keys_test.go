@@ -0,0 +1,109 @@
+package request_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gildas/go-request"
+)
+
+type keyedAddress struct {
+	StreetName string
+	ZipCode    string `json:"zip"`
+}
+
+type keyedUser struct {
+	UserID    string
+	FirstName string
+	Nickname  string `json:"nick_name"`
+	CreatedAt time.Time
+	Addresses []keyedAddress
+	Labels    map[string]string
+}
+
+func TestCanTranslateKeys(t *testing.T) {
+	names := map[string][2]string{
+		"UserID":     {"user_id", "userID"},
+		"FirstName":  {"first_name", "firstName"},
+		"HTTPServer": {"http_server", "httpServer"},
+		"ID":         {"id", "id"},
+		"Item2Name":  {"item2_name", "item2Name"},
+		"name":       {"name", "name"},
+	}
+	for name, expected := range names {
+		assert.Equal(t, expected[0], request.SnakeCaseKeys.Translate(name), "Wrong snake case for %s", name)
+		assert.Equal(t, expected[1], request.CamelCaseKeys.Translate(name), "Wrong camel case for %s", name)
+		assert.Equal(t, name, request.NoKeyTranslation.Translate(name))
+	}
+	assert.Equal(t, "snake", request.SnakeCaseKeys.String())
+}
+
+func CreateKeyTranslationTestServer(suite *RequestSuite) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body) // echo
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendAndReceiveWithSnakeCaseKeys() {
+	server := CreateKeyTranslationTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	user := keyedUser{
+		UserID:    "1234",
+		FirstName: "John",
+		Nickname:  "Johnny",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Addresses: []keyedAddress{{StreetName: "Main St", ZipCode: "12345"}},
+		Labels:    map[string]string{"FavoriteColor": "blue"},
+	}
+	results := keyedUser{}
+	content, err := request.Send(&request.Options{
+		URL:            serverURL,
+		Payload:        user,
+		KeyTranslation: request.SnakeCaseKeys,
+		Logger:         suite.Logger,
+	}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal(user, results)
+
+	var sent map[string]interface{}
+	suite.Require().NoError(json.Unmarshal(content.Data, &sent))
+	suite.Assert().Contains(sent, "user_id")
+	suite.Assert().Contains(sent, "first_name")
+	suite.Assert().Contains(sent, "nick_name", "Tagged keys should not be translated")
+	suite.Assert().Equal("2024-01-02T03:04:05Z", sent["created_at"])
+	suite.Assert().Equal([]interface{}{map[string]interface{}{"street_name": "Main St", "zip": "12345"}}, sent["addresses"])
+	suite.Assert().Equal(map[string]interface{}{"FavoriteColor": "blue"}, sent["labels"], "Map keys should not be translated")
+}
+
+func (suite *RequestSuite) TestCanReceiveResultsWithCamelCaseKeys() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": [{"userID": "1234", "firstName": "John", "nick_name": "Johnny"}]}`))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	results := []keyedUser{}
+	_, err := request.Send(&request.Options{
+		URL:            serverURL,
+		ResultPath:     "data",
+		KeyTranslation: request.CamelCaseKeys,
+		Logger:         suite.Logger,
+	}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().Len(results, 1)
+	suite.Assert().Equal("1234", results[0].UserID)
+	suite.Assert().Equal("John", results[0].FirstName)
+	suite.Assert().Equal("Johnny", results[0].Nickname)
+}
@@ -0,0 +1,173 @@
+package request
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// BalancingStrategy tells how a LoadBalancer chooses its endpoints
+type BalancingStrategy int
+
+const (
+	// RoundRobin chooses the endpoints one after the other
+	RoundRobin BalancingStrategy = iota
+	// Weighted chooses the endpoints one after the other, proportionally to their Weight (smooth weighted round-robin)
+	Weighted
+	// LeastPending chooses the endpoint with the fewest requests in flight
+	LeastPending
+)
+
+// DefaultEndpointDownDelay defines how long an endpoint is not used after it failed
+const DefaultEndpointDownDelay = 30 * time.Second
+
+// DefaultEndpointFailureThreshold defines how many consecutive failures mark an endpoint down
+const DefaultEndpointFailureThreshold = 1
+
+// Endpoint is a base URL a LoadBalancer can send requests to
+type Endpoint struct {
+	URL    *url.URL
+	Weight int // used by the Weighted strategy, by default: 1
+
+	pending       int
+	currentWeight int
+	failures      int       // consecutive failures
+	downUntil     time.Time // zero when the endpoint is up
+	probing       bool      // true while a single request checks if the endpoint is back up
+}
+
+// LoadBalancer chooses the BaseURL of requests among its Endpoints
+//
+// Endpoints that fail (network errors, 502, 503, 504, or too many attempts) FailureThreshold times in a row are marked down for DownDelay.
+// After DownDelay, a single request probes the endpoint: if it succeeds, the endpoint is up again, otherwise it is marked down for another DownDelay.
+// When all endpoints are down, the one that will be back up first is used.
+type LoadBalancer struct {
+	Strategy         BalancingStrategy
+	Endpoints        []*Endpoint
+	DownDelay        time.Duration // how long a failed endpoint is not used, by default: 30s
+	FailureThreshold int           // how many consecutive failures mark an endpoint down, by default: 1
+
+	next int
+	lock sync.Mutex
+}
+
+// NewLoadBalancer instantiates a new LoadBalancer with the given base URLs, each with a Weight of 1
+func NewLoadBalancer(strategy BalancingStrategy, baseURLs ...*url.URL) *LoadBalancer {
+	balancer := &LoadBalancer{Strategy: strategy}
+	for _, baseURL := range baseURLs {
+		balancer.Endpoints = append(balancer.Endpoints, &Endpoint{URL: baseURL, Weight: 1})
+	}
+	return balancer
+}
+
+// Next gets the Endpoint the next request should be sent to
+//
+// Done must be called with the Endpoint once the request completes.
+func (balancer *LoadBalancer) Next() (*Endpoint, error) {
+	balancer.lock.Lock()
+	defer balancer.lock.Unlock()
+
+	if len(balancer.Endpoints) == 0 {
+		return nil, errors.Empty.With("Endpoints")
+	}
+	now := time.Now()
+	available := make([]*Endpoint, 0, len(balancer.Endpoints))
+	for _, endpoint := range balancer.Endpoints {
+		if !now.Before(endpoint.downUntil) && !endpoint.probing {
+			available = append(available, endpoint)
+		}
+	}
+	if len(available) == 0 {
+		// All endpoints are down, use the one that will be back up first
+		endpoint := balancer.Endpoints[0]
+		for _, candidate := range balancer.Endpoints[1:] {
+			if candidate.downUntil.Before(endpoint.downUntil) {
+				endpoint = candidate
+			}
+		}
+		endpoint.pending++
+		return endpoint, nil
+	}
+
+	var endpoint *Endpoint
+	switch balancer.Strategy {
+	case Weighted:
+		total := 0
+		for _, candidate := range available {
+			weight := candidate.Weight
+			if weight <= 0 {
+				weight = 1
+			}
+			total += weight
+			candidate.currentWeight += weight
+			if endpoint == nil || candidate.currentWeight > endpoint.currentWeight {
+				endpoint = candidate
+			}
+		}
+		endpoint.currentWeight -= total
+	case LeastPending:
+		// Start after the last chosen endpoint so ties are spread evenly
+		for i := range available {
+			candidate := available[(balancer.next+i)%len(available)]
+			if endpoint == nil || candidate.pending < endpoint.pending {
+				endpoint = candidate
+			}
+		}
+		balancer.next++
+	default:
+		endpoint = available[balancer.next%len(available)]
+		balancer.next++
+	}
+	if !endpoint.downUntil.IsZero() {
+		// The endpoint was down, this request probes it and the others wait for its result
+		endpoint.probing = true
+	}
+	endpoint.pending++
+	return endpoint, nil
+}
+
+// Done tells the LoadBalancer the request sent to the Endpoint completed with the given error
+//
+// If the error shows the Endpoint is failing FailureThreshold times in a row, or while it is probed, it is marked down for DownDelay.
+// Otherwise, the Endpoint is up.
+func (balancer *LoadBalancer) Done(endpoint *Endpoint, err error) {
+	balancer.lock.Lock()
+	defer balancer.lock.Unlock()
+
+	if endpoint.pending > 0 {
+		endpoint.pending--
+	}
+	probing := endpoint.probing
+	endpoint.probing = false
+	if !isEndpointFailure(err) {
+		endpoint.failures = 0
+		endpoint.downUntil = time.Time{}
+		return
+	}
+	endpoint.failures++
+	threshold := balancer.FailureThreshold
+	if threshold <= 0 {
+		threshold = DefaultEndpointFailureThreshold
+	}
+	if probing || endpoint.failures >= threshold {
+		delay := balancer.DownDelay
+		if delay == 0 {
+			delay = DefaultEndpointDownDelay
+		}
+		endpoint.downUntil = time.Now().Add(delay)
+	}
+}
+
+// isEndpointFailure tells if the error shows the endpoint is failing, as opposed to the request being wrong
+func isEndpointFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, errors.HTTPBadGateway) ||
+		errors.Is(err, errors.HTTPServiceUnavailable) ||
+		errors.Is(err, errors.HTTPStatusGatewayTimeout) ||
+		errors.Is(err, errors.HTTPStatusRequestTimeout) ||
+		DefaultRetryableErrorClassifier.IsRetryable(err)
+}
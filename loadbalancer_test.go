@@ -0,0 +1,152 @@
+package request_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func createBaseURLs(hosts ...string) []*url.URL {
+	baseURLs := make([]*url.URL, 0, len(hosts))
+	for _, host := range hosts {
+		baseURLs = append(baseURLs, &url.URL{Scheme: "https", Host: host})
+	}
+	return baseURLs
+}
+
+func nextHost(t *testing.T, balancer *request.LoadBalancer) string {
+	endpoint, err := balancer.Next()
+	require.NoError(t, err, "Failed to get the next endpoint")
+	balancer.Done(endpoint, nil)
+	return endpoint.URL.Host
+}
+
+func TestCanBalanceWithRoundRobin(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b", "c")...)
+	hosts := []string{}
+	for i := 0; i < 6; i++ {
+		hosts = append(hosts, nextHost(t, balancer))
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, hosts)
+}
+
+func TestCanBalanceWithWeights(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.Weighted, createBaseURLs("a", "b")...)
+	balancer.Endpoints[0].Weight = 3
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[nextHost(t, balancer)]++
+	}
+	assert.Equal(t, 6, counts["a"])
+	assert.Equal(t, 2, counts["b"])
+}
+
+func TestCanBalanceWithLeastPending(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.LeastPending, createBaseURLs("a", "b")...)
+	first, err := balancer.Next()
+	require.NoError(t, err, "Failed to get the next endpoint")
+	second, err := balancer.Next()
+	require.NoError(t, err, "Failed to get the next endpoint")
+	assert.NotEqual(t, first.URL.Host, second.URL.Host, "Endpoints should be different")
+	balancer.Done(first, nil)
+	assert.Equal(t, first.URL.Host, nextHost(t, balancer), "The endpoint without pending requests should be chosen")
+}
+
+func TestShouldSkipFailedEndpoints(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b")...)
+	endpoint, err := balancer.Next()
+	require.NoError(t, err, "Failed to get the next endpoint")
+	balancer.Done(endpoint, errors.HTTPServiceUnavailable.WithStack())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", nextHost(t, balancer), "Failed endpoint should be skipped")
+	}
+}
+
+func TestShouldNotSkipEndpointsOnClientErrors(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b")...)
+	endpoint, err := balancer.Next()
+	require.NoError(t, err, "Failed to get the next endpoint")
+	balancer.Done(endpoint, errors.HTTPNotFound.WithStack())
+	assert.Equal(t, "b", nextHost(t, balancer))
+	assert.Equal(t, "a", nextHost(t, balancer))
+}
+
+func TestShouldUseEndpointBackFirstWhenAllAreDown(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b")...)
+	a, _ := balancer.Next()
+	b, _ := balancer.Next()
+	balancer.Done(b, errors.HTTPBadGateway.WithStack())
+	balancer.Done(a, errors.HTTPBadGateway.WithStack())
+	assert.Equal(t, "b", nextHost(t, balancer))
+}
+
+func TestShouldSkipEndpointsAfterFailureThreshold(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b")...)
+	balancer.FailureThreshold = 2
+	a, _ := balancer.Next()
+	balancer.Done(a, errors.HTTPServiceUnavailable.WithStack())
+	assert.Equal(t, "b", nextHost(t, balancer))
+	a, _ = balancer.Next()
+	assert.Equal(t, "a", a.URL.Host, "Endpoint should not be skipped before the threshold")
+	balancer.Done(a, errors.HTTPServiceUnavailable.WithStack())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", nextHost(t, balancer), "Failed endpoint should be skipped")
+	}
+}
+
+func TestCanProbeEndpointAfterDownDelay(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin, createBaseURLs("a", "b")...)
+	balancer.DownDelay = 10 * time.Millisecond
+	a, _ := balancer.Next()
+	balancer.Done(a, errors.HTTPServiceUnavailable.WithStack())
+	time.Sleep(20 * time.Millisecond)
+
+	// Only one request probes the endpoint
+	var probe *request.Endpoint
+	for probe == nil {
+		endpoint, err := balancer.Next()
+		require.NoError(t, err, "Failed to get the next endpoint")
+		if endpoint.URL.Host == "a" {
+			probe = endpoint
+		} else {
+			balancer.Done(endpoint, nil)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", nextHost(t, balancer), "Probed endpoint should be skipped")
+	}
+
+	// A failed probe marks the endpoint down again
+	balancer.Done(probe, errors.HTTPBadGateway.WithStack())
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", nextHost(t, balancer), "Failed endpoint should be skipped")
+	}
+
+	// A successful probe marks the endpoint up again
+	time.Sleep(20 * time.Millisecond)
+	probe = nil
+	for probe == nil {
+		endpoint, err := balancer.Next()
+		require.NoError(t, err, "Failed to get the next endpoint")
+		if endpoint.URL.Host == "a" {
+			probe = endpoint
+		} else {
+			balancer.Done(endpoint, nil)
+		}
+	}
+	balancer.Done(probe, nil)
+	hosts := []string{nextHost(t, balancer), nextHost(t, balancer)}
+	assert.ElementsMatch(t, []string{"a", "b"}, hosts, "Endpoint should be up again")
+}
+
+func TestShouldFailBalancingWithoutEndpoints(t *testing.T) {
+	balancer := request.NewLoadBalancer(request.RoundRobin)
+	_, err := balancer.Next()
+	assert.ErrorIs(t, err, errors.Empty)
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"github.com/gildas/go-logger"
+)
+
+// levelStream moves the TRACE, DEBUG, and INFO records of a request to another level before writing them in the Logger of the options
+//
+// If quiet, these records are dropped and only the warnings and errors are written.
+//
+// implements logger.Streamer
+type levelStream struct {
+	parent *logger.Logger
+	level  logger.Level
+	quiet  bool
+}
+
+// requestLogger gets the Logger that writes the details of the request at the level of the options
+func requestLogger(options *Options) *logger.Logger {
+	if options.LogLevel == logger.UNSET && !options.LogFailuresOnly {
+		return options.Logger
+	}
+	name, _ := options.Logger.GetRecord("name").(string)
+	if len(name) == 0 {
+		name = "request"
+	}
+	stream := &levelStream{parent: options.Logger, level: options.LogLevel, quiet: options.LogFailuresOnly}
+	return logger.Create(name, stream).Child(options.Logger.GetTopic(), options.Logger.GetScope())
+}
+
+// Write writes the given Record
+//
+// implements logger.Streamer
+func (stream *levelStream) Write(record *logger.Record) error {
+	if level, ok := record.Get("level").(logger.Level); ok && level < logger.WARN {
+		if stream.quiet {
+			return nil
+		}
+		record.Data["level"] = stream.levelOf(level)
+	}
+	return stream.parent.Write(record)
+}
+
+// ShouldWrite tells if the given level should be written to this stream
+//
+// implements logger.Streamer
+func (stream *levelStream) ShouldWrite(level logger.Level, topic, scope string) bool {
+	if level < logger.WARN {
+		if stream.quiet {
+			return false
+		}
+		level = stream.levelOf(level)
+	}
+	return stream.parent.ShouldWrite(level, topic, scope)
+}
+
+// ShouldLogSourceInfo tells if the source info should be logged
+//
+// implements logger.Streamer
+func (stream *levelStream) ShouldLogSourceInfo() bool {
+	return stream.parent.ShouldLogSourceInfo()
+}
+
+// Flush flushes the stream
+//
+// implements logger.Streamer
+func (stream *levelStream) Flush() {
+	stream.parent.Flush()
+}
+
+// Close does nothing, the Logger of the options belongs to the caller
+//
+// implements logger.Streamer
+func (stream *levelStream) Close() {
+}
+
+// GetFilterLevels gets the FilterLevels
+//
+// implements logger.Streamer
+func (stream *levelStream) GetFilterLevels() logger.LevelSet {
+	return stream.parent.GetFilterLevels()
+}
+
+// levelOf gets the level a record of the given level is written at
+func (stream *levelStream) levelOf(level logger.Level) logger.Level {
+	if stream.level == logger.UNSET {
+		return level
+	}
+	return stream.level
+}
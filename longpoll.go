@@ -0,0 +1,89 @@
+package request
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// LongPollHandler handles the Content of each response to a long polling request, see SendLongPoll
+//
+// If it returns an error, SendLongPoll stops and returns that error.
+type LongPollHandler func(content *Content) error
+
+// SendLongPoll sends the request again and again, each time the previous one completes or times out
+//
+// Each response with a body is given to the handler, empty responses (e.g.: 204 No Content) are ignored.
+// Between 2 requests, SendLongPoll waits for the PollInterval of the options plus a random delay up to their PollJitter.
+//
+// The Timeout of the options should be longer than the time the server holds a request.
+// When the request times out (on the client or with a 408 or 504 status), it is simply sent again.
+//
+// SendLongPoll returns nil when the Context of the options is done,
+// or the first error that is not a timeout, or the first error of the handler.
+func SendLongPoll(options *Options, handler LongPollHandler) error {
+	if options == nil {
+		return errors.ArgumentMissing.With("options")
+	}
+	if handler == nil {
+		return errors.ArgumentMissing.With("handler")
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for {
+		content, err := Send(options, nil)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if !isLongPollTimeout(err) {
+				return err
+			}
+			if options.Logger != nil {
+				options.Logger.Debugf("Long polling request timed out, sending it again")
+			}
+		} else if content != nil && content.Length > 0 {
+			if err = handler(content); err != nil {
+				return err
+			}
+		}
+		if !waitPollInterval(ctx, options.PollInterval, options.PollJitter) {
+			return nil
+		}
+	}
+}
+
+// isLongPollTimeout tells if the error means the long polling request timed out
+func isLongPollTimeout(err error) bool {
+	if errors.Is(err, errors.HTTPStatusRequestTimeout) || errors.Is(err, errors.HTTPStatusGatewayTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// waitPollInterval waits for the interval plus a random delay up to the jitter
+//
+// returns false if the context is done before
+func waitPollInterval(ctx context.Context, interval, jitter time.Duration) bool {
+	delay := interval
+	if jitter > 0 {
+		delay += rand.N(jitter)
+	}
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
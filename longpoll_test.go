@@ -0,0 +1,83 @@
+package request_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateLongPollTestServer(suite *RequestSuite, calls *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch call := calls.Add(1); call {
+		case 1, 3:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"event": %d}`, call)))
+		case 2:
+			w.WriteHeader(http.StatusNoContent) // nothing happened while the request was held
+		case 4:
+			time.Sleep(200 * time.Millisecond) // the client times out
+		case 5:
+			w.WriteHeader(http.StatusGatewayTimeout)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendLongPollRequests() {
+	calls := atomic.Int32{}
+	server := CreateLongPollTestServer(suite, &calls)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	events := []string{}
+	err := request.SendLongPoll(&request.Options{
+		URL:          serverURL,
+		Timeout:      100 * time.Millisecond,
+		Attempts:     1,
+		PollInterval: 10 * time.Millisecond,
+		PollJitter:   10 * time.Millisecond,
+		Logger:       suite.Logger,
+	}, func(content *request.Content) error {
+		events = append(events, string(content.Data))
+		return nil
+	})
+	suite.Require().Error(err, "Should have stopped with the 403 error")
+	suite.Assert().ErrorIs(err, errors.HTTPForbidden)
+	suite.Assert().Equal([]string{`{"event": 1}`, `{"event": 3}`}, events)
+	suite.Assert().Equal(int32(6), calls.Load())
+}
+
+func (suite *RequestSuite) TestCanStopLongPollRequests() {
+	calls := atomic.Int32{}
+	server := CreateLongPollTestServer(suite, &calls)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := request.SendLongPoll(&request.Options{
+		Context:      ctx,
+		URL:          serverURL,
+		PollInterval: time.Hour,
+		Logger:       suite.Logger,
+	}, func(content *request.Content) error {
+		cancel()
+		return nil
+	})
+	suite.Require().NoError(err, "Cancelling the context should stop without error")
+	suite.Assert().Equal(int32(1), calls.Load())
+
+	err = request.SendLongPoll(&request.Options{URL: serverURL, Logger: suite.Logger}, func(content *request.Content) error {
+		return errors.NotImplemented
+	})
+	suite.Assert().ErrorIs(err, errors.NotImplemented, "The error of the handler should stop the polling")
+}
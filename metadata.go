@@ -0,0 +1,92 @@
+package request
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// Allowed describes what a server allows on a resource, as answered to an OPTIONS request
+type Allowed struct {
+	Methods          []string      // methods of the Allow header
+	Origin           string        // Access-Control-Allow-Origin header
+	AllowMethods     []string      // methods of the Access-Control-Allow-Methods header
+	AllowHeaders     []string      // headers of the Access-Control-Allow-Headers header
+	ExposeHeaders    []string      // headers of the Access-Control-Expose-Headers header
+	AllowCredentials bool          // Access-Control-Allow-Credentials header
+	MaxAge           time.Duration // Access-Control-Max-Age header
+	Content          *Content      // the metadata of the response
+}
+
+// Head sends a HEAD request and returns the metadata of the resource
+//
+// The returned Content has no data, its Length is given by the Content-Length header,
+// its Type by the Content-Type header. See Content.ETag and Content.LastModified.
+func Head(options *Options) (*Content, error) {
+	return sendMetadataOnly(options, http.MethodHead)
+}
+
+// Allow sends an OPTIONS request and returns what the server allows on the resource
+//
+// The response body, if any, is not read. To send a CORS preflight request,
+// give the Origin and Access-Control-Request-Method headers in the options.
+func Allow(options *Options) (*Allowed, error) {
+	content, err := sendMetadataOnly(options, http.MethodOptions)
+	if err != nil {
+		return nil, err
+	}
+	allowed := Allowed{
+		Methods:          headerList(content.Headers, "Allow"),
+		Origin:           content.Headers.Get("Access-Control-Allow-Origin"),
+		AllowMethods:     headerList(content.Headers, "Access-Control-Allow-Methods"),
+		AllowHeaders:     headerList(content.Headers, "Access-Control-Allow-Headers"),
+		ExposeHeaders:    headerList(content.Headers, "Access-Control-Expose-Headers"),
+		AllowCredentials: strings.EqualFold(content.Headers.Get("Access-Control-Allow-Credentials"), "true"),
+		Content:          content,
+	}
+	if seconds, err := strconv.Atoi(content.Headers.Get("Access-Control-Max-Age")); err == nil {
+		allowed.MaxAge = time.Duration(seconds) * time.Second
+	}
+	return &allowed, nil
+}
+
+// sendMetadataOnly sends a request with the given method and returns its Content without reading the response body
+func sendMetadataOnly(options *Options, method string) (*Content, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	options = options.Clone()
+	options.Method = method
+	options.Payload = nil
+	options.PayloadType = ""
+	options.Attachment = nil
+	options.Attachments = nil
+	options.ResponseVerifier = nil
+	options.ResponseDecryption = nil
+	reader := &ContentReader{}
+	content, err := Send(options, reader)
+	if err != nil {
+		return content, err
+	}
+	_ = reader.Close()
+	if reader.Length > 0 {
+		content.Length = uint64(reader.Length)
+	}
+	return content, nil
+}
+
+// headerList gets the comma-separated values of a header
+func headerList(headers http.Header, name string) []string {
+	values := []string{}
+	for _, value := range headers.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package request_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateMetadataTestServer(suite *RequestSuite) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodOptions:
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT")
+			w.Header().Add("Access-Control-Allow-Headers", "Authorization")
+			w.Header().Add("Access-Control-Allow-Headers", "X-Tenant, X-Request-Id")
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			_, _ = w.Write([]byte("This body should not be read"))
+		default:
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("ETag", `"1234"`)
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			w.Header().Set("Content-Length", "123456")
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(make([]byte, 123456))
+			}
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendHeadRequest() {
+	server := CreateMetadataTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/document.pdf")
+
+	content, err := request.Head(&request.Options{URL: serverURL, Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("application/pdf", content.Type)
+	suite.Assert().Equal(uint64(123456), content.Length)
+	suite.Assert().Empty(content.Data)
+	suite.Assert().Equal(`"1234"`, content.ETag())
+	lastModified, found := content.LastModified()
+	suite.Require().True(found, "Last-Modified should be found")
+	suite.Assert().Equal(time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC), lastModified)
+}
+
+func (suite *RequestSuite) TestCanSendOptionsRequest() {
+	server := CreateMetadataTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL + "/document.pdf")
+
+	allowed, err := request.Allow(&request.Options{
+		URL:     serverURL,
+		Headers: map[string]string{"Origin": "https://www.acme.com", "Access-Control-Request-Method": "PUT"},
+		Logger:  suite.Logger,
+	})
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(allowed, "Allowed should not be nil")
+	suite.Assert().Equal([]string{"GET", "HEAD", "OPTIONS"}, allowed.Methods)
+	suite.Assert().Equal("https://www.acme.com", allowed.Origin)
+	suite.Assert().Equal([]string{"GET", "PUT"}, allowed.AllowMethods)
+	suite.Assert().Equal([]string{"Authorization", "X-Tenant", "X-Request-Id"}, allowed.AllowHeaders)
+	suite.Assert().Empty(allowed.ExposeHeaders)
+	suite.Assert().True(allowed.AllowCredentials)
+	suite.Assert().Equal(10*time.Minute, allowed.MaxAge)
+	suite.Require().NotNil(allowed.Content, "Content should not be nil")
+	suite.Assert().Empty(allowed.Content.Data, "The body should not be read")
+}
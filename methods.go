@@ -0,0 +1,66 @@
+package request
+
+import (
+	"context"
+	"net/http"
+)
+
+// Get sends a GET request to the given URL and decodes the response into the results, if not nil
+//
+// The opts can configure the request further (e.g.: WithHeader, WithRetry).
+func Get(ctx context.Context, rawURL string, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodGet, rawURL, nil, "", results, opts)
+}
+
+// Delete sends a DELETE request to the given URL and decodes the response into the results, if not nil
+func Delete(ctx context.Context, rawURL string, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodDelete, rawURL, nil, "", results, opts)
+}
+
+// Post sends a POST request with the payload to the given URL and decodes the response into the results, if not nil
+//
+// The payload type is computed like Send does, see Options.Payload.
+func Post(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPost, rawURL, payload, "", results, opts)
+}
+
+// PostJSON sends a POST request with the payload as JSON to the given URL and decodes the response into the results, if not nil
+func PostJSON(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPost, rawURL, payload, "application/json", results, opts)
+}
+
+// Put sends a PUT request with the payload to the given URL and decodes the response into the results, if not nil
+//
+// The payload type is computed like Send does, see Options.Payload.
+func Put(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPut, rawURL, payload, "", results, opts)
+}
+
+// PutJSON sends a PUT request with the payload as JSON to the given URL and decodes the response into the results, if not nil
+func PutJSON(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPut, rawURL, payload, "application/json", results, opts)
+}
+
+// Patch sends a PATCH request with the payload to the given URL and decodes the response into the results, if not nil
+//
+// The payload type is computed like Send does, see Options.Payload.
+func Patch(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPatch, rawURL, payload, "", results, opts)
+}
+
+// PatchJSON sends a PATCH request with the payload as JSON to the given URL and decodes the response into the results, if not nil
+func PatchJSON(ctx context.Context, rawURL string, payload, results interface{}, opts ...Option) (*Content, error) {
+	return sendMethod(ctx, http.MethodPatch, rawURL, payload, "application/json", results, opts)
+}
+
+// sendMethod sends a request with the given method, URL, payload, and results via SendWith
+func sendMethod(ctx context.Context, method, rawURL string, payload interface{}, payloadType string, results interface{}, opts []Option) (*Content, error) {
+	options := []Option{WithMethod(method), To(rawURL)}
+	if payload != nil {
+		options = append(options, WithPayload(payload, payloadType))
+	}
+	if results != nil {
+		options = append(options, Into(results))
+	}
+	return SendWith(ctx, append(options, opts...)...)
+}
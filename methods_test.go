@@ -0,0 +1,29 @@
+package request_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanSendRequestWithMethodHelpers() {
+	results := struct {
+		Code int `json:"code"`
+	}{}
+	content, err := request.Get(context.Background(), suite.Server.URL+"/results", &results, request.WithLogger(suite.Logger))
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal(http.StatusOK, content.StatusCode)
+	suite.Assert().Equal(1234, results.Code)
+
+	content, err = request.PostJSON(context.Background(), suite.Server.URL+"/item", stuff{"1234"}, nil, request.WithLogger(suite.Logger))
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("1234", string(content.Data))
+
+	content, err = request.Post(context.Background(), suite.Server.URL+"/item", stuff{"5678"}, nil, request.WithLogger(suite.Logger))
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal("5678", string(content.Data))
+}
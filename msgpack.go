@@ -0,0 +1,351 @@
+package request
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// MsgPackMediaType is the Content Type of MessagePack payloads
+const MsgPackMediaType = "application/msgpack"
+
+// MsgPackEncoder encodes payloads in MessagePack
+//
+// The payload is mapped like encoding/json does (json tags, json.Marshaler), so the same types can be sent as JSON or MessagePack.
+// It is registered for application/msgpack and application/x-msgpack by default.
+var MsgPackEncoder Encoder = EncoderFunc(func(payload interface{}) ([]byte, error) {
+	value, err := jsonValue(payload)
+	if err != nil {
+		return nil, err
+	}
+	buffer := &bytes.Buffer{}
+	if err = writeMsgPack(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+})
+
+// MsgPackDecoder decodes MessagePack into results
+//
+// The results are mapped like encoding/json does (json tags, json.Unmarshaler). Binary values are decoded as base64 strings (i.e. into []byte),
+// and timestamps as RFC 3339 strings (i.e. into time.Time).
+//
+// It is not registered by default, use RegisterDecoder(request.MsgPackMediaType, 1, request.MsgPackDecoder) to accept MessagePack responses.
+var MsgPackDecoder Decoder = DecoderFunc(func(data []byte, results interface{}) error {
+	reader := &msgPackReader{data: data}
+	value, err := reader.read()
+	if err != nil {
+		return err
+	}
+	if reader.offset != len(data) {
+		return MsgPackInvalid.With("trailing bytes", len(data)-reader.offset)
+	}
+	return unmarshalJSONValue(value, results)
+})
+
+// writeMsgPack writes a value decoded by encoding/json (with UseNumber) in MessagePack
+func writeMsgPack(buffer *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if value {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case json.Number:
+		if integer, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			writeMsgPackInt(buffer, integer)
+		} else if unsigned, err := strconv.ParseUint(string(value), 10, 64); err == nil {
+			buffer.WriteByte(0xcf)
+			_ = binary.Write(buffer, binary.BigEndian, unsigned)
+		} else if float, err := value.Float64(); err == nil {
+			buffer.WriteByte(0xcb)
+			_ = binary.Write(buffer, binary.BigEndian, math.Float64bits(float))
+		} else {
+			return errors.ArgumentInvalid.With("number", value)
+		}
+	case string:
+		writeMsgPackHeader(buffer, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buffer.WriteString(value)
+	case []interface{}:
+		writeMsgPackHeader(buffer, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range value {
+			if err := writeMsgPack(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // so the same payload is always encoded the same way
+		writeMsgPackHeader(buffer, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			_ = writeMsgPack(buffer, key)
+			if err := writeMsgPack(buffer, value[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.ArgumentInvalid.With("value", fmt.Sprintf("%T", value))
+	}
+	return nil
+}
+
+// writeMsgPackInt writes an integer in its shortest MessagePack form
+func writeMsgPackInt(buffer *bytes.Buffer, value int64) {
+	switch {
+	case value >= 0 && value <= 0x7f:
+		buffer.WriteByte(byte(value))
+	case value >= -32 && value < 0:
+		buffer.WriteByte(byte(int8(value)))
+	case value >= 0 && value <= math.MaxUint8:
+		buffer.Write([]byte{0xcc, byte(value)})
+	case value >= 0 && value <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		_ = binary.Write(buffer, binary.BigEndian, uint16(value))
+	case value >= 0 && value <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(value))
+	case value >= 0:
+		buffer.WriteByte(0xcf)
+		_ = binary.Write(buffer, binary.BigEndian, uint64(value))
+	case value >= math.MinInt8:
+		buffer.Write([]byte{0xd0, byte(int8(value))})
+	case value >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		_ = binary.Write(buffer, binary.BigEndian, int16(value))
+	case value >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		_ = binary.Write(buffer, binary.BigEndian, int32(value))
+	default:
+		buffer.WriteByte(0xd3)
+		_ = binary.Write(buffer, binary.BigEndian, value)
+	}
+}
+
+// writeMsgPackHeader writes the header of a string, an array, or a map of the given length
+//
+// fixed is the code of the fixed form, used when length < fixedMax. code8 is 0 when there is no 8-bit form.
+func writeMsgPackHeader(buffer *bytes.Buffer, length int, fixed byte, fixedMax int, code8, code16, code32 byte) {
+	switch {
+	case length < fixedMax:
+		buffer.WriteByte(fixed | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buffer.Write([]byte{code8, byte(length)})
+	case length <= math.MaxUint16:
+		buffer.WriteByte(code16)
+		_ = binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(code32)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+}
+
+// msgPackMaxDepth is the maximum nesting depth of arrays and maps a msgPackReader accepts
+const msgPackMaxDepth = 1000
+
+// msgPackReader reads MessagePack values as values encoding/json can marshal
+type msgPackReader struct {
+	data   []byte
+	offset int
+	depth  int
+}
+
+func (reader *msgPackReader) next(length int) ([]byte, error) {
+	if length < 0 || reader.offset+length > len(reader.data) {
+		return nil, MsgPackInvalid.With("unexpected end at offset", reader.offset)
+	}
+	chunk := reader.data[reader.offset : reader.offset+length]
+	reader.offset += length
+	return chunk, nil
+}
+
+func (reader *msgPackReader) uint(size int) (uint64, error) {
+	chunk, err := reader.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(chunk[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(chunk)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(chunk)), nil
+	default:
+		return binary.BigEndian.Uint64(chunk), nil
+	}
+}
+
+func (reader *msgPackReader) read() (interface{}, error) {
+	chunk, err := reader.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := chunk[0]
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return reader.readMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return reader.readArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return reader.readString(int(code & 0x1f))
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin 8, 16, 32
+		length, err := reader.uint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return reader.next(int(length))
+	case 0xc7, 0xc8, 0xc9: // ext 8, 16, 32
+		length, err := reader.uint(1 << (code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return reader.readExt(int(length))
+	case 0xca:
+		bits, err := reader.uint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := reader.uint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return reader.uint(1 << (code - 0xcc))
+	case 0xd0:
+		value, err := reader.uint(1)
+		return int64(int8(value)), err
+	case 0xd1:
+		value, err := reader.uint(2)
+		return int64(int16(value)), err
+	case 0xd2:
+		value, err := reader.uint(4)
+		return int64(int32(value)), err
+	case 0xd3:
+		value, err := reader.uint(8)
+		return int64(value), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1, 2, 4, 8, 16
+		return reader.readExt(1 << (code - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		length, err := reader.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return reader.readString(int(length))
+	case 0xdc, 0xdd:
+		length, err := reader.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return reader.readArray(int(length))
+	case 0xde, 0xdf:
+		length, err := reader.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return reader.readMap(int(length))
+	}
+	return nil, MsgPackInvalid.With("code", fmt.Sprintf("0x%02x", code))
+}
+
+func (reader *msgPackReader) readString(length int) (interface{}, error) {
+	chunk, err := reader.next(length)
+	if err != nil {
+		return nil, err
+	}
+	return string(chunk), nil
+}
+
+func (reader *msgPackReader) readArray(length int) (interface{}, error) {
+	if length > len(reader.data)-reader.offset {
+		return nil, MsgPackInvalid.With("array length", length)
+	}
+	if reader.depth >= msgPackMaxDepth {
+		return nil, MsgPackInvalid.With("depth", reader.depth+1)
+	}
+	reader.depth++
+	defer func() { reader.depth-- }()
+	items := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		item, err := reader.read()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (reader *msgPackReader) readMap(length int) (interface{}, error) {
+	if length > len(reader.data)-reader.offset {
+		return nil, MsgPackInvalid.With("map length", length)
+	}
+	if reader.depth >= msgPackMaxDepth {
+		return nil, MsgPackInvalid.With("depth", reader.depth+1)
+	}
+	reader.depth++
+	defer func() { reader.depth-- }()
+	items := make(map[string]interface{}, length)
+	for i := 0; i < length; i++ {
+		key, err := reader.read()
+		if err != nil {
+			return nil, err
+		}
+		value, err := reader.read()
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := key.(string); ok {
+			items[name] = value
+		} else {
+			items[fmt.Sprint(key)] = value
+		}
+	}
+	return items, nil
+}
+
+// readExt reads an extension, only timestamps (type -1) are supported
+func (reader *msgPackReader) readExt(length int) (interface{}, error) {
+	chunk, err := reader.next(1)
+	if err != nil {
+		return nil, err
+	}
+	extType := int8(chunk[0])
+	if chunk, err = reader.next(length); err != nil {
+		return nil, err
+	}
+	if extType != -1 {
+		return nil, MsgPackInvalid.With("extension type", extType)
+	}
+	switch length {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(chunk)), 0).UTC(), nil
+	case 8:
+		value := binary.BigEndian.Uint64(chunk)
+		return time.Unix(int64(value&0x3ffffffff), int64(value>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(chunk[4:])), int64(binary.BigEndian.Uint32(chunk[:4]))).UTC(), nil
+	}
+	return nil, MsgPackInvalid.With("timestamp length", length)
+}
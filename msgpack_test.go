@@ -0,0 +1,119 @@
+package request_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+type msgPackStuff struct {
+	ID       string            `json:"id"`
+	Count    int64             `json:"count"`
+	Negative int               `json:"negative"`
+	Big      uint64            `json:"big"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  bool              `json:"enabled"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Data     []byte            `json:"data"`
+	Comment  *string           `json:"comment"`
+}
+
+func TestCanEncodeMsgPack(t *testing.T) {
+	// Example from https://msgpack.org
+	data, err := request.MsgPackEncoder.Encode(map[string]interface{}{"compact": true, "schema": 0})
+	require.NoError(t, err, "Failed to encode")
+	assert.Equal(t, "82a7636f6d70616374c3a6736368656d6100", hex.EncodeToString(data))
+}
+
+func TestCanEncodeAndDecodeMsgPack(t *testing.T) {
+	expected := msgPackStuff{
+		ID:       strings.Repeat("a", 300),
+		Count:    70000,
+		Negative: -200,
+		Big:      math.MaxUint64,
+		Ratio:    3.14,
+		Enabled:  true,
+		Tags:     []string{"one", "two"},
+		Labels:   map[string]string{"env": "test"},
+		Data:     []byte{0x00, 0x01, 0xff},
+	}
+	data, err := request.MsgPackEncoder.Encode(expected)
+	require.NoError(t, err, "Failed to encode")
+
+	var actual msgPackStuff
+	require.NoError(t, request.MsgPackDecoder.Decode(data, &actual), "Failed to decode")
+	assert.Equal(t, expected, actual)
+}
+
+func TestCanDecodeMsgPackTimestamp(t *testing.T) {
+	var results struct {
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	// {"createdAt": timestamp 32 of 1700000000}
+	data, _ := hex.DecodeString("81a9637265617465644174d6ff6553f100")
+	require.NoError(t, request.MsgPackDecoder.Decode(data, &results), "Failed to decode")
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), results.CreatedAt)
+}
+
+func TestShouldFailDecodingInvalidMsgPack(t *testing.T) {
+	var results map[string]interface{}
+	for _, data := range []string{"c1", "81a3", "82a161", "81a16101ff", "81a161c7010501"} {
+		payload, _ := hex.DecodeString(data)
+		err := request.MsgPackDecoder.Decode(payload, &results)
+		assert.ErrorIs(t, err, request.MsgPackInvalid, "Decoding %s should have failed", data)
+	}
+}
+
+func TestShouldFailDecodingTooDeeplyNestedMsgPack(t *testing.T) {
+	var results interface{}
+	// [[[[...[nil]...]]]] nested a million times
+	payload := append(bytes.Repeat([]byte{0x91}, 1000000), 0xc0)
+	err := request.MsgPackDecoder.Decode(payload, &results)
+	assert.ErrorIs(t, err, request.MsgPackInvalid)
+
+	// {"a": {"a": ... {"a": nil} ... }} nested a million times
+	payload = append(bytes.Repeat([]byte{0x81, 0xa1, 'a'}, 1000000), 0xc0)
+	err = request.MsgPackDecoder.Decode(payload, &results)
+	assert.ErrorIs(t, err, request.MsgPackInvalid)
+}
+
+func (suite *RequestSuite) TestCanSendAndReceiveMsgPack() {
+	suite.Require().NoError(request.RegisterDecoder(request.MsgPackMediaType, 1, request.MsgPackDecoder))
+	defer request.UnregisterDecoder(request.MsgPackMediaType)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received stuff
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != request.MsgPackMediaType || request.MsgPackDecoder.Decode(body, &received) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := request.MsgPackEncoder.Encode(stuff{ID: received.ID + "-" + r.Header.Get("Accept")})
+		w.Header().Set("Content-Type", request.MsgPackMediaType)
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	var results stuff
+	_, err := request.Send(&request.Options{
+		URL:         serverURL,
+		Payload:     stuff{ID: "1234"},
+		PayloadType: request.MsgPackMediaType,
+		Logger:      suite.Logger,
+	}, &results)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal("1234-application/json, application/msgpack", results.ID)
+}
@@ -0,0 +1,133 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// Attachment describes a file sent in a multipart form, see Options.Attachments
+type Attachment struct {
+	FieldName   string    // name of the form field (e.g.: "file")
+	FileName    string    // name of the file (e.g.: "image.png")
+	ContentType string    // MIME type of the file, if empty it is guessed from the FileName's extension or sniffed from files and io.ReaderAt
+	Reader      io.Reader // data of the file
+	Size        int64     // if not 0, the number of bytes the Reader must give
+}
+
+// quoteEscaper escapes the quotes of multipart form field and file names
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// buildMultipartContent builds a multipart data form with the given fields, in order, followed by the attachments of the options
+//
+// A field whose name starts with ">" gets the Attachment of the options, its value is the file name (deprecated, use Options.Attachments).
+func buildMultipartContent(log *logger.Logger, options *Options, fields []FormField) (*Content, error) {
+	log.Tracef("Building a multipart data form with %d fields and %d attachments", len(fields), len(options.Attachments))
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, field := range fields {
+		if strings.HasPrefix(field.Name, ">") {
+			key := strings.TrimPrefix(field.Name, ">")
+			if len(key) == 0 {
+				return nil, errors.Errorf("Empty key for multipart form field with attachment")
+			}
+			if len(field.Value) == 0 {
+				return nil, errors.Errorf("Empty value for multipart form field %s", key)
+			}
+			if options.Attachment == nil {
+				return nil, errors.Errorf("Missing/Empty Attachment for multipart form field %s", key)
+			}
+			if seeker, ok := options.Attachment.(io.Seeker); ok && options.Attempts > 1 {
+				if _, ok := sectionReader(options.Attachment); !ok {
+					// if options.Attempts == 1, we don't need to seek to the beginning of the attachment
+					if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+						return nil, errors.Wrapf(err, "Failed to seek to beginning of attachment for field %s", key)
+					}
+				}
+			}
+			attachment := Attachment{FieldName: key, FileName: field.Value, ContentType: options.AttachmentType, Reader: options.Attachment}
+			if err := writeAttachment(log, writer, attachment, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := writer.WriteField(field.Name, field.Value); err != nil {
+			return nil, errors.Wrapf(err, "Failed to create multipart form field %s", field.Name)
+		}
+		log.Tracef("  Added field %s = %s", field.Name, field.Value)
+	}
+	for index, attachment := range options.Attachments {
+		if len(attachment.FieldName) == 0 {
+			return nil, errors.ArgumentMissing.With(fmt.Sprintf("Attachments[%d].FieldName", index))
+		}
+		if attachment.Reader == nil {
+			return nil, errors.ArgumentMissing.With(fmt.Sprintf("Attachments[%d].Reader", index))
+		}
+		if err := writeAttachment(log, writer, attachment, false); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "Failed to create multipart data")
+	}
+	content, _ := ContentFromReader(body, writer.FormDataContentType())
+	return content, nil
+}
+
+// writeAttachment writes an attachment as a part of a multipart data form
+//
+// The Content-Type is guessed from the file name, sniffed from files and io.ReaderAt, or application/octet-stream.
+// If untyped is true, it is only sniffed (this is how the ">key" convention always worked).
+func writeAttachment(log *logger.Logger, writer *multipart.Writer, attachment Attachment, untyped bool) error {
+	var reader io.Reader = attachment.Reader
+	contentType := attachment.ContentType
+	if len(contentType) == 0 && !untyped {
+		contentType = mime.TypeByExtension(filepath.Ext(attachment.FileName))
+	}
+	if section, ok := sectionReader(attachment.Reader); ok {
+		reader = section
+		if len(contentType) == 0 {
+			sniffed := make([]byte, 512)
+			read, _ := section.ReadAt(sniffed, 0)
+			contentType = http.DetectContentType(sniffed[:read])
+		}
+	}
+	if len(contentType) == 0 && !untyped {
+		contentType = "application/octet-stream"
+	}
+	partHeader := textproto.MIMEHeader{}
+	if len(attachment.FileName) > 0 {
+		partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(attachment.FieldName), quoteEscaper.Replace(attachment.FileName)))
+	} else {
+		partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(attachment.FieldName)))
+	}
+	if len(contentType) > 0 {
+		partHeader.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create multipart for field %s", attachment.FieldName)
+	}
+	written, err := io.Copy(part, reader)
+	if err != nil {
+		return errors.Errorf("Failed to write attachment to multipart form field %s", attachment.FieldName)
+	}
+	if written == 0 {
+		return errors.Errorf("Missing/Empty Attachment for multipart form field %s", attachment.FieldName)
+	}
+	if attachment.Size > 0 && written != attachment.Size {
+		return ContentLengthMismatch.With(strconv.FormatInt(written, 10), attachment.Size)
+	}
+	log.Tracef("Wrote %d bytes to multipart form field %s", written, attachment.FieldName)
+	return nil
+}
@@ -0,0 +1,206 @@
+package request_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+	"github.com/gildas/go-request/requesttest"
+)
+
+type multipartPart struct {
+	FieldName   string `json:"fieldName"`
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Data        string `json:"data"`
+}
+
+func CreateMultipartTestServer(suite *RequestSuite) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		parts := []multipartPart{}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(part)
+			parts = append(parts, multipartPart{
+				FieldName:   part.FormName(),
+				FileName:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Data:        string(data),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"method": r.Method, "parts": parts})
+	}))
+}
+
+func (suite *RequestSuite) TestCanSendRequestWithAttachments() {
+	server := CreateMultipartTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	received := struct {
+		Method string          `json:"method"`
+		Parts  []multipartPart `json:"parts"`
+	}{}
+	_, err := request.Send(&request.Options{
+		URL:     serverURL,
+		Payload: map[string]string{"ID": "1234"},
+		Attachments: []request.Attachment{
+			{FieldName: "image", FileName: "image.png", Reader: strings.NewReader("not really a PNG")},
+			{FieldName: "notes", FileName: "notes.txt", ContentType: "text/markdown", Reader: strings.NewReader("# Notes"), Size: 7},
+		},
+		Logger: suite.Logger,
+	}, &received)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal(http.MethodPost, received.Method)
+	suite.Require().Len(received.Parts, 3)
+	suite.Assert().Equal(multipartPart{FieldName: "ID", Data: "1234"}, received.Parts[0])
+	suite.Assert().Equal(multipartPart{FieldName: "image", FileName: "image.png", ContentType: "image/png", Data: "not really a PNG"}, received.Parts[1], "Content Type should be guessed from the file name")
+	suite.Assert().Equal(multipartPart{FieldName: "notes", FileName: "notes.txt", ContentType: "text/markdown", Data: "# Notes"}, received.Parts[2])
+}
+
+func (suite *RequestSuite) TestCanSendRequestWithAttachmentsAndStructPayload() {
+	server := CreateMultipartTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	received := struct {
+		Parts []multipartPart `json:"parts"`
+	}{}
+	_, err := request.Send(&request.Options{
+		URL: serverURL,
+		Payload: struct {
+			Title string `form:"title"`
+		}{Title: "Holidays"},
+		Attachments: []request.Attachment{
+			{FieldName: "photo", FileName: "beach.jpg", Reader: strings.NewReader("sand and sea")},
+		},
+		Logger: suite.Logger,
+	}, &received)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().Len(received.Parts, 2)
+	suite.Assert().Equal(multipartPart{FieldName: "title", Data: "Holidays"}, received.Parts[0])
+	suite.Assert().Equal("photo", received.Parts[1].FieldName)
+	suite.Assert().Equal("image/jpeg", received.Parts[1].ContentType)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingRequestWithAttachmentOfWrongSize() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	_, err := request.Send(&request.Options{
+		URL: serverURL,
+		Attachments: []request.Attachment{
+			{FieldName: "file", FileName: "data.bin", Reader: strings.NewReader("12345"), Size: 10},
+		},
+		Logger: suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "Send should have failed")
+	suite.Assert().ErrorIs(err, request.ContentLengthMismatch)
+}
+
+func (suite *RequestSuite) TestShouldFailSendingRequestWithIncompleteAttachment() {
+	serverURL, _ := url.Parse(suite.Server.URL)
+	_, err := request.Send(&request.Options{
+		URL:         serverURL,
+		Attachments: []request.Attachment{{FileName: "data.bin", Reader: strings.NewReader("12345")}},
+		Logger:      suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "Send should have failed")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+
+	_, err = request.Send(&request.Options{
+		URL:         serverURL,
+		Attachments: []request.Attachment{{FieldName: "file", FileName: "data.bin"}},
+		Logger:      suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "Send should have failed")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
+
+func (suite *RequestSuite) TestCanSendRequestWithOrderedFormFields() {
+	server := CreateMultipartTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	received := struct {
+		Parts []multipartPart `json:"parts"`
+	}{}
+	_, err := request.Send(&request.Options{
+		URL: serverURL,
+		Payload: []request.FormField{
+			{Name: "key", Value: "uploads/image.png"},
+			{Name: "tags", Value: "beach"},
+			{Name: "tags", Value: "holidays"},
+			{Name: "acl", Value: "private"},
+		},
+		Attachments: []request.Attachment{
+			{FieldName: "file", FileName: "image.png", Reader: strings.NewReader("not really a PNG")},
+		},
+		Logger: suite.Logger,
+	}, &received)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	names := []string{}
+	for _, part := range received.Parts {
+		names = append(names, part.FieldName)
+	}
+	suite.Assert().Equal([]string{"key", "tags", "tags", "acl", "file"}, names, "Fields should be sent in order, followed by the files")
+	suite.Assert().Equal("beach", received.Parts[1].Data)
+	suite.Assert().Equal("holidays", received.Parts[2].Data)
+}
+
+func (suite *RequestSuite) TestCanSendMultipartFormWithoutAttachments() {
+	server := CreateMultipartTestServer(suite)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	received := struct {
+		Parts []multipartPart `json:"parts"`
+	}{}
+	_, err := request.Send(&request.Options{
+		URL:         serverURL,
+		Payload:     []request.FormField{{Name: "b", Value: "2"}, {Name: "a", Value: "1"}},
+		PayloadType: "multipart/form-data",
+		Logger:      suite.Logger,
+	}, &received)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Require().Len(received.Parts, 2)
+	suite.Assert().Equal(multipartPart{FieldName: "b", Data: "2"}, received.Parts[0])
+	suite.Assert().Equal(multipartPart{FieldName: "a", Data: "1"}, received.Parts[1])
+}
+
+func (suite *RequestSuite) TestCanSendFormWithOrderedFormFields() {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	echo := requesttest.Echo{}
+	_, err := request.Send(&request.Options{
+		URL: server.Endpoint("/echo"),
+		Payload: []request.FormField{
+			{Name: "tags", Value: "beach"},
+			{Name: "name", Value: "Joe Doe"},
+			{Name: "tags", Value: "holidays"},
+		},
+		Logger: suite.Logger,
+	}, &echo)
+	suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+	suite.Assert().Equal(http.MethodPost, echo.Method)
+	suite.Assert().Equal("application/x-www-form-urlencoded", echo.Headers.Get("Content-Type"))
+	suite.Assert().Equal("tags=beach&name=Joe+Doe&tags=holidays", echo.Body)
+}
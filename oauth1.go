@@ -0,0 +1,132 @@
+package request
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// OAuth 1.0a signature methods
+const (
+	OAuth1HMACSHA1 = "HMAC-SHA1"
+	OAuth1RSASHA1  = "RSA-SHA1"
+)
+
+// OAuth1Signer signs requests with OAuth 1.0a (RFC 5849)
+//
+// The query parameters and the parameters of application/x-www-form-urlencoded payloads are signed.
+//
+// implements Signer
+type OAuth1Signer struct {
+	ConsumerKey     string
+	ConsumerSecret  string          // used with HMAC-SHA1
+	Token           string          // optional
+	TokenSecret     string          // used with HMAC-SHA1
+	SignatureMethod string          // OAuth1HMACSHA1 or OAuth1RSASHA1, by default: OAuth1HMACSHA1
+	PrivateKey      *rsa.PrivateKey // used with RSA-SHA1
+	Realm           string          // optional
+}
+
+// Sign signs the request with the current time and a random nonce
+//
+// implements Signer
+func (signer OAuth1Signer) Sign(req *http.Request, payload []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.WithStack(err)
+	}
+	return signer.SignAt(req, payload, time.Now(), hex.EncodeToString(nonce))
+}
+
+// SignAt signs the request as if it was sent at the given time with the given nonce
+func (signer OAuth1Signer) SignAt(req *http.Request, payload []byte, at time.Time, nonce string) error {
+	if len(signer.ConsumerKey) == 0 {
+		return errors.ArgumentMissing.With("ConsumerKey")
+	}
+	method := signer.SignatureMethod
+	if len(method) == 0 {
+		method = OAuth1HMACSHA1
+	}
+	oauth := map[string]string{
+		"oauth_consumer_key":     signer.ConsumerKey,
+		"oauth_nonce":            nonce,
+		"oauth_signature_method": method,
+		"oauth_timestamp":        strconv.FormatInt(at.Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	if len(signer.Token) > 0 {
+		oauth["oauth_token"] = signer.Token
+	}
+
+	parameters := [][2]string{}
+	for key, value := range oauth {
+		parameters = append(parameters, [2]string{percentEncode(key), percentEncode(value)})
+	}
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			parameters = append(parameters, [2]string{percentEncode(key), percentEncode(value)})
+		}
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(payload))
+		if err != nil {
+			return errors.ArgumentInvalid.With("payload", string(payload))
+		}
+		for key, values := range form {
+			for _, value := range values {
+				parameters = append(parameters, [2]string{percentEncode(key), percentEncode(value)})
+			}
+		}
+	}
+	baseURL := url.URL{Scheme: strings.ToLower(req.URL.Scheme), Host: strings.ToLower(req.URL.Host), Path: req.URL.Path}
+	if (baseURL.Scheme == "http" && baseURL.Port() == "80") || (baseURL.Scheme == "https" && baseURL.Port() == "443") {
+		baseURL.Host = baseURL.Hostname()
+	}
+	base := strings.ToUpper(req.Method) + "&" + percentEncode(baseURL.String()) + "&" + percentEncode(joinSortedParameters(parameters))
+
+	switch method {
+	case OAuth1HMACSHA1:
+		mac := hmac.New(sha1.New, []byte(percentEncode(signer.ConsumerSecret)+"&"+percentEncode(signer.TokenSecret)))
+		mac.Write([]byte(base))
+		oauth["oauth_signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	case OAuth1RSASHA1:
+		if signer.PrivateKey == nil {
+			return errors.ArgumentMissing.With("PrivateKey")
+		}
+		digest := sha1.Sum([]byte(base))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, signer.PrivateKey, crypto.SHA1, digest[:])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		oauth["oauth_signature"] = base64.StdEncoding.EncodeToString(signature)
+	default:
+		return errors.ArgumentInvalid.With("SignatureMethod", method)
+	}
+
+	keys := make([]string, 0, len(oauth))
+	for key := range oauth {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fields := make([]string, 0, len(keys)+1)
+	if len(signer.Realm) > 0 {
+		fields = append(fields, `realm="`+signer.Realm+`"`)
+	}
+	for _, key := range keys {
+		fields = append(fields, percentEncode(key)+`="`+percentEncode(oauth[key])+`"`)
+	}
+	req.Header.Set("Authorization", "OAuth "+strings.Join(fields, ", "))
+	return nil
+}
@@ -0,0 +1,385 @@
+package request
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// DefaultOAuth2RefreshBefore defines how long before its expiry an OAuth2 token is refreshed
+const DefaultOAuth2RefreshBefore = 1 * time.Minute
+
+// OAuth2Token describes the tokens given by an OAuth2 token endpoint (RFC 6749)
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`   // OpenID Connect
+	ExpiresAt    time.Time `json:"expires_at,omitempty"` // zero if the token does not expire
+}
+
+// Expired tells if the token is expired or will be within the given delay
+func (token OAuth2Token) Expired(within time.Duration) bool {
+	return !token.ExpiresAt.IsZero() && !time.Now().Add(within).Before(token.ExpiresAt)
+}
+
+// Authorization gets the Authorization of this token (e.g.: "Bearer xxx")
+func (token OAuth2Token) Authorization() string {
+	if len(token.TokenType) == 0 || strings.EqualFold(token.TokenType, "bearer") {
+		return BearerAuthorization(token.AccessToken)
+	}
+	return token.TokenType + " " + token.AccessToken
+}
+
+// OAuth2AuthStyle tells how the client credentials are sent to the token endpoint
+type OAuth2AuthStyle int
+
+const (
+	// OAuth2AuthInHeader sends the client credentials with a Basic Authorization
+	OAuth2AuthInHeader OAuth2AuthStyle = iota
+	// OAuth2AuthInParams sends the client credentials in the client_id and client_secret form fields
+	OAuth2AuthInParams
+)
+
+// OAuth2Config describes an OAuth2 client and the endpoints of its authorization server
+type OAuth2Config struct {
+	AuthURL      *url.URL        // the authorization endpoint, where users are sent to get a code
+	TokenURL     *url.URL        // the token endpoint
+	ClientID     string          // the client identifier
+	ClientSecret string          // the client secret, empty for public clients
+	RedirectURL  string          // the redirect_uri, if any
+	Scopes       []string        // the scopes to request, if any
+	AuthStyle    OAuth2AuthStyle // how the client credentials are sent, by default: OAuth2AuthInHeader
+	Options      *Options        // if not nil, the options of the token requests (e.g.: Logger, Timeout, Transport)
+}
+
+// AuthCodeURL gets the URL of the authorization endpoint where users are sent to get an authorization code
+//
+// The state is sent back to the RedirectURL with the code, it protects against CSRF.
+// The other parameters are added to the query (e.g.: code_challenge, prompt, access_type).
+func (config OAuth2Config) AuthCodeURL(state string, parameters ...FormField) (*url.URL, error) {
+	if config.AuthURL == nil {
+		return nil, errors.ArgumentMissing.With("AuthURL")
+	}
+	authURL := cloneURL(config.AuthURL)
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientID)
+	if len(config.RedirectURL) > 0 {
+		query.Set("redirect_uri", config.RedirectURL)
+	}
+	if len(config.Scopes) > 0 {
+		query.Set("scope", strings.Join(config.Scopes, " "))
+	}
+	if len(state) > 0 {
+		query.Set("state", state)
+	}
+	for _, parameter := range parameters {
+		query.Add(parameter.Name, parameter.Value)
+	}
+	authURL.RawQuery = query.Encode()
+	return authURL, nil
+}
+
+// Exchange exchanges an authorization code for a token
+//
+// The other parameters are sent to the token endpoint (e.g.: code_verifier).
+func (config OAuth2Config) Exchange(context context.Context, code string, parameters ...FormField) (*OAuth2Token, error) {
+	if len(code) == 0 {
+		return nil, errors.ArgumentMissing.With("code")
+	}
+	fields := []FormField{{Name: "grant_type", Value: "authorization_code"}, {Name: "code", Value: code}}
+	if len(config.RedirectURL) > 0 {
+		fields = append(fields, FormField{Name: "redirect_uri", Value: config.RedirectURL})
+	}
+	return config.requestToken(context, append(fields, parameters...))
+}
+
+// Refresh gets a new token with a refresh token
+//
+// If the token endpoint does not give a new refresh token, the given one is kept in the new token.
+func (config OAuth2Config) Refresh(context context.Context, refreshToken string) (*OAuth2Token, error) {
+	if len(refreshToken) == 0 {
+		return nil, errors.ArgumentMissing.With("refreshToken")
+	}
+	fields := []FormField{{Name: "grant_type", Value: "refresh_token"}, {Name: "refresh_token", Value: refreshToken}}
+	if len(config.Scopes) > 0 {
+		fields = append(fields, FormField{Name: "scope", Value: strings.Join(config.Scopes, " ")})
+	}
+	token, err := config.requestToken(context, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(token.RefreshToken) == 0 {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// ClientCredentials gets a token for the client itself
+//
+// The other parameters are sent to the token endpoint (e.g.: audience, resource).
+func (config OAuth2Config) ClientCredentials(context context.Context, parameters ...FormField) (*OAuth2Token, error) {
+	fields := []FormField{{Name: "grant_type", Value: "client_credentials"}}
+	if len(config.Scopes) > 0 {
+		fields = append(fields, FormField{Name: "scope", Value: strings.Join(config.Scopes, " ")})
+	}
+	return config.requestToken(context, append(fields, parameters...))
+}
+
+// oauth2TokenResponse is the body of the responses of token endpoints, JSON or form-encoded
+type oauth2TokenResponse struct {
+	AccessToken      string      `json:"access_token" url:"access_token"`
+	TokenType        string      `json:"token_type" url:"token_type"`
+	RefreshToken     string      `json:"refresh_token" url:"refresh_token"`
+	Scope            string      `json:"scope" url:"scope"`
+	IDToken          string      `json:"id_token" url:"id_token"`
+	ExpiresIn        json.Number `json:"expires_in" url:"expires_in"` // some servers send a string
+	Error            string      `json:"error" url:"error"`
+	ErrorDescription string      `json:"error_description" url:"error_description"`
+	ErrorURI         string      `json:"error_uri" url:"error_uri"`
+}
+
+// requestToken sends the form fields with the client credentials to the token endpoint
+func (config OAuth2Config) requestToken(context context.Context, fields []FormField) (*OAuth2Token, error) {
+	if config.TokenURL == nil {
+		return nil, errors.ArgumentMissing.With("TokenURL")
+	}
+	if len(config.ClientID) == 0 {
+		return nil, errors.ArgumentMissing.With("ClientID")
+	}
+	options := config.Options.Clone()
+	if options == nil {
+		options = &Options{}
+	}
+	if context != nil {
+		options.Context = context
+	}
+	options.Method = http.MethodPost
+	options.URL = config.TokenURL
+	options.URLString = ""
+	options.BaseURL = nil
+	options.PayloadType = "application/x-www-form-urlencoded"
+	if config.AuthStyle == OAuth2AuthInParams || len(config.ClientSecret) == 0 {
+		fields = append(fields, FormField{Name: "client_id", Value: config.ClientID})
+		if len(config.ClientSecret) > 0 {
+			fields = append(fields, FormField{Name: "client_secret", Value: config.ClientSecret})
+		}
+	} else {
+		// RFC 6749, 2.3.1: the client credentials are form-encoded before being used in the Basic Authorization
+		options.Authorization = BasicAuthorization(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+	options.Payload = fields
+	options.TokenProvider = nil
+	options.OnUnauthorized = nil
+	if options.ResponseEnvelope == nil {
+		options.ResponseEnvelope = JSONErrorEnvelope{}
+	}
+
+	response := oauth2TokenResponse{}
+	content, err := Send(options, &response)
+	if err != nil {
+		if oauth2Err := oauth2FormError(content, err); oauth2Err != nil {
+			return nil, oauth2Err
+		}
+		return nil, err
+	}
+	if len(response.Error) > 0 { // some servers (e.g.: GitHub) give errors with a 200 OK
+		return nil, oauth2Error(response, content.StatusCode, nil)
+	}
+	if len(response.AccessToken) == 0 {
+		return nil, errors.ArgumentMissing.With("access_token")
+	}
+	token := OAuth2Token{
+		AccessToken:  response.AccessToken,
+		TokenType:    response.TokenType,
+		RefreshToken: response.RefreshToken,
+		Scope:        response.Scope,
+		IDToken:      response.IDToken,
+	}
+	if expiresIn, err := response.ExpiresIn.Int64(); err == nil && expiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// oauth2FormError gets the OAuth2 error of a form-encoded error response, JSON ones are given by the JSONErrorEnvelope
+func oauth2FormError(content *Content, cause error) error {
+	if content == nil {
+		return nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(content.Type); err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return nil
+	}
+	response := oauth2TokenResponse{}
+	if err := FormDecoder.Decode(content.Data, &response); err != nil || len(response.Error) == 0 {
+		return nil
+	}
+	return oauth2Error(response, content.StatusCode, cause)
+}
+
+// oauth2Error gets the EnvelopeError of an OAuth2 error response
+func oauth2Error(response oauth2TokenResponse, statusCode int, cause error) error {
+	envelopeError := EnvelopeError{
+		Code:       response.Error,
+		Message:    response.ErrorDescription,
+		StatusCode: statusCode,
+		Details:    map[string]interface{}{"error": response.Error},
+	}
+	if len(response.ErrorDescription) > 0 {
+		envelopeError.Details["error_description"] = response.ErrorDescription
+	} else {
+		envelopeError.Code, envelopeError.Message = "", response.Error
+	}
+	if len(response.ErrorURI) > 0 {
+		envelopeError.Details["error_uri"] = response.ErrorURI
+	}
+	if cause != nil {
+		envelopeError.Cause = cause
+		return &envelopeError
+	}
+	return envelopeError.withCause()
+}
+
+// NewOAuth2CodeVerifier creates a PKCE code verifier and its S256 code challenge (RFC 7636)
+//
+// Send the challenge to the authorization endpoint and the verifier to the token endpoint:
+//
+//	verifier, challenge, err := request.NewOAuth2CodeVerifier()
+//	authURL, err := config.AuthCodeURL(state, request.FormField{Name: "code_challenge", Value: challenge}, request.FormField{Name: "code_challenge_method", Value: "S256"})
+//	// ...
+//	token, err := config.Exchange(ctx, code, request.FormField{Name: "code_verifier", Value: verifier})
+func NewOAuth2CodeVerifier() (verifier string, challenge string, err error) {
+	random := make([]byte, 32)
+	if _, err = rand.Read(random); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(random)
+	hash := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// OAuth2TokenProvider provides the Authorization of requests with an OAuth2 token, refreshed when it is about to expire
+//
+// implements TokenProvider
+//
+// Use it as the Options.TokenProvider, and its RefreshAuthorization as the Options.OnUnauthorized:
+//
+//	provider := &request.OAuth2TokenProvider{Config: config, Token: token, Store: store}
+//	options.TokenProvider = provider
+//	options.OnUnauthorized = provider.RefreshAuthorization
+type OAuth2TokenProvider struct {
+	Config        *OAuth2Config
+	Token         *OAuth2Token  // the current token, if nil it is loaded from the Store
+	Store         TokenStore    // if not nil, the tokens are loaded from it and the refreshed tokens are saved in it
+	Audience      string        // the key of the token in the Store, by default: the ClientID of the Config
+	RefreshBefore time.Duration // how long before its expiry the token is refreshed, by default: 1 minute
+
+	mutex sync.Mutex
+}
+
+// Authorization provides the Authorization with a valid token
+//
+// implements TokenProvider
+func (provider *OAuth2TokenProvider) Authorization(context context.Context) (string, error) {
+	token, err := provider.CurrentToken(context)
+	if err != nil {
+		return "", err
+	}
+	return token.Authorization(), nil
+}
+
+// RefreshAuthorization refreshes the token, even if it is not about to expire, and provides its Authorization
+//
+// It can be used as the Options.OnUnauthorized.
+func (provider *OAuth2TokenProvider) RefreshAuthorization(context context.Context) (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if err := provider.load(context); err != nil {
+		return "", err
+	}
+	if err := provider.refresh(context); err != nil {
+		return "", err
+	}
+	return provider.Token.Authorization(), nil
+}
+
+// CurrentToken gets the current token, it is refreshed if it is about to expire
+func (provider *OAuth2TokenProvider) CurrentToken(context context.Context) (*OAuth2Token, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if err := provider.load(context); err != nil {
+		return nil, err
+	}
+	refreshBefore := provider.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultOAuth2RefreshBefore
+	}
+	if provider.Token.Expired(refreshBefore) {
+		if err := provider.refresh(context); err != nil {
+			return nil, err
+		}
+	}
+	token := *provider.Token
+	return &token, nil
+}
+
+// audience gets the key of the token in the Store
+func (provider *OAuth2TokenProvider) audience() string {
+	if len(provider.Audience) == 0 && provider.Config != nil {
+		return provider.Config.ClientID
+	}
+	return provider.Audience
+}
+
+// load loads the token from the Store if there is no current token
+func (provider *OAuth2TokenProvider) load(context context.Context) error {
+	if provider.Token != nil {
+		return nil
+	}
+	if provider.Store != nil {
+		token, err := provider.Store.Load(context, provider.audience())
+		if err != nil {
+			return errors.Wrap(err, "Failed to load the token from the store")
+		}
+		provider.Token = token
+	}
+	if provider.Token == nil {
+		return errors.ArgumentMissing.With("Token")
+	}
+	return nil
+}
+
+// refresh refreshes the current token and saves it in the Store
+func (provider *OAuth2TokenProvider) refresh(context context.Context) error {
+	if provider.Config == nil {
+		return errors.ArgumentMissing.With("Config")
+	}
+	if len(provider.Token.RefreshToken) == 0 {
+		return errors.ArgumentMissing.With("RefreshToken")
+	}
+	token, err := provider.Config.Refresh(context, provider.Token.RefreshToken)
+	if err != nil {
+		return errors.Wrap(err, "Failed to refresh the token")
+	}
+	provider.Token = token
+	if provider.Store != nil {
+		if err := provider.Store.Save(context, provider.audience(), token); err != nil {
+			return errors.Wrap(err, "Failed to save the token in the store")
+		}
+	}
+	return nil
+}
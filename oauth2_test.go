@@ -0,0 +1,257 @@
+package request_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateOAuth2TestServer(suite *RequestSuite, refreshes *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			id, secret, _ := r.BasicAuth()
+			id, _ = url.QueryUnescape(id)
+			secret, _ = url.QueryUnescape(secret)
+			if id != "my-client" || secret != "s3cr3t/+" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+				return
+			}
+			switch r.PostForm.Get("grant_type") {
+			case "authorization_code":
+				if r.PostForm.Get("code") != "CODE" || r.PostForm.Get("redirect_uri") != "https://app.example.com/callback" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "The code has expired"}`))
+					return
+				}
+				hash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+				if base64.RawURLEncoding.EncodeToString(hash[:]) != "9IMsKG_aXQXK49xjKpLFxi2BGMUZVrq3tqLOgK2d4rE" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error": "invalid_grant", "error_description": "Invalid code verifier"}`))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"access_token": "ACCESS-1", "token_type": "bearer", "expires_in": 3600, "refresh_token": "REFRESH-1", "scope": "read write"}`))
+			case "refresh_token":
+				if r.PostForm.Get("refresh_token") != "REFRESH-1" {
+					w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`error=invalid_grant&error_description=Unknown+refresh+token`))
+					return
+				}
+				refreshes.Add(1)
+				w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+				_, _ = w.Write([]byte(`access_token=ACCESS-2&token_type=bearer&expires_in=3600`))
+			case "client_credentials":
+				w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+				_, _ = w.Write([]byte(`error=unsupported_grant_type`)) // like GitHub, with a 200 OK
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case "/api":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"authorization": "` + r.Header.Get("Authorization") + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func (suite *RequestSuite) TestCanExchangeOAuth2Code() {
+	server := CreateOAuth2TestServer(suite, &atomic.Int32{})
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	config := request.OAuth2Config{
+		TokenURL:     serverURL.JoinPath("token"),
+		ClientID:     "my-client",
+		ClientSecret: "s3cr3t/+",
+		RedirectURL:  "https://app.example.com/callback",
+		Options:      &request.Options{Logger: suite.Logger},
+	}
+	token, err := config.Exchange(context.Background(), "CODE", request.FormField{Name: "code_verifier", Value: "dBjftJeZ4CVP-mJ92K9RNJR7Gg3Rv3Cx0Rce7P5zj8A"})
+	suite.Require().NoError(err, "Failed to exchange the code, err=%+v", err)
+	suite.Assert().Equal("ACCESS-1", token.AccessToken)
+	suite.Assert().Equal("REFRESH-1", token.RefreshToken)
+	suite.Assert().Equal("read write", token.Scope)
+	suite.Assert().Equal("Bearer ACCESS-1", token.Authorization())
+	suite.Assert().WithinDuration(time.Now().Add(time.Hour), token.ExpiresAt, 5*time.Second)
+	suite.Assert().False(token.Expired(request.DefaultOAuth2RefreshBefore))
+}
+
+func (suite *RequestSuite) TestShouldFailExchangingInvalidOAuth2Code() {
+	server := CreateOAuth2TestServer(suite, &atomic.Int32{})
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	config := request.OAuth2Config{
+		TokenURL:     serverURL.JoinPath("token"),
+		ClientID:     "my-client",
+		ClientSecret: "s3cr3t/+",
+		RedirectURL:  "https://app.example.com/callback",
+		Options:      &request.Options{Logger: suite.Logger},
+	}
+	_, err := config.Exchange(context.Background(), "EXPIRED")
+	suite.Require().Error(err, "The exchange should have failed")
+	var envelopeError *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelopeError)
+	suite.Assert().Equal("invalid_grant", envelopeError.Code)
+	suite.Assert().Equal("The code has expired", envelopeError.Message)
+	suite.Assert().ErrorIs(err, errors.HTTPBadRequest)
+
+	_, err = config.Exchange(context.Background(), "")
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
+
+func (suite *RequestSuite) TestCanRefreshOAuth2Token() {
+	refreshes := atomic.Int32{}
+	server := CreateOAuth2TestServer(suite, &refreshes)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	config := request.OAuth2Config{
+		TokenURL:     serverURL.JoinPath("token"),
+		ClientID:     "my-client",
+		ClientSecret: "s3cr3t/+",
+		Options:      &request.Options{Logger: suite.Logger},
+	}
+	token, err := config.Refresh(context.Background(), "REFRESH-1")
+	suite.Require().NoError(err, "Failed to refresh the token, err=%+v", err)
+	suite.Assert().Equal("ACCESS-2", token.AccessToken)
+	suite.Assert().Equal("REFRESH-1", token.RefreshToken, "The refresh token should be kept")
+
+	_, err = config.Refresh(context.Background(), "REFRESH-0")
+	suite.Require().Error(err, "The refresh should have failed")
+	var envelopeError *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelopeError)
+	suite.Assert().Equal("invalid_grant", envelopeError.Code)
+	suite.Assert().Equal("Unknown refresh token", envelopeError.Message)
+	suite.Assert().ErrorIs(err, errors.HTTPBadRequest)
+}
+
+func (suite *RequestSuite) TestShouldFailGettingOAuth2TokenWithErrorIn200() {
+	server := CreateOAuth2TestServer(suite, &atomic.Int32{})
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	config := request.OAuth2Config{
+		TokenURL:     serverURL.JoinPath("token"),
+		ClientID:     "my-client",
+		ClientSecret: "s3cr3t/+",
+		Options:      &request.Options{Logger: suite.Logger},
+	}
+	_, err := config.ClientCredentials(context.Background())
+	suite.Require().Error(err, "Getting the token should have failed")
+	var envelopeError *request.EnvelopeError
+	suite.Require().ErrorAs(err, &envelopeError)
+	suite.Assert().Equal("unsupported_grant_type", envelopeError.Message)
+}
+
+func (suite *RequestSuite) TestCanSendRequestWithOAuth2TokenProvider() {
+	refreshes := atomic.Int32{}
+	server := CreateOAuth2TestServer(suite, &refreshes)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	store := &request.MemoryTokenStore{}
+	suite.Require().NoError(store.Save(context.Background(), "my-client", &request.OAuth2Token{
+		AccessToken:  "ACCESS-1",
+		RefreshToken: "REFRESH-1",
+		ExpiresAt:    time.Now().Add(30 * time.Second), // expires within the RefreshBefore delay
+	}))
+	provider := &request.OAuth2TokenProvider{
+		Config: &request.OAuth2Config{
+			TokenURL:     serverURL.JoinPath("token"),
+			ClientID:     "my-client",
+			ClientSecret: "s3cr3t/+",
+			Options:      &request.Options{Logger: suite.Logger},
+		},
+		Store: store,
+	}
+	for i := 0; i < 2; i++ {
+		results := struct {
+			Authorization string `json:"authorization"`
+		}{}
+		_, err := request.Send(&request.Options{
+			URL:           serverURL.JoinPath("api"),
+			TokenProvider: provider,
+			Logger:        suite.Logger,
+		}, &results)
+		suite.Require().NoError(err, "Failed sending request, err=%+v", err)
+		suite.Assert().Equal("Bearer ACCESS-2", results.Authorization)
+	}
+	suite.Assert().Equal(int32(1), refreshes.Load(), "The token should be refreshed once")
+
+	saved, err := store.Load(context.Background(), "my-client")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(saved, "The refreshed token should be saved")
+	suite.Assert().Equal("ACCESS-2", saved.AccessToken)
+	suite.Assert().Equal("REFRESH-1", saved.RefreshToken)
+}
+
+func (suite *RequestSuite) TestShouldFailOAuth2TokenProviderWithoutToken() {
+	provider := &request.OAuth2TokenProvider{Config: &request.OAuth2Config{ClientID: "my-client"}, Store: &request.MemoryTokenStore{}}
+	_, err := provider.Authorization(context.Background())
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
+
+func TestCanGetOAuth2AuthCodeURL(t *testing.T) {
+	authURL, _ := url.Parse("https://auth.example.com/authorize?audience=api")
+	config := request.OAuth2Config{
+		AuthURL:     authURL,
+		ClientID:    "my-client",
+		RedirectURL: "https://app.example.com/callback",
+		Scopes:      []string{"openid", "profile"},
+	}
+	codeURL, err := config.AuthCodeURL("xyz", request.FormField{Name: "code_challenge_method", Value: "S256"})
+	require.NoError(t, err)
+	assert.Equal(t, "auth.example.com", codeURL.Host)
+	query := codeURL.Query()
+	assert.Equal(t, "api", query.Get("audience"))
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "my-client", query.Get("client_id"))
+	assert.Equal(t, "https://app.example.com/callback", query.Get("redirect_uri"))
+	assert.Equal(t, "openid profile", query.Get("scope"))
+	assert.Equal(t, "xyz", query.Get("state"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "audience=api", authURL.RawQuery, "The AuthURL should not be modified")
+
+	_, err = request.OAuth2Config{}.AuthCodeURL("xyz")
+	assert.ErrorIs(t, err, errors.ArgumentMissing)
+}
+
+func TestCanCreateOAuth2CodeVerifier(t *testing.T) {
+	verifier, challenge, err := request.NewOAuth2CodeVerifier()
+	require.NoError(t, err)
+	assert.Len(t, verifier, 43)
+	hash := sha256.Sum256([]byte(verifier))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(hash[:]), challenge)
+}
+
+func TestCanGetOAuth2TokenAuthorization(t *testing.T) {
+	assert.Equal(t, "Bearer abc", request.OAuth2Token{AccessToken: "abc"}.Authorization())
+	assert.Equal(t, "MAC abc", request.OAuth2Token{AccessToken: "abc", TokenType: "MAC"}.Authorization())
+	assert.False(t, request.OAuth2Token{AccessToken: "abc"}.Expired(time.Hour), "Tokens without expiry should never expire")
+	assert.True(t, request.OAuth2Token{AccessToken: "abc", ExpiresAt: time.Now().Add(time.Minute)}.Expired(2*time.Minute))
+}
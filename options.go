@@ -0,0 +1,194 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Clone returns a copy of these Options that can be changed without changing them
+//
+// The URLs, headers, trailers, parameters, query values, cookies, attachments, status codes, API Key, and encryptions are deep copied.
+// The Transport, Logger, Context, Payload, and the other interfaces and functions are shared with the copy.
+func (options *Options) Clone() *Options {
+	if options == nil {
+		return nil
+	}
+	clone := *options
+	clone.URL = cloneURL(options.URL)
+	clone.BaseURL = cloneURL(options.BaseURL)
+	clone.Proxy = cloneURL(options.Proxy)
+	clone.Headers = cloneStringMap(options.Headers)
+	clone.Header = cloneHeader(options.Header)
+	clone.Trailer = cloneHeader(options.Trailer)
+	clone.Cookies = cloneCookies(options.Cookies)
+	clone.Parameters = cloneStringMap(options.Parameters)
+	clone.PathParameters = cloneStringMap(options.PathParameters)
+	clone.QueryValues = cloneValues(options.QueryValues)
+	clone.RequireResponseHeaders = cloneStringMap(options.RequireResponseHeaders)
+	clone.Attachments = cloneSlice(options.Attachments)
+	clone.AllowedSchemes = cloneSlice(options.AllowedSchemes)
+	clone.PinnedCertificates = cloneSlice(options.PinnedCertificates)
+	clone.ExpectStatus = cloneSlice(options.ExpectStatus)
+	clone.AcceptableStatusCodes = cloneSlice(options.AcceptableStatusCodes)
+	clone.RetryableStatusCodes = cloneSlice(options.RetryableStatusCodes)
+	if options.APIKey != nil {
+		apiKey := *options.APIKey
+		clone.APIKey = &apiKey
+	}
+	if options.PayloadEncryption != nil {
+		encryption := Encryption{Algorithm: options.PayloadEncryption.Algorithm, Key: cloneSlice(options.PayloadEncryption.Key)}
+		clone.PayloadEncryption = &encryption
+	}
+	if options.ResponseDecryption != nil {
+		encryption := Encryption{Algorithm: options.ResponseDecryption.Algorithm, Key: cloneSlice(options.ResponseDecryption.Key)}
+		clone.ResponseDecryption = &encryption
+	}
+	return &clone
+}
+
+// MergeOptions returns new Options made of the base Options overridden by the override Options
+//
+// Each field of override that is not the zero value replaces the field of base.
+// Headers, Header, Trailer, Parameters, PathParameters, QueryValues, and RequireResponseHeaders are merged key by key,
+// Cookies are merged by name. Neither base nor override are modified.
+//
+// As zero values are ignored, a boolean that is true in base cannot be set to false by override.
+func MergeOptions(base, override *Options) *Options {
+	if base == nil {
+		return override.Clone()
+	}
+	merged := base.Clone()
+	if override == nil {
+		return merged
+	}
+	source := override.Clone()
+	mergedValue := reflect.ValueOf(merged).Elem()
+	sourceValue := reflect.ValueOf(source).Elem()
+	for i := 0; i < sourceValue.NumField(); i++ {
+		if field := sourceValue.Field(i); !field.IsZero() && mergedValue.Type().Field(i).IsExported() {
+			switch field.Kind() {
+			case reflect.Map:
+				continue // merged below
+			case reflect.Slice:
+				if field.Len() == 0 {
+					continue
+				}
+			}
+			mergedValue.Field(i).Set(field)
+		}
+	}
+	merged.Headers = mergeStringMaps(merged.Headers, source.Headers)
+	merged.Parameters = mergeStringMaps(merged.Parameters, source.Parameters)
+	merged.PathParameters = mergeStringMaps(merged.PathParameters, source.PathParameters)
+	merged.RequireResponseHeaders = mergeStringMaps(merged.RequireResponseHeaders, source.RequireResponseHeaders)
+	merged.Header = http.Header(mergeValues(url.Values(merged.Header), url.Values(source.Header)))
+	merged.Trailer = http.Header(mergeValues(url.Values(merged.Trailer), url.Values(source.Trailer)))
+	merged.QueryValues = mergeValues(merged.QueryValues, source.QueryValues)
+	if source.URL == nil && len(source.URLString) > 0 {
+		merged.URL = nil // the URL of base would take precedence over the URLString of override
+	}
+	merged.Cookies = cloneCookies(base.Cookies)
+	for _, cookie := range source.Cookies {
+		replaced := false
+		for index, existing := range merged.Cookies {
+			if existing.Name == cookie.Name {
+				merged.Cookies[index] = cookie
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Cookies = append(merged.Cookies, cookie)
+		}
+	}
+	return merged
+}
+
+func cloneURL(source *url.URL) *url.URL {
+	if source == nil {
+		return nil
+	}
+	clone := *source
+	if source.User != nil {
+		user := *source.User
+		clone.User = &user
+	}
+	return &clone
+}
+
+func cloneSlice[T any](source []T) []T {
+	if source == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(source)), source...)
+}
+
+func cloneStringMap(source map[string]string) map[string]string {
+	if source == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(source))
+	for key, value := range source {
+		clone[key] = value
+	}
+	return clone
+}
+
+func cloneValues(source url.Values) url.Values {
+	if source == nil {
+		return nil
+	}
+	clone := make(url.Values, len(source))
+	for key, values := range source {
+		clone[key] = cloneSlice(values)
+	}
+	return clone
+}
+
+func cloneHeader(source http.Header) http.Header {
+	return http.Header(cloneValues(url.Values(source)))
+}
+
+func cloneCookies(source []*http.Cookie) []*http.Cookie {
+	if source == nil {
+		return nil
+	}
+	clone := make([]*http.Cookie, 0, len(source))
+	for _, cookie := range source {
+		if cookie == nil {
+			clone = append(clone, nil)
+			continue
+		}
+		copied := *cookie
+		copied.Unparsed = cloneSlice(cookie.Unparsed)
+		clone = append(clone, &copied)
+	}
+	return clone
+}
+
+func mergeStringMaps(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(map[string]string, len(override))
+	}
+	for key, value := range override {
+		base[key] = value
+	}
+	return base
+}
+
+func mergeValues(base, override url.Values) url.Values {
+	if len(override) == 0 {
+		return base
+	}
+	if base == nil {
+		base = make(url.Values, len(override))
+	}
+	for key, values := range override {
+		base[key] = values
+	}
+	return base
+}
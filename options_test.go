@@ -0,0 +1,90 @@
+package request_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanCloneOptions(t *testing.T) {
+	serverURL, _ := url.Parse("https://example.com/v1/users?active=true")
+	options := &request.Options{
+		URL:          serverURL,
+		Headers:      map[string]string{"X-Tenant": "acme"},
+		Header:       http.Header{"X-Trace": {"1", "2"}},
+		Parameters:   map[string]string{"page": "1"},
+		QueryValues:  url.Values{"tag": {"a", "b"}},
+		Cookies:      []*http.Cookie{{Name: "session", Value: "1234"}},
+		ExpectStatus: []int{http.StatusOK},
+		APIKey:       &request.APIKey{Key: "secret"},
+		Timeout:      5 * time.Second,
+	}
+	clone := options.Clone()
+	require.NotNil(t, clone)
+	assert.Equal(t, options, clone)
+
+	clone.URL.RawQuery = "active=false"
+	clone.Headers["X-Tenant"] = "other"
+	clone.Header.Add("X-Trace", "3")
+	clone.Parameters["page"] = "2"
+	clone.QueryValues["tag"][0] = "c"
+	clone.Cookies[0].Value = "5678"
+	clone.ExpectStatus[0] = http.StatusCreated
+	clone.APIKey.Key = "other"
+
+	assert.Equal(t, "active=true", options.URL.RawQuery)
+	assert.Equal(t, "acme", options.Headers["X-Tenant"])
+	assert.Equal(t, []string{"1", "2"}, options.Header["X-Trace"])
+	assert.Equal(t, "1", options.Parameters["page"])
+	assert.Equal(t, []string{"a", "b"}, options.QueryValues["tag"])
+	assert.Equal(t, "1234", options.Cookies[0].Value)
+	assert.Equal(t, []int{http.StatusOK}, options.ExpectStatus)
+	assert.Equal(t, "secret", options.APIKey.Key)
+
+	var nilOptions *request.Options
+	assert.Nil(t, nilOptions.Clone())
+}
+
+func TestCanMergeOptions(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/v1")
+	base := &request.Options{
+		BaseURL:    baseURL,
+		Headers:    map[string]string{"X-Tenant": "acme", "X-Version": "1"},
+		Parameters: map[string]string{"page": "1"},
+		Cookies:    []*http.Cookie{{Name: "session", Value: "1234"}, {Name: "lang", Value: "en"}},
+		Timeout:    5 * time.Second,
+		Attempts:   3,
+	}
+	override := &request.Options{
+		Path:     "/users",
+		Method:   http.MethodPost,
+		Headers:  map[string]string{"X-Version": "2"},
+		Cookies:  []*http.Cookie{{Name: "lang", Value: "fr"}},
+		Attempts: 1,
+	}
+	merged := request.MergeOptions(base, override)
+	require.NotNil(t, merged)
+	assert.Equal(t, "https://example.com/v1", merged.BaseURL.String())
+	assert.Equal(t, "/users", merged.Path)
+	assert.Equal(t, http.MethodPost, merged.Method)
+	assert.Equal(t, 5*time.Second, merged.Timeout)
+	assert.Equal(t, uint(1), merged.Attempts)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme", "X-Version": "2"}, merged.Headers)
+	assert.Equal(t, map[string]string{"page": "1"}, merged.Parameters)
+	require.Len(t, merged.Cookies, 2)
+	assert.Equal(t, "1234", merged.Cookies[0].Value)
+	assert.Equal(t, "fr", merged.Cookies[1].Value)
+
+	assert.Equal(t, "1", base.Headers["X-Version"], "base should not be modified")
+	assert.Equal(t, "en", base.Cookies[1].Value, "base should not be modified")
+	assert.Empty(t, base.Path, "base should not be modified")
+
+	assert.Equal(t, base, request.MergeOptions(base, nil))
+	assert.Equal(t, override, request.MergeOptions(nil, override))
+}
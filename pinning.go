@@ -0,0 +1,44 @@
+package request
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+
+	"github.com/gildas/go-core"
+)
+
+// CertificatePin computes the pin of a certificate: the base64 encoded SHA-256 hash of its Subject Public Key Info
+//
+// This is the format expected by Options.PinnedCertificates (same as HPKP's pin-sha256).
+func CertificatePin(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// verifyPinnedCertificates creates a tls.Config.VerifyConnection func that accepts a connection if one of its certificates is pinned
+//
+// Only the certificates of the verified chains are checked, the other certificates sent by the server prove nothing
+// as anybody can append a public certificate to their chain.
+// Without verified chains (e.g.: with InsecureSkipVerify), only the leaf certificate is checked.
+//
+// VerifyConnection is also called on resumed sessions, unlike VerifyPeerCertificate.
+func verifyPinnedCertificates(host string, pins []string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.VerifiedChains) == 0 {
+			if len(state.PeerCertificates) > 0 && core.Contains(pins, CertificatePin(state.PeerCertificates[0])) {
+				return nil
+			}
+			return CertificatePinningFailed.With(host)
+		}
+		for _, chain := range state.VerifiedChains {
+			for _, certificate := range chain {
+				if core.Contains(pins, CertificatePin(certificate)) {
+					return nil
+				}
+			}
+		}
+		return CertificatePinningFailed.With(host)
+	}
+}
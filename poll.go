@@ -0,0 +1,107 @@
+package request
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// Poll sends the request again and again until the operation it checks is done
+//
+// This is meant for asynchronous job APIs: when a response is 202 Accepted with
+// an Operation-Location, Azure-AsyncOperation, or Location header, the next requests
+// are GET requests to that status resource (relative URLs are resolved against the request URL).
+//
+// The Content of the other responses is given to until, which tells if the operation is done.
+// Poll returns that Content when until returns true, or the error until returns.
+//
+// Between 2 requests, Poll waits for the Retry-After of the response if any, otherwise for the interval.
+// If maxWait is not 0 and the operation is not done after maxWait, Poll returns a PollTimeout error.
+//
+//	content, err := request.Poll(&request.Options{
+//	    Method:  http.MethodPost,
+//	    URL:     exportURL,
+//	    Payload: exportJob,
+//	}, func(content *request.Content) (bool, error) {
+//	    status := JobStatus{}
+//	    if err := content.UnmarshalContentJSON(&status); err != nil {
+//	        return false, err
+//	    }
+//	    return status.State == "completed", nil
+//	}, 2*time.Second, 5*time.Minute)
+func Poll(options *Options, until func(content *Content) (done bool, err error), interval, maxWait time.Duration) (*Content, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	if until == nil {
+		return nil, errors.ArgumentMissing.With("until")
+	}
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx := parent
+	if maxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, maxWait)
+		defer cancel()
+	}
+	pollOptions := options.Clone()
+	pollOptions.Context = ctx
+	pollOptions.FollowAsyncLocation = false
+
+	timedOut := func(content *Content, err error) error {
+		if maxWait > 0 && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			target := pollOptions.URL
+			if content != nil && content.URL != nil {
+				target = content.URL
+			}
+			return errors.WrapErrors(PollTimeout.With(describeURL(target), maxWait), err)
+		}
+		return err
+	}
+
+	for {
+		content, err := Send(pollOptions, nil)
+		if err != nil {
+			return content, timedOut(content, err)
+		}
+		following := false
+		if content.StatusCode == http.StatusAccepted {
+			if location := asyncLocation(content.Headers, content.URL); location != nil && (content.URL == nil || location.String() != content.URL.String()) {
+				if options.Logger != nil {
+					options.Logger.Debugf("Polling the status of the operation at %s", location)
+				}
+				pollOptions = asyncStatusOptions(pollOptions, location)
+				following = true
+			}
+		}
+		if !following {
+			done, err := until(content)
+			if err != nil {
+				return content, err
+			}
+			if done {
+				return content, nil
+			}
+		}
+		delay, found := parseRetryAfter(content.Headers)
+		if !found {
+			delay = interval
+		}
+		if !waitPollInterval(ctx, delay, 0) {
+			return content, timedOut(content, ctx.Err())
+		}
+	}
+}
+
+// describeURL gets the URL to show in errors, or "request" if it is not known
+func describeURL(target *url.URL) string {
+	if target == nil {
+		return "request"
+	}
+	return target.String()
+}
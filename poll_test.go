@@ -0,0 +1,133 @@
+package request_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-errors"
+
+	"github.com/gildas/go-request"
+)
+
+type pollJobStatus struct {
+	State string `json:"state"`
+}
+
+func CreatePollTestServer(suite *RequestSuite, checks *atomic.Int32, doneAfter int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jobs":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Location", "/jobs/1234/status")
+			w.WriteHeader(http.StatusAccepted)
+		case "/jobs/1234/status":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			status := pollJobStatus{State: "running"}
+			if checks.Add(1) >= doneAfter {
+				status.State = "completed"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(status)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func jobCompleted(content *request.Content) (bool, error) {
+	status := pollJobStatus{}
+	if err := content.UnmarshalContentJSON(&status); err != nil {
+		return false, err
+	}
+	return status.State == "completed", nil
+}
+
+func (suite *RequestSuite) TestCanPollAsyncJob() {
+	checks := atomic.Int32{}
+	server := CreatePollTestServer(suite, &checks, 3)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	content, err := request.Poll(&request.Options{
+		Method:  http.MethodPost,
+		URL:     serverURL.JoinPath("jobs"),
+		Payload: struct{ Report string }{Report: "monthly"},
+		Logger:  suite.Logger,
+	}, jobCompleted, 10*time.Millisecond, 5*time.Second)
+	suite.Require().NoError(err, "Failed to poll the job")
+	suite.Require().NotNil(content, "Content should not be nil")
+	suite.Assert().Equal(http.StatusOK, content.StatusCode)
+	suite.Assert().Equal("/jobs/1234/status", content.URL.Path)
+	suite.Assert().JSONEq(`{"state": "completed"}`, string(content.Data))
+	suite.Assert().Equal(int32(3), checks.Load())
+}
+
+func (suite *RequestSuite) TestShouldFailPollingWhenMaxWaitIsExceeded() {
+	checks := atomic.Int32{}
+	server := CreatePollTestServer(suite, &checks, 1000)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	start := time.Now()
+	_, err := request.Poll(&request.Options{
+		Method:  http.MethodPost,
+		URL:     serverURL.JoinPath("jobs"),
+		Payload: struct{ Report string }{Report: "monthly"},
+		Logger:  suite.Logger,
+	}, jobCompleted, 20*time.Millisecond, 200*time.Millisecond)
+	suite.Require().Error(err, "Polling should have timed out")
+	suite.Assert().ErrorIs(err, request.PollTimeout)
+	suite.Assert().Less(time.Since(start), 2*time.Second)
+	suite.Assert().Greater(checks.Load(), int32(1))
+}
+
+func (suite *RequestSuite) TestShouldStopPollingWhenUntilFails() {
+	checks := atomic.Int32{}
+	server := CreatePollTestServer(suite, &checks, 1000)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	_, err := request.Poll(&request.Options{
+		URL:    serverURL.JoinPath("jobs", "1234", "status"),
+		Logger: suite.Logger,
+	}, func(content *request.Content) (bool, error) {
+		return false, errors.Unsupported.With("state", "running")
+	}, 10*time.Millisecond, 0)
+	suite.Require().Error(err, "Polling should have failed")
+	suite.Assert().ErrorIs(err, errors.Unsupported)
+	suite.Assert().Equal(int32(1), checks.Load())
+}
+
+func (suite *RequestSuite) TestShouldStopPollingWhenContextIsCanceled() {
+	checks := atomic.Int32{}
+	server := CreatePollTestServer(suite, &checks, 1000)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := request.Poll(&request.Options{
+		Context: ctx,
+		URL:     serverURL.JoinPath("jobs", "1234", "status"),
+		Logger:  suite.Logger,
+	}, jobCompleted, 20*time.Millisecond, time.Minute)
+	suite.Require().Error(err, "Polling should have stopped")
+	suite.Assert().NotErrorIs(err, request.PollTimeout, "The context expired before maxWait")
+}
+
+func (suite *RequestSuite) TestShouldFailPollingWithoutUntil() {
+	_, err := request.Poll(&request.Options{}, nil, time.Second, 0)
+	suite.Require().Error(err)
+	suite.Assert().ErrorIs(err, errors.ArgumentMissing)
+}
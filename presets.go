@@ -0,0 +1,76 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APNsProductionURL is the base URL of the Apple Push Notification service
+var APNsProductionURL, _ = url.Parse("https://api.push.apple.com")
+
+// APNsSandboxURL is the base URL of the Apple Push Notification service development environment
+var APNsSandboxURL, _ = url.Parse("https://api.sandbox.push.apple.com")
+
+// FCMURL is the base URL of the Firebase Cloud Messaging service
+var FCMURL, _ = url.Parse("https://fcm.googleapis.com")
+
+// apnsTransport is shared by the Options created by APNsOptions, so their connections are reused
+var apnsTransport = http2Transport()
+
+// fcmTransport is shared by the Options created by FCMOptions, so their connections are reused
+var fcmTransport = http2Transport()
+
+// APNsOptions creates the Options to send a notification to a device via the Apple Push Notification service
+//
+// token is the provider authentication token (a JWT signed with the key from the Apple Developer account).
+// topic is the bundle ID of the app. The caller still needs to provide the Payload.
+//
+// The apns-push-type header is "alert", change it in the Headers of the Options for other notifications (e.g.: "background").
+//
+// APNs requires HTTP/2, and its retryable status codes are 429, 500, and 503.
+func APNsOptions(deviceToken, topic, token string, sandbox bool) *Options {
+	baseURL := APNsProductionURL
+	if sandbox {
+		baseURL = APNsSandboxURL
+	}
+	return &Options{
+		Method:               http.MethodPost,
+		URL:                  baseURL.JoinPath("3", "device", deviceToken),
+		PayloadType:          "application/json",
+		Authorization:        BearerAuthorization(token),
+		Headers:              map[string]string{"apns-topic": topic, "apns-push-type": "alert"},
+		Transport:            apnsTransport,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		Attempts:             DefaultAttempts,
+		InterAttemptDelay:    1 * time.Second,
+		Timeout:              10 * time.Second,
+	}
+}
+
+// FCMOptions creates the Options to send a message via the Firebase Cloud Messaging HTTP v1 API
+//
+// token is an OAuth2 access token with the firebase.messaging scope. The caller still needs to provide the Payload.
+//
+// FCM asks clients to honor the Retry-After header on 429 and 503.
+func FCMOptions(projectID, token string) *Options {
+	return &Options{
+		Method:                    http.MethodPost,
+		URL:                       FCMURL.JoinPath("v1", "projects", projectID, "messages:send"),
+		PayloadType:               "application/json",
+		Authorization:             BearerAuthorization(token),
+		Transport:                 fcmTransport,
+		RetryableStatusCodes:      []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		Attempts:                  DefaultAttempts,
+		InterAttemptDelay:         1 * time.Second,
+		InterAttemptUseRetryAfter: true,
+		Timeout:                   10 * time.Second,
+	}
+}
+
+// http2Transport creates a Transport that attempts HTTP/2
+func http2Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
@@ -0,0 +1,48 @@
+package request_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanCreateAPNsOptions(t *testing.T) {
+	options := request.APNsOptions("1234abcd", "com.acme.app", "mytoken", false)
+	require.NotNil(t, options)
+	assert.Equal(t, http.MethodPost, options.Method)
+	assert.Equal(t, "https://api.push.apple.com/3/device/1234abcd", options.URL.String())
+	assert.Equal(t, "Bearer mytoken", options.Authorization)
+	assert.Equal(t, "com.acme.app", options.Headers["apns-topic"])
+	require.NotNil(t, options.Transport)
+	assert.True(t, options.Transport.ForceAttemptHTTP2)
+	assert.Contains(t, options.RetryableStatusCodes, http.StatusTooManyRequests)
+}
+
+func TestCanCreateAPNsSandboxOptions(t *testing.T) {
+	options := request.APNsOptions("1234abcd", "com.acme.app", "mytoken", true)
+	require.NotNil(t, options)
+	assert.Equal(t, "https://api.sandbox.push.apple.com/3/device/1234abcd", options.URL.String())
+}
+
+func TestCanCreateFCMOptions(t *testing.T) {
+	options := request.FCMOptions("my-project", "mytoken")
+	require.NotNil(t, options)
+	assert.Equal(t, http.MethodPost, options.Method)
+	assert.Equal(t, "https://fcm.googleapis.com/v1/projects/my-project/messages:send", options.URL.String())
+	assert.Equal(t, "Bearer mytoken", options.Authorization)
+	assert.True(t, options.InterAttemptUseRetryAfter)
+	require.NotNil(t, options.Transport)
+	assert.True(t, options.Transport.ForceAttemptHTTP2)
+}
+
+func TestShouldShareTransportOfPresets(t *testing.T) {
+	apns := request.APNsOptions("1234abcd", "com.acme.app", "mytoken", false)
+	assert.Same(t, apns.Transport, request.APNsOptions("5678efgh", "com.acme.app", "mytoken", true).Transport, "APNs Options should share their Transport")
+	fcm := request.FCMOptions("my-project", "mytoken")
+	assert.Same(t, fcm.Transport, request.FCMOptions("my-project", "mytoken").Transport, "FCM Options should share their Transport")
+	assert.Equal(t, "alert", apns.Headers["apns-push-type"])
+}
@@ -0,0 +1,122 @@
+package request
+
+import (
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// ProblemDetails describes an error returned as application/problem+json (RFC 7807)
+//
+// Send returns it as the error when the response is a problem, it wraps the error of the HTTP status:
+//
+//	var problem *request.ProblemDetails
+//	if errors.As(err, &problem) {
+//	    log.Errorf("%s: %s", problem.Title, problem.Detail)
+//	}
+type ProblemDetails struct {
+	Type       string                 `json:"type,omitempty"`
+	Title      string                 `json:"title,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"` // the other members of the problem
+	Cause      error                  `json:"-"` // the error of the HTTP status
+}
+
+// ProblemDetailsMediaType is the Content Type of ProblemDetails
+const ProblemDetailsMediaType = "application/problem+json"
+
+// isProblemDetails tells if the content type is application/problem+json
+func isProblemDetails(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && strings.EqualFold(mediaType, ProblemDetailsMediaType)
+}
+
+// problemDetailsFromContent decodes the ProblemDetails of a Content
+func problemDetailsFromContent(content *Content, cause error) (*ProblemDetails, error) {
+	problem := ProblemDetails{}
+	if err := content.UnmarshalContentJSON(&problem); err != nil {
+		return nil, err
+	}
+	if problem.Status == 0 {
+		problem.Status = content.StatusCode
+	}
+	problem.Cause = cause
+	return &problem, nil
+}
+
+// Error returns the string version of this error
+//
+// implements error
+func (problem ProblemDetails) Error() string {
+	sb := strings.Builder{}
+	if len(problem.Title) > 0 {
+		sb.WriteString(problem.Title)
+	} else if problem.Cause != nil {
+		sb.WriteString(problem.Cause.Error())
+	} else {
+		sb.WriteString("Problem")
+	}
+	if problem.Status > 0 {
+		sb.WriteString(" (")
+		sb.WriteString(strconv.Itoa(problem.Status))
+		sb.WriteString(")")
+	}
+	if len(problem.Detail) > 0 {
+		sb.WriteString(": ")
+		sb.WriteString(problem.Detail)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error of the HTTP status
+func (problem ProblemDetails) Unwrap() error {
+	return problem.Cause
+}
+
+// MarshalJSON marshals the ProblemDetails into JSON
+//
+// implements json.Marshaler
+func (problem ProblemDetails) MarshalJSON() ([]byte, error) {
+	type surrogate ProblemDetails
+	members := map[string]interface{}{}
+	for key, value := range problem.Extensions {
+		members[key] = value
+	}
+	payload, err := json.Marshal(surrogate(problem))
+	if err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	if err = json.Unmarshal(payload, &members); err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	data, err := json.Marshal(members)
+	return data, errors.JSONMarshalError.Wrap(err)
+}
+
+// UnmarshalJSON unmarshals the ProblemDetails from JSON
+//
+// implements json.Unmarshaler
+func (problem *ProblemDetails) UnmarshalJSON(payload []byte) error {
+	type surrogate ProblemDetails
+	var inner surrogate
+	if err := json.Unmarshal(payload, &inner); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(payload, &members); err != nil {
+		return errors.JSONUnmarshalError.WrapIfNotMe(err)
+	}
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+	*problem = ProblemDetails(inner)
+	if len(members) > 0 {
+		problem.Extensions = members
+	}
+	return nil
+}
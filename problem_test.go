@@ -0,0 +1,23 @@
+package request_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanMarshalProblemDetails(t *testing.T) {
+	expected := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"balance":30}`
+	problem := request.ProblemDetails{}
+	require.NoError(t, json.Unmarshal([]byte(expected), &problem))
+	assert.Equal(t, 403, problem.Status)
+	assert.Equal(t, map[string]interface{}{"balance": float64(30)}, problem.Extensions)
+
+	payload, err := json.Marshal(problem)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(payload))
+}
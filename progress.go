@@ -1,6 +1,10 @@
 package request
 
-import "io"
+import (
+	"io"
+	"sync"
+	"time"
+)
 
 // ProgressBarMaxSetter is an interface that allows setting the maximum value of a progress bar
 type ProgressBarMaxSetter interface {
@@ -24,3 +28,100 @@ func (reader *progressReader) Read(p []byte) (n int, err error) {
 	_, _ = reader.Progress.Write(p[:n])
 	return
 }
+
+// DefaultProgressInterval defines how often the ProgressFunc is called by default
+const DefaultProgressInterval = 1 * time.Second
+
+// ProgressETA estimates the remaining time of a transfer from the values given to a ProgressFunc
+//
+// returns 0 if the total or the rate is unknown
+func ProgressETA(transferred, total int64, rate float64) time.Duration {
+	if total <= 0 || rate <= 0 || transferred >= total {
+		return 0
+	}
+	return time.Duration(float64(total-transferred) / rate * float64(time.Second))
+}
+
+// progressReporter calls a ProgressFunc at most once per interval with the transferred bytes and the rate
+//
+// It forwards the writes, the maximum, and the close to the next ProgressWriter, if any.
+type progressReporter struct {
+	Next        io.Writer
+	Func        func(transferred, total int64, rate float64)
+	Interval    time.Duration
+	transferred int64
+	total       int64
+	start       time.Time
+	lastReport  time.Time
+	lock        sync.Mutex
+}
+
+func newProgressReporter(next io.Writer, progressFunc func(transferred, total int64, rate float64), interval time.Duration) *progressReporter {
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	return &progressReporter{Next: next, Func: progressFunc, Interval: interval}
+}
+
+func (reporter *progressReporter) Write(data []byte) (int, error) {
+	reporter.lock.Lock()
+	now := time.Now()
+	if reporter.start.IsZero() {
+		reporter.start = now
+		reporter.lastReport = now
+	}
+	reporter.transferred += int64(len(data))
+	if now.Sub(reporter.lastReport) >= reporter.Interval {
+		reporter.lastReport = now
+		reporter.report(now)
+	}
+	reporter.lock.Unlock()
+
+	if reporter.Next != nil {
+		return reporter.Next.Write(data)
+	}
+	return len(data), nil
+}
+
+// setTotal sets the total without forwarding it to the next ProgressWriter
+func (reporter *progressReporter) setTotal(total int64) {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+	reporter.total = total
+}
+
+// SetMax64 sets the total of the transfer
+//
+// implements ProgressBarMaxSetter
+func (reporter *progressReporter) SetMax64(total int64) {
+	reporter.setTotal(total)
+	if maxSetter, ok := reporter.Next.(ProgressBarMaxSetter); ok {
+		maxSetter.SetMax64(total)
+	} else if maxChanger, ok := reporter.Next.(ProgressBarMaxChanger); ok {
+		maxChanger.ChangeMax64(total)
+	}
+}
+
+// Close reports the final progress
+//
+// implements io.Closer
+func (reporter *progressReporter) Close() error {
+	reporter.lock.Lock()
+	if !reporter.start.IsZero() {
+		reporter.report(time.Now())
+	}
+	reporter.lock.Unlock()
+	if closer, ok := reporter.Next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// report calls the ProgressFunc, the lock must be held
+func (reporter *progressReporter) report(now time.Time) {
+	rate := 0.0
+	if elapsed := now.Sub(reporter.start).Seconds(); elapsed > 0 {
+		rate = float64(reporter.transferred) / elapsed
+	}
+	reporter.Func(reporter.transferred, reporter.total, rate)
+}
@@ -0,0 +1,17 @@
+package request_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanComputeProgressETA(t *testing.T) {
+	assert.Equal(t, 2*time.Second, request.ProgressETA(600, 1000, 200))
+	assert.Equal(t, time.Duration(0), request.ProgressETA(1000, 1000, 200), "A complete transfer has no ETA")
+	assert.Equal(t, time.Duration(0), request.ProgressETA(600, 0, 200), "A transfer without total has no ETA")
+	assert.Equal(t, time.Duration(0), request.ProgressETA(600, 1000, 0), "A transfer without rate has no ETA")
+}
@@ -0,0 +1,225 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/google/uuid"
+)
+
+// QueuedRequest is a request persisted in a Queue
+//
+// Its Authorization is persisted as well, the Queue's directory should be protected accordingly.
+type QueuedRequest struct {
+	ID            string            `json:"id"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Header        http.Header       `json:"header,omitempty"`
+	Authorization string            `json:"authorization,omitempty"`
+	Accept        string            `json:"accept,omitempty"`
+	Content       Content           `json:"content"` // URL, Cookies, Type, and Data of the request
+	EnqueuedAt    time.Time         `json:"enqueuedAt"`
+}
+
+// Queue is a durable outbox of requests, persisted in a directory
+//
+// Requests are enqueued while offline and sent, in order, when the Queue is drained.
+// A request that fails because the server cannot be reached stays in the Queue and stops the draining.
+// A request that fails for any other reason (e.g.: 400, 404) is moved to the "failed" subdirectory.
+type Queue struct {
+	Path     string  // directory where the requests are persisted
+	Defaults Options // options used to send the requests (e.g.: Logger, Transport, Attempts, Timeout)
+	lock     sync.Mutex
+}
+
+// NewQueue instantiates a new Queue that persists its requests in the given directory
+//
+// The directory is created if needed.
+func NewQueue(path string, defaults *Options) (*Queue, error) {
+	if len(path) == 0 {
+		return nil, errors.ArgumentMissing.With("path")
+	}
+	if err := os.MkdirAll(filepath.Join(path, "failed"), 0700); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	queue := &Queue{Path: path}
+	if defaults != nil {
+		queue.Defaults = *defaults
+	}
+	return queue, nil
+}
+
+// Enqueue persists the request described by the options
+//
+// The payload is encoded now, so it can be any payload Send accepts. Results cannot be requested.
+//
+// returns the ID of the QueuedRequest, which is also sent as the RequestID of the request (in the X-Request-Id header by default)
+func (queue *Queue) Enqueue(options *Options) (string, error) {
+	if options == nil {
+		return "", errors.ArgumentMissing.With("options")
+	}
+	normalized := options.Clone()
+	if err := normalizeOptions(normalized, nil); err != nil {
+		return "", err
+	}
+	content, err := buildRequestContent(normalized.Logger, normalized)
+	if err != nil {
+		return "", err
+	}
+	queued := QueuedRequest{
+		ID:            normalized.RequestID,
+		Method:        normalized.Method,
+		Headers:       normalized.Headers,
+		Header:        normalized.Header,
+		Authorization: normalized.Authorization,
+		Accept:        normalized.Accept,
+		Content:       *content,
+		EnqueuedAt:    time.Now().UTC(),
+	}
+	queued.Content.URL = normalized.URL
+	queued.Content.Cookies = normalized.Cookies
+
+	payload, err := json.Marshal(queued)
+	if err != nil {
+		return "", errors.JSONMarshalError.Wrap(err)
+	}
+
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	// The file names sort in the order the requests were enqueued
+	filename := filepath.Join(queue.Path, fmt.Sprintf("%020d-%s.json", queued.EnqueuedAt.UnixNano(), uuid.NewString()))
+	if err = os.WriteFile(filename+".tmp", payload, 0600); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err = os.Rename(filename+".tmp", filename); err != nil {
+		return "", errors.WithStack(err)
+	}
+	normalized.Logger.Child("queue", "enqueue", "reqid", queued.ID).Debugf("Enqueued %s %s in %s", queued.Method, queued.Content.URL, filename)
+	return queued.ID, nil
+}
+
+// Len gets the number of requests waiting in the Queue
+func (queue *Queue) Len() (int, error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	filenames, err := queue.filenames()
+	return len(filenames), err
+}
+
+// Drain sends the requests of the Queue in the order they were enqueued
+//
+// Drain stops at the first request that fails because the server cannot be reached, that request stays in the Queue.
+//
+// returns the number of requests that were sent successfully
+func (queue *Queue) Drain() (sent int, err error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	filenames, err := queue.filenames()
+	if err != nil {
+		return 0, err
+	}
+	for _, filename := range filenames {
+		var queued QueuedRequest
+
+		payload, err := os.ReadFile(filename)
+		if err != nil {
+			return sent, errors.WithStack(err)
+		}
+		if err = json.Unmarshal(payload, &queued); err != nil {
+			queue.logger().Errorf("Invalid queued request in %s, moving it to the failed requests", filename, err)
+			_ = os.Rename(filename, queue.failedFilename(filename))
+			continue
+		}
+		options := queue.Defaults
+		options.Method = queued.Method
+		options.URL = queued.Content.URL
+		options.BaseURL = nil
+		options.Headers = queued.Headers
+		options.Header = queued.Header
+		options.Authorization = queued.Authorization
+		options.Accept = queued.Accept
+		options.Cookies = queued.Content.Cookies
+		options.RequestID = queued.ID
+		options.Payload = nil
+		if len(queued.Content.Data) > 0 {
+			content := queued.Content
+			content.URL = nil
+			content.Cookies = nil
+			options.Payload = content
+		}
+		if _, err = Send(&options, nil); err != nil {
+			if isEndpointFailure(err) {
+				return sent, err
+			}
+			queue.logger().Errorf("Failed to send queued request %s (%s %s), moving it to the failed requests", queued.ID, queued.Method, queued.Content.URL, err)
+			if err = os.Rename(filename, queue.failedFilename(filename)); err != nil {
+				return sent, errors.WithStack(err)
+			}
+			continue
+		}
+		if err = os.Remove(filename); err != nil {
+			return sent, errors.WithStack(err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Run drains the Queue at the given interval until the context is done
+func (queue *Queue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := queue.Drain(); err != nil {
+			queue.logger().Debugf("Queue not drained (sent: %d): %s", sent, err)
+		} else if sent > 0 {
+			queue.logger().Infof("Queue drained (sent: %d)", sent)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// filenames gets the files of the queued requests, in the order they were enqueued
+func (queue *Queue) filenames() ([]string, error) {
+	entries, err := os.ReadDir(queue.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	filenames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			filenames = append(filenames, filepath.Join(queue.Path, entry.Name()))
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+func (queue *Queue) failedFilename(filename string) string {
+	return filepath.Join(queue.Path, "failed", filepath.Base(filename))
+}
+
+func (queue *Queue) logger() *logger.Logger {
+	if queue.Defaults.Logger != nil {
+		return queue.Defaults.Logger.Child("queue", "drain")
+	}
+	return logger.Create("request").Child("queue", "drain")
+}
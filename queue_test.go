@@ -0,0 +1,102 @@
+package request_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanDrainQueue() {
+	queue, err := request.NewQueue(suite.T().TempDir(), &request.Options{Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to create the queue")
+	serverURL, _ := url.Parse(suite.Server.URL)
+
+	_, err = queue.Enqueue(&request.Options{
+		Method:  http.MethodPost,
+		URL:     serverURL.JoinPath("items"),
+		Payload: []stuff{{"1234"}, {"5678"}},
+		Logger:  suite.Logger,
+	})
+	suite.Require().NoError(err, "Failed to enqueue the request")
+	id, err := queue.Enqueue(&request.Options{
+		URL:       serverURL,
+		RequestID: "queued-request",
+		Logger:    suite.Logger,
+	})
+	suite.Require().NoError(err, "Failed to enqueue the request")
+	suite.Assert().Equal("queued-request", id)
+	count, err := queue.Len()
+	suite.Require().NoError(err, "Failed to count the queued requests")
+	suite.Assert().Equal(2, count)
+
+	sent, err := queue.Drain()
+	suite.Require().NoError(err, "Failed to drain the queue")
+	suite.Assert().Equal(2, sent)
+	count, err = queue.Len()
+	suite.Require().NoError(err, "Failed to count the queued requests")
+	suite.Assert().Equal(0, count)
+}
+
+func (suite *RequestSuite) TestShouldKeepQueuedRequestsWhenOffline() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err, "Failed starting the listener")
+	deadURL, _ := url.Parse("http://" + listener.Addr().String())
+	listener.Close() // that will cause the ECONNREFUSED
+
+	queue, err := request.NewQueue(suite.T().TempDir(), &request.Options{Attempts: 1, Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to create the queue")
+	_, err = queue.Enqueue(&request.Options{URL: deadURL, Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to enqueue the request")
+
+	sent, err := queue.Drain()
+	suite.Require().Error(err, "Should have failed draining the queue")
+	suite.Assert().Equal(0, sent)
+	count, err := queue.Len()
+	suite.Require().NoError(err, "Failed to count the queued requests")
+	suite.Assert().Equal(1, count, "The request should still be queued")
+}
+
+func (suite *RequestSuite) TestShouldMoveFailedQueuedRequests() {
+	path := suite.T().TempDir()
+	queue, err := request.NewQueue(path, &request.Options{Attempts: 1, Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to create the queue")
+	serverURL, _ := url.Parse(suite.Server.URL)
+	_, err = queue.Enqueue(&request.Options{
+		Method:  http.MethodPost,
+		URL:     serverURL.JoinPath("items"),
+		Payload: []stuff{}, // the server does not accept empty items
+		Logger:  suite.Logger,
+	})
+	suite.Require().NoError(err, "Failed to enqueue the request")
+
+	sent, err := queue.Drain()
+	suite.Require().NoError(err, "Failed to drain the queue")
+	suite.Assert().Equal(0, sent)
+	count, err := queue.Len()
+	suite.Require().NoError(err, "Failed to count the queued requests")
+	suite.Assert().Equal(0, count)
+	failed, err := os.ReadDir(filepath.Join(path, "failed"))
+	suite.Require().NoError(err, "Failed to read the failed requests")
+	suite.Assert().Len(failed, 1, "The request should have been moved to the failed requests")
+}
+
+func (suite *RequestSuite) TestCanRunQueue() {
+	queue, err := request.NewQueue(suite.T().TempDir(), &request.Options{Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to create the queue")
+	serverURL, _ := url.Parse(suite.Server.URL)
+	_, err = queue.Enqueue(&request.Options{URL: serverURL, Logger: suite.Logger})
+	suite.Require().NoError(err, "Failed to enqueue the request")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	queue.Run(ctx, 50*time.Millisecond)
+	count, err := queue.Len()
+	suite.Require().NoError(err, "Failed to count the queued requests")
+	suite.Assert().Equal(0, count)
+}
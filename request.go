@@ -1,23 +1,23 @@
 package request
 
 import (
-	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"mime"
-	"mime/multipart"
 	"net"
 	"net/http"
-	"net/textproto"
+	"net/http/httptrace"
 	"net/url"
+	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/gildas/go-core"
@@ -31,30 +31,97 @@ type Options struct {
 	Context                     context.Context
 	Method                      string
 	URL                         *url.URL
+	URLString                   string        // if URL is not provided, it is parsed from this string (e.g.: https://api.acme.com/v2/users)
+	BaseURL                     *url.URL      // if URL is not provided, it is computed from BaseURL and Path
+	Path                        string        // path (and query) relative to BaseURL (e.g.: /v2/users?active=true)
+	AllowedSchemes              []string      // lowercase URL schemes accepted besides http and https (e.g.: when the Transport handles other schemes)
+	LoadBalancer                *LoadBalancer // if URL is not provided, BaseURL is chosen by this LoadBalancer
+	SingleFlight                *SingleFlight // if not nil, concurrent identical GET requests share one call
+	Scheduler                   *Scheduler    // if not nil, the request waits for a slot of this Scheduler before being sent
+	Priority                    int           // priority of the request in the Scheduler, higher first, by default: 0
+	SRV                         string        // if URL is not provided, the host:port of BaseURL is resolved from this DNS SRV record (e.g.: _api._tcp.example.com), and re-resolved on connection failures
+	SRVResolver                 SRVResolver   // resolves the SRV record, by default: net.DefaultResolver
 	Proxy                       *url.URL
 	Headers                     map[string]string
+	Header                      http.Header       // headers sent as they are, with their multiple values and casing, they take precedence over Headers
+	Trailer                     http.Header       // trailers sent after the request body, which is then chunked, their values can be set by TrailerFunc
+	TrailerFunc                 func(http.Header) // if not nil, it is called with the Trailer once the request body is sent to set the trailer values (e.g.: a checksum)
+	PreserveHeaderCase          bool              // if true, the keys of Headers are sent with their casing instead of being canonicalized (e.g.: for servers that require "SOAPAction")
 	Cookies                     []*http.Cookie
 	Parameters                  map[string]string
+	PathParameters              map[string]string // values of the RFC 6570 expressions of the URL's path (e.g.: /users/{id}/orders/{order})
+	QueryValues                 url.Values        // merged with the URL's query and the Parameters, allows repeated keys. See EncodeQuery to build them from a struct
 	Accept                      string
-	PayloadType                 string      // if not provided, it is computed. See https://gihub.com/gildas/go-request#payload
-	Payload                     interface{} // See https://gihub.com/gildas/go-request#payload
-	AttachmentType              string      // MIME type of the attachment
-	Attachment                  io.Reader   // binary data that should be attached to the paylod (e.g.: multipart forms)
+	PayloadType                 string                        // if not provided, it is computed. See https://gihub.com/gildas/go-request#payload
+	Payload                     interface{}                   // See https://gihub.com/gildas/go-request#payload
+	PayloadLength               int64                         // if not 0, an io.Reader Payload is streamed with this Content-Length (-1 if unknown) instead of being read in memory, the request is then sent only once unless GetPayload is given
+	GetPayload                  func() (io.ReadCloser, error) // if not nil, it gives a new body to stream for each attempt (like http.Request.GetBody), so payloads that cannot seek can be sent again
+	AttachmentType              string                        // MIME type of the attachment
+	Attachment                  io.Reader                     // binary data that should be attached to the paylod, in multipart forms the ">key" convention is deprecated, use Attachments
+	Attachments                 []Attachment                  // files sent in a multipart form with the fields of the Payload (nil, a map, or a struct)
 	Authorization               string
-	RequestID                   string
+	TokenProvider               TokenProvider                         // if not nil, it provides the Authorization before each attempt, superseding Authorization
+	APIKey                      *APIKey                               // if not nil, the API Key is placed in a header, the query, or a cookie. See APIKeyAuthorization
+	Signer                      Signer                                // if not nil, it signs the request before each attempt (e.g.: AWSSigV4Signer)
+	OnUnauthorized              func(context.Context) (string, error) // if not nil, it is called once on a 401 to get a new Authorization and the request is sent again with it, even if there is a TokenProvider
+	ExpectStatus                []int                                 // if not empty, the response status must be one of these. Expected 4xx/5xx statuses are not errors
+	AcceptableStatusCodes       []int                                 // 4xx/5xx statuses that are returned as a Content instead of an error (e.g.: 404 for "get-or-nil")
+	RequestID                   string                                // if empty, it is taken from the Context (see ContextWithRequestID and RequestIDContextKey), or a random UUID is generated
+	RequestIDHeader             string                                // the header that carries the RequestID, by default: X-Request-Id
+	RequestIDContextKey         interface{}                           // if not nil, the RequestID is taken from the value of this key in the Context (a string or a fmt.Stringer) instead of the one given by ContextWithRequestID
+	AttemptHeader               string                                // the header that carries the attempt number, starting at 1, by default: X-Attempt
+	DisableAttemptHeader        bool                                  // if true, the attempt number is not sent (e.g.: to third-party servers), it is still given to the Logger and OnAttempt
 	UserAgent                   string
 	Transport                   *http.Transport
-	ProgressWriter              io.Writer // if not nil, the progress of the request will be written to this writer
+	DisableCompression          bool             // if true, Accept-Encoding is not sent and compressed responses are not decompressed (e.g.: to archive responses verbatim)
+	KeepRawData                 bool             // if true, the compressed bytes of an uncompressed response are kept in Content.RawData
+	InsecureSkipVerify          bool             // if true, the server certificate is not verified (e.g.: self-signed certificates in staging)
+	TLSMinVersion               uint16           // minimum TLS version (e.g.: tls.VersionTLS12), by default: the Transport's
+	ServerName                  string           // server name used for SNI and certificate verification, by default: the URL's host
+	PinnedCertificates          []string         // base64 encoded SHA-256 hashes of the Subject Public Key Info of the accepted certificates
+	ProgressWriter              io.Writer        // if not nil, the progress of the request will be written to this writer
+	KeyTranslation              KeyTranslation   // how the keys of the JSON payload and results are translated from and to the Go field names, by default: NoKeyTranslation
+	ResultPath                  string           // if not empty, the results are unmarshaled from this dot-separated path of the response body (e.g.: data.items, or data.items.0)
+	TeeWriter                   io.Writer        // if not nil, the raw response body is also written to this writer while it is read (e.g.: to persist it while decoding the results)
+	VerifyChecksum              bool             // if true, the response body is verified against the Content-MD5, x-amz-checksum-*, Digest, or Content-Digest headers
+	PayloadEncryption           *Encryption      // if not nil, the payload is encrypted and its algorithm is sent in the X-Content-Encryption header
+	ResponseDecryption          *Encryption      // if not nil, the response body is decrypted with the algorithm of its X-Content-Encryption header, or this Algorithm if it is missing
+	ResponseVerifier            ResponseVerifier // if not nil, it verifies the raw body of successful responses before they are decrypted or decoded
+	ResponseEnvelope            ResponseEnvelope // if not nil, it finds the errors carried in the response body, even with a 2xx status (e.g.: JSONErrorEnvelope)
 	ProgressSetMaxFunc          func(int64)
-	RetryableStatusCodes        []int         // Status codes that should be retried, by default: 429, 502, 503, 504
-	Attempts                    uint          // number of attempts, by default: 5
-	InterAttemptDelay           time.Duration // how long to wait between 2 attempts during the first backoff interval, by default: 3s
-	InterAttemptBackoffInterval time.Duration // how often the inter attempt delay should be increased, by default: 5 minutes
-	InterAttemptUseRetryAfter   bool          // if true, the Retry-After header will be used to wait between 2 attempts, otherwise an exponential backoff will be used, by default: false
+	ProgressFunc                func(transferred, total int64, rate float64) // if not nil, it is called every ProgressInterval with the transferred bytes, the total (0 if unknown), and the rate in bytes/s, like ProgressWriter
+	ProgressInterval            time.Duration                                // how often ProgressFunc is called, by default: 1s
+	MaxBytesPerSecond           int64                                        // if not 0, the upload and the download are each limited to this many bytes per second
+	RetryableStatusCodes        []int                                        // Status codes that should be retried, by default: 429, 502, 503, 504
+	RetryableErrors             RetryableErrorClassifier                     // tells which errors should be retried, by default: DefaultRetryableErrorClassifier
+	RetryNonIdempotent          bool                                         // if true, POST and PATCH requests are retried on network errors even if they might have been delivered
+	Attempts                    uint                                         // number of attempts, by default: 5
+	InterAttemptDelay           time.Duration                                // how long to wait between 2 attempts during the first backoff interval, by default: 3s
+	InterAttemptBackoffInterval time.Duration                                // how often the inter attempt delay should be increased, by default: 5 minutes
+	InterAttemptUseRetryAfter   bool                                         // if true, the Retry-After header of any retryable response will be used to wait between 2 attempts, otherwise only 429 and 503 responses use it, by default: false
+	MaxRetryAfter               time.Duration                                // the maximum delay a Retry-After header can impose between 2 attempts, by default: 5 minutes
+	FollowAsyncLocation         bool                                         // if true, 201/202 responses with a Location or Operation-Location header are polled until the operation reaches a terminal status (see SendAsyncOperation)
+	PollInterval                time.Duration                                // how long to wait between 2 requests of SendLongPoll, or before the first status request of SendAsyncOperation
+	PollJitter                  time.Duration                                // if not 0, a random delay up to this is added to PollInterval
+	MaxPollInterval             time.Duration                                // the maximum delay between 2 status requests of SendAsyncOperation, by default: 1 minute
+	HedgeAfter                  time.Duration                                // if not 0, a second identical GET/HEAD/OPTIONS request is sent when the first one has not responded within this delay, the first response wins
 	Timeout                     time.Duration
-	RequestBodyLogSize          int // how many characters of the request body should be logged, if possible (<0 => nothing logged)
-	ResponseBodyLogSize         int // how many characters of the response body should be logged (<0 => nothing logged)
+	ConnectTimeout              time.Duration     // if not 0, how long a connection to the server can take, applied to a copy of the Transport
+	IPPreference                IPPreference      // which IP versions are used to connect to the server, applied to a copy of the Transport, by default: IPAny
+	TLSHandshakeTimeout         time.Duration     // if not 0, how long the TLS handshake can take, applied to a copy of the Transport
+	ResponseHeaderTimeout       time.Duration     // if not 0, how long to wait for the response headers once the request is sent, applied to a copy of the Transport
+	SendTimeoutHint             bool              // if true, the Timeout is sent in milliseconds in the X-Request-Timeout header so servers can give up early
+	MaxResponseHeaderBytes      int64             // how many bytes the response headers can use, by default: the Transport's limit
+	MaxResponseHeaderCount      int               // how many header values the response can contain, by default: no limit
+	RequireResponseHeaders      map[string]string // headers a successful response must contain, an empty value means any value
+	RequestBodyLogSize          int               // how many characters of the request body should be logged, if possible (<0 => nothing logged)
+	ResponseBodyLogSize         int               // how many characters of the response body should be logged (<0 => nothing logged)
 	Logger                      *logger.Logger
+	LogLevel                    logger.Level      // if set, the details of the request and its response are logged at this level (e.g.: logger.DEBUG, logger.INFO) instead of TRACE, DEBUG, or INFO
+	LogFailuresOnly             bool              // if true, only the warnings and errors of the request are logged
+	NormalizedOptionsFunc       func(Options)     // if not nil, it is called with the effective options after they are normalized by Send
+	TraceFunc                   func(Timing)      // if not nil, it is called with the Timing of each attempt once its response headers are received or it failed
+	OnAttempt                   func(AttemptInfo) // if not nil, it is called before and after each attempt
 }
 
 // DefaultAttempts defines the number of attempts for requests by default
@@ -69,6 +136,24 @@ const DefaultInterAttemptDelay = 3 * time.Second
 // DefaultInterAttemptBackoffInterval defines the interval between 2 inter attempt delay increases
 const DefaultInterAttemptBackoffInterval = 5 * time.Minute
 
+// DefaultMaxRetryAfter defines the maximum delay a Retry-After header can impose between 2 attempts by default
+const DefaultMaxRetryAfter = 5 * time.Minute
+
+// DefaultAttemptHeader is the header that carries the attempt number by default
+const DefaultAttemptHeader = "X-Attempt"
+
+// DefaultAsyncPollInterval defines the delay before the first status request of SendAsyncOperation by default
+const DefaultAsyncPollInterval = 1 * time.Second
+
+// DefaultMaxPollInterval defines the maximum delay between 2 status requests of SendAsyncOperation by default
+const DefaultMaxPollInterval = 1 * time.Minute
+
+// responseHeadersExceeded is in the error of net/http when the response headers exceed the MaxResponseHeaderBytes of the Transport
+const responseHeadersExceeded = "server response headers exceeded"
+
+// defaultMaxResponseHeaderBytes is the limit net/http uses when the MaxResponseHeaderBytes of the Transport is not set
+const defaultMaxResponseHeaderBytes = 10 << 20
+
 // DefaultRequestBodyLogSize  defines the maximum size of the request body that should be logged
 const DefaultRequestBodyLogSize = 2048
 
@@ -76,13 +161,65 @@ const DefaultRequestBodyLogSize = 2048
 const DefaultResponseBodyLogSize = 2048
 
 // Send sends an HTTP request
-func Send(options *Options, results interface{}) (*Content, error) {
-	var err error
-
+//
+// The options are not modified, Send works on a copy of them (see Options.Clone).
+// So the same Options can be used by concurrent requests.
+func Send(options *Options, results interface{}) (content *Content, err error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	if options.FollowAsyncLocation {
+		return followAsyncLocation(options, results)
+	}
+	options = options.Clone()
+	var endpoint *Endpoint // the Endpoint of the LoadBalancer the current attempt is sent to
+	if options.URL == nil && len(options.URLString) == 0 && options.LoadBalancer != nil {
+		if endpoint, err = options.LoadBalancer.Next(); err != nil {
+			return nil, err
+		}
+		options.BaseURL = endpoint.URL
+		defer func() {
+			if endpoint != nil { // nil when the next Endpoint could not be chosen after a failed attempt
+				options.LoadBalancer.Done(endpoint, err)
+			}
+		}()
+	}
+	useSRV := options.URL == nil && len(options.URLString) == 0 && len(options.SRV) > 0
+	if useSRV {
+		if err = applySRV(options); err != nil {
+			return nil, err
+		}
+	}
+	if writers, ok := results.([]io.Writer); ok {
+		for index, writer := range writers {
+			if writer == nil {
+				return nil, errors.ArgumentMissing.With(fmt.Sprintf("results[%d]", index))
+			}
+		}
+		results = io.MultiWriter(writers...)
+	}
 	if err = normalizeOptions(options, results); err != nil {
 		return nil, err
 	}
-	log := options.Logger.Child(nil, "request", "reqid", options.RequestID, "method", options.Method)
+	log := requestLogger(options).Child(nil, "request", "reqid", options.RequestID, "method", options.Method)
+	log.Tracef("Effective Options: attempts=%d, timeout=%s, delay=%s, backoff interval=%s, retryable=%v, accept=%s, user agent=%s",
+		options.Attempts, options.Timeout, options.InterAttemptDelay, options.InterAttemptBackoffInterval, options.RetryableStatusCodes, options.Accept, options.UserAgent,
+	)
+	if options.NormalizedOptionsFunc != nil {
+		options.NormalizedOptionsFunc(*options)
+	}
+	if options.SingleFlight != nil {
+		if key, ok := singleFlightKey(options, results); ok {
+			return sendSingleFlight(options, results, key)
+		}
+	}
+	if options.Scheduler != nil {
+		if err = options.Scheduler.acquire(options.Context, options.Priority); err != nil {
+			log.Errorf("Failed to get a slot from the scheduler", err)
+			return nil, err
+		}
+		defer options.Scheduler.release()
+	}
 
 	if progressCloser, ok := options.ProgressWriter.(io.Closer); ok {
 		defer func() {
@@ -90,12 +227,26 @@ func Send(options *Options, results interface{}) (*Content, error) {
 		}()
 	}
 
-	log.Debugf("HTTP %s %s", options.Method, options.URL.String())
-	req, err := buildRequest(log, options)
+	log.Record("url", options.URL.String()).Debugf("Sending the request")
+	// The request content is built only once, so all attempts send the same bytes (e.g.: multipart boundaries)
+	reqContent, err := buildRequestContent(log, options)
 	if err != nil {
 		return nil, err // err is already decorated
 	}
-	log = log.Record("method", options.Method)
+	if stream, ok := options.Payload.(*ContentReader); ok {
+		defer stream.Close()
+	}
+	if options.PayloadEncryption != nil && len(reqContent.Data) > 0 {
+		log.Tracef("Encrypting the payload with %s", options.PayloadEncryption.Algorithm)
+		if reqContent, err = reqContent.Encrypt(options.PayloadEncryption.Algorithm, options.PayloadEncryption.Key); err != nil {
+			return nil, err // err is already decorated
+		}
+	}
+	req, err := buildRequest(log, options, reqContent)
+	if err != nil {
+		return nil, err // err is already decorated
+	}
+	log = log.Record("method", options.Method) // the method might have been computed while building the request
 
 	httpclient := http.Client{
 		Transport: options.Transport,
@@ -110,80 +261,236 @@ func Send(options *Options, results interface{}) (*Content, error) {
 	}
 	// Sending the request...
 	start := time.Now()
-	for attempt := uint(0); attempt < options.Attempts; attempt++ {
-		log.Tracef("Attempt #%d/%d (timeout: %s)", attempt+1, options.Attempts, httpclient.Timeout)
-		req.Header.Set("X-Attempt", strconv.FormatUint(uint64(attempt+1), 10))
+	failedHosts := []string{}
+	refreshes := uint(0) // sending the request again with a refreshed Authorization does not count as an attempt
+	requestLog := log
+	for sent := uint(0); sent < options.Attempts+refreshes; sent++ {
+		attempt := sent - refreshes
+		log := requestLog.Records("attempt", attempt+1, "url", options.URL.String())
+		log.Records("attempts", options.Attempts, "timeout_ms", httpclient.Timeout.Milliseconds()).Tracef("Sending the attempt")
+		if !options.DisableAttemptHeader {
+			req.Header.Set(options.AttemptHeader, strconv.FormatUint(uint64(attempt+1), 10))
+		}
 		log.Tracef("Request Headers: %#v", req.Header)
+		if options.OnAttempt != nil {
+			options.OnAttempt(AttemptInfo{Attempt: attempt + 1, Attempts: options.Attempts})
+		}
 		reqStart := time.Now()
-		res, err := httpclient.Do(req)
+		tracer := newTimingTracer(log)
+		res, err := doHedged(log, &httpclient, req.WithContext(httptrace.WithClientTrace(req.Context(), tracer.ClientTrace())), options.HedgeAfter)
 		reqDuration := time.Since(reqStart)
-		log = log.Record("duration", reqDuration/time.Millisecond)
+		if options.TraceFunc != nil {
+			options.TraceFunc(*tracer.Timing())
+		}
+		attempted := func(statusCode int, err error, delay time.Duration) {
+			if options.OnAttempt != nil {
+				options.OnAttempt(AttemptInfo{Attempt: attempt + 1, Attempts: options.Attempts, Done: true, StatusCode: statusCode, Error: err, Duration: reqDuration, Delay: delay, Connection: tracer.Connection()})
+			}
+		}
+		log = log.Record("duration_ms", reqDuration.Milliseconds())
 		if err != nil {
-			netErr := &net.OpError{}
-			if errors.As(err, &netErr) && (errors.Is(netErr, syscall.ECONNRESET) || errors.Is(netErr, syscall.ECONNABORTED) || errors.Is(netErr, syscall.ECONNREFUSED)) {
-				if attempt+1 < options.Attempts {
+			if strings.Contains(err.Error(), responseHeadersExceeded) {
+				limit := options.Transport.MaxResponseHeaderBytes
+				if limit <= 0 {
+					limit = defaultMaxResponseHeaderBytes
+				}
+				log.Errorf("Response Headers exceeded %d bytes", limit)
+				attempted(0, err, 0)
+				return nil, ResponseHeadersTooLarge.With("bytes", limit)
+			}
+			retryable := options.RetryableErrors.IsRetryable(err)
+			lastAttempt := attempt+1 >= options.Attempts
+			if retryable && !lastAttempt && !options.RetryNonIdempotent && !isIdempotentRequest(req) && isDeliveryAmbiguous(err) {
+				log.Warnf("Not retrying the %s request as it might have been delivered, use an Idempotency-Key header or Options.RetryNonIdempotent to retry it", req.Method)
+				lastAttempt = true
+			}
+			var budgetErr error
+			if retryable && !lastAttempt {
+				budgetErr = checkRetryBudget(options.Context, options.InterAttemptDelay)
+			}
+			if retryable && !lastAttempt && budgetErr == nil {
+				attempted(0, err, options.InterAttemptDelay)
+			} else {
+				attempted(0, err, 0)
+			}
+			if retryable {
+				if !lastAttempt {
+					if budgetErr != nil {
+						log.Errorf("Cannot send the request again: %s", budgetErr.Error())
+						return nil, errors.WrapErrors(budgetErr, err)
+					}
 					log.Warnf("Temporary failed to send request (duration: %s/%s), Error: %s", reqDuration, options.Timeout, err.Error()) // we don't want the stack here
+					if useSRV {
+						failedHosts = append(failedHosts, options.URL.Host)
+						if host, err := resolveSRV(options.Context, options.SRVResolver, options.SRV, failedHosts...); err == nil {
+							log.Infof("SRV %s resolved to %s", options.SRV, host)
+							target := *options.URL
+							target.Host = host
+							options.URL = &target
+						}
+					}
+					if endpoint != nil {
+						if endpoint, err = nextEndpoint(log, options, endpoint, err); err != nil {
+							return nil, err
+						}
+					}
 					log.Infof("Waiting for %s before trying again", options.InterAttemptDelay)
 					time.Sleep(options.InterAttemptDelay)
-					req, _ = buildRequest(log, options)
+					if err = rewindPayload(options); err != nil {
+						log.Errorf("Failed to get the payload for attempt #%d", attempt+2, err)
+						return nil, err
+					}
+					if req, err = buildRequest(log, options, reqContent); err != nil {
+						log.Errorf("Failed to build the request for attempt #%d", attempt+2, err)
+						return nil, errors.Wrapf(err, "Failed to build the request for attempt #%d", attempt+2)
+					}
 					continue
 				}
 				break
 			}
 			urlErr := &url.Error{}
 			if errors.As(err, &urlErr) {
-				if urlErr.Timeout() || urlErr.Temporary() || urlErr.Unwrap() == io.EOF || errors.Is(err, context.DeadlineExceeded) {
-					if attempt+1 < options.Attempts {
-						log.Warnf("Temporary failed to send request (duration: %s/%s), Error: %s", reqDuration, options.Timeout, err.Error()) // we don't want the stack here
-						log.Infof("Waiting for %s before trying again", options.InterAttemptDelay)
-						time.Sleep(options.InterAttemptDelay)
-						req, _ = buildRequest(log, options)
-						continue
-					}
-					break
-				} else {
-					log.Errorf("URL Error, temporary=%t, timeout=%t, unwrap=%s", urlErr.Temporary(), urlErr.Timeout(), urlErr.Unwrap(), err)
-					return nil, errors.WithStack(err)
-				}
+				log.Errorf("URL Error, temporary=%t, timeout=%t, unwrap=%s", urlErr.Temporary(), urlErr.Timeout(), urlErr.Unwrap(), err)
+				return nil, errors.WithStack(err)
 			}
 			return nil, err
 		}
-		defer res.Body.Close()
+		log = log.Record("status", res.StatusCode)
+		streaming := false // when true, the body belongs to the ContentReader given as results
+		defer func(body io.Closer) {
+			if !streaming {
+				body.Close()
+			}
+		}(res.Body)
+
+		// Checking the response headers
+		if options.MaxResponseHeaderCount > 0 {
+			count := 0
+			for _, values := range res.Header {
+				count += len(values)
+			}
+			if count > options.MaxResponseHeaderCount {
+				log.Errorf("Response contains %d header values (max: %d)", count, options.MaxResponseHeaderCount)
+				attempted(res.StatusCode, ResponseHeadersTooLarge.With("count", count), 0)
+				return nil, ResponseHeadersTooLarge.With("count", count)
+			}
+		}
 
 		// Processing the status
+		expected := len(options.ExpectStatus) == 0 || core.Contains(options.ExpectStatus, res.StatusCode)
+		acceptable := res.StatusCode >= 400 && (core.Contains(options.AcceptableStatusCodes, res.StatusCode) || (len(options.ExpectStatus) > 0 && expected))
+		if acceptable || !expected && res.StatusCode < 400 {
+			if acceptable {
+				attempted(res.StatusCode, nil, 0)
+			} else {
+				attempted(res.StatusCode, UnexpectedStatus.With(strconv.Itoa(res.StatusCode), options.ExpectStatus), 0)
+			}
+			resContent, err := ContentFromReader(res.Body, res.Header.Get("Content-Type"), core.Atoi(res.Header.Get("Content-Length"), 0), res.Header, res.Cookies(), keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			setResponseInfo(resContent, res, tracer)
+			if acceptable {
+				log.Infof("Acceptable Response")
+				return resContent, nil
+			}
+			log.Errorf("Unexpected Response (expected: %v)", options.ExpectStatus)
+			return resContent, UnexpectedStatus.With(strconv.Itoa(res.StatusCode), options.ExpectStatus)
+		}
 		if res.StatusCode >= 400 {
-			log.Errorf("Response %s in %s", res.Status, reqDuration)
+			log.Errorf("Response failed")
 			log.Debugf("Response Headers: %#v", res.Header)
+			if res.StatusCode == http.StatusUnauthorized && options.OnUnauthorized != nil && refreshes == 0 && (!isContentReader(options.Payload) || options.GetPayload != nil) {
+				log.Infof("Refreshing the Authorization before sending the request again")
+				attempted(res.StatusCode, errors.FromHTTPStatusCode(res.StatusCode), 0)
+				refreshes++
+				authorization, err := options.OnUnauthorized(options.Context)
+				if err != nil {
+					return nil, errors.Wrap(err, "Failed to refresh the Authorization")
+				}
+				options.Authorization = authorization
+				options.TokenProvider = nil // the refreshed Authorization supersedes the TokenProvider, which might still give the rejected one
+				if err = rewindPayload(options); err != nil {
+					log.Errorf("Failed to get the payload for attempt #%d", attempt+1, err)
+					return nil, err
+				}
+				if req, err = buildRequest(log, options, reqContent); err != nil {
+					log.Errorf("Failed to build the request for attempt #%d", attempt+1, err)
+					return nil, errors.Wrapf(err, "Failed to build the request for attempt #%d", attempt+1)
+				}
+				continue
+			}
+			statusErr := errors.FromHTTPStatusCode(res.StatusCode)
 			if core.Contains(options.RetryableStatusCodes, res.StatusCode) {
 				if attempt+1 < options.Attempts {
 					var retryAfter time.Duration
 
 					log.Infof("Retryable Response Status: %s", res.Status)
-					if options.InterAttemptUseRetryAfter && len(res.Header.Get("Retry-After")) > 0 {
-						retryAfter = time.Duration(core.Atoi(res.Header.Get("Retry-After"), 0))*time.Second + 1*time.Second // just to stay on the safe side, add 1 second
+					useRetryAfter := options.InterAttemptUseRetryAfter || res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable
+					if delay, found := parseRetryAfter(res.Header); useRetryAfter && found {
+						retryAfter = delay + 1*time.Second // just to stay on the safe side, add 1 second
 						log.Debugf("Retry-After from headers (+1s safety net): %s", retryAfter)
+						if retryAfter > options.MaxRetryAfter {
+							log.Warnf("Retry-After %s is capped to %s", retryAfter, options.MaxRetryAfter)
+							retryAfter = options.MaxRetryAfter
+						}
 					} else {
 						elapsed := time.Since(start)
 						interval := int(elapsed/options.InterAttemptBackoffInterval) + 1
 						retryAfter = time.Duration(math.Pow(options.InterAttemptDelay.Seconds(), float64(interval))) * time.Second
 						log.Debugf("Interval: %d, delay: %s, Exponential Backoff: %s", interval, options.InterAttemptDelay, retryAfter)
 					}
-					log.Infof("Waiting for %s before trying again", retryAfter)
-					time.Sleep(retryAfter)
-					req, _ = buildRequest(log, options)
-					continue
+					if budgetErr := checkRetryBudget(options.Context, retryAfter); budgetErr != nil {
+						log.Errorf("Cannot send the request again: %s", budgetErr.Error())
+						statusErr = errors.WrapErrors(budgetErr, statusErr)
+					} else {
+						attempted(res.StatusCode, statusErr, retryAfter)
+						if endpoint != nil && isEndpointFailure(statusErr) {
+							if endpoint, err = nextEndpoint(log, options, endpoint, statusErr); err != nil {
+								return nil, err
+							}
+						}
+						log.Infof("Waiting for %s before trying again", retryAfter)
+						time.Sleep(retryAfter)
+						if err = rewindPayload(options); err != nil {
+							log.Errorf("Failed to get the payload for attempt #%d", attempt+2, err)
+							return nil, err
+						}
+						if req, err = buildRequest(log, options, reqContent); err != nil {
+							log.Errorf("Failed to build the request for attempt #%d", attempt+2, err)
+							return nil, errors.Wrapf(err, "Failed to build the request for attempt #%d", attempt+2)
+						}
+						continue
+					}
 				}
 			}
+			attempted(res.StatusCode, statusErr, 0)
 			// Read the body to get the error message
-			resContent, err := ContentFromReader(res.Body, res.Header.Get("Content-Type"), core.Atoi(res.Header.Get("Content-Length"), 0), res.Header, res.Cookies(), log)
+			resContent, err := ContentFromReader(res.Body, res.Header.Get("Content-Type"), core.Atoi(res.Header.Get("Content-Length"), 0), res.Header, res.Cookies(), keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
 			if err != nil {
-				return nil, errors.FromHTTPStatusCode(res.StatusCode)
+				return nil, statusErr
 			}
-			log.Infof("Response body in %s: %s", time.Since(start), resContent.LogString(uint64(options.ResponseBodyLogSize)))
-			return resContent, errors.FromHTTPStatusCode(res.StatusCode)
+			setResponseInfo(resContent, res, tracer)
+			log.Record("bytes", resContent.Length).Infof("Response body: %s", resContent.LogString(uint64(options.ResponseBodyLogSize)))
+			if isProblemDetails(resContent.Type) {
+				if problem, err := problemDetailsFromContent(resContent, statusErr); err == nil {
+					return resContent, problem
+				}
+				log.Warnf("Failed to decode the problem details")
+			}
+			if envelopeErr := checkEnvelope(options.ResponseEnvelope, resContent); envelopeErr != nil {
+				return resContent, envelopeErr
+			}
+			return resContent, statusErr
 		}
 
-		log.Debugf("Response %s in %s", res.Status, reqDuration)
+		if err := checkResponseHeaders(res.Header, options.RequireResponseHeaders); err != nil {
+			log.Errorf("Response does not contain the required headers", err)
+			attempted(res.StatusCode, err, 0)
+			return nil, err
+		}
+		attempted(res.StatusCode, nil, 0)
+		log.Debugf("Response received")
 		log.Tracef("Response Headers: %#v", res.Header)
 
 		// Analyze the response content type
@@ -206,9 +513,57 @@ func Send(options *Options, results interface{}) (*Content, error) {
 		log.Tracef("Computed Response Content-Type: %s", resContentType)
 
 		// Reading the response body
+		body := io.Reader(res.Body)
+		if options.MaxBytesPerSecond > 0 {
+			body = newThrottledReader(options.Context, body, options.MaxBytesPerSecond)
+		}
+		if options.TeeWriter != nil {
+			body = io.TeeReader(body, options.TeeWriter)
+		}
+		var checksums *checksumVerifier
+		if options.VerifyChecksum {
+			if checksums = newChecksumVerifier(res.Header); checksums != nil {
+				body = io.TeeReader(body, checksums)
+			}
+		}
 
-		if writer, ok := results.(io.Writer); ok {
+		if reader, ok := results.(*ContentReader); ok {
+			closer := io.Closer(res.Body)
+			if options.ResponseDecryption != nil {
+				encrypted, err := ContentFromReader(body, resContentType, res.Header, keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				decrypted, err := decryptResponse(options.ResponseDecryption, encrypted)
+				if err != nil {
+					return nil, err // err is already decorated
+				}
+				body = decrypted.Reader()
+				res.ContentLength = int64(decrypted.Length)
+			}
+			*reader = ContentReader{
+				Type:       resContentType,
+				URL:        res.Request.URL,
+				Length:     res.ContentLength,
+				Headers:    res.Header,
+				Cookies:    res.Cookies(),
+				Trailer:    res.Trailer,
+				StatusCode: res.StatusCode,
+				Proto:      res.Proto,
+			}
+			reader.setBody(body, closer, checksums.Verify)
+			streaming = true
+			log.Record("bytes", res.ContentLength).Tracef("Streaming the response body")
+			resContent := ContentWithData([]byte{}, resContentType, res.Header, res.Cookies())
+			setResponseInfo(resContent, res, tracer)
+			return resContent, nil
+		} else if writer, ok := results.(io.Writer); ok {
 			if options.ProgressWriter != nil {
+				if reporter, ok := options.ProgressWriter.(*progressReporter); ok {
+					if size, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64); err == nil {
+						reporter.setTotal(size)
+					}
+				}
 				if options.ProgressSetMaxFunc != nil {
 					if size, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64); err == nil {
 						options.ProgressSetMaxFunc(size)
@@ -224,35 +579,95 @@ func Send(options *Options, results interface{}) (*Content, error) {
 				}
 				writer = io.MultiWriter(writer, options.ProgressWriter)
 			}
-			bytesRead, err := io.Copy(writer, res.Body)
+			if options.ResponseVerifier != nil || options.ResponseDecryption != nil {
+				raw, err := ContentFromReader(body, resContentType, res.Header, keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				if err = verifyResponse(options.ResponseVerifier, raw); err != nil {
+					log.Errorf("Failed to verify the response", err)
+					return nil, err
+				}
+				if options.ResponseDecryption != nil {
+					if raw, err = decryptResponse(options.ResponseDecryption, raw); err != nil {
+						return nil, err // err is already decorated
+					}
+				}
+				body = raw.Reader()
+			}
+			bytesRead, err := io.Copy(writer, body)
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
-			log.Tracef("Read %d bytes", bytesRead)
+			log.Record("bytes", bytesRead).Tracef("Read the response body")
 			resContent := ContentWithData([]byte{}, resContentType, bytesRead, res.Header, res.Cookies())
+			setResponseInfo(resContent, res, tracer)
+			if err = checksums.Verify(); err != nil {
+				log.Errorf("Response body is corrupted", err)
+				return resContent, err
+			}
 			return resContent, nil
 		} else if results != nil { // Unmarshaling the response body if requested (structs, arrays, maps, etc)
-			resContent, err := ContentFromReader(res.Body, resContentType, res.Header, res.Cookies(), log)
+			resContent, err := ContentFromReader(body, resContentType, res.Header, res.Cookies(), keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
-			log.Tracef("Response body in %s: %s", time.Since(start), resContent.LogString(uint64(options.ResponseBodyLogSize)))
+			if err = verifyResponse(options.ResponseVerifier, resContent); err != nil {
+				log.Errorf("Failed to verify the response", err)
+				return resContent, err
+			}
+			if options.ResponseDecryption != nil {
+				if resContent, err = decryptResponse(options.ResponseDecryption, resContent); err != nil {
+					return nil, err // err is already decorated
+				}
+			}
+			setResponseInfo(resContent, res, tracer)
+			log.Record("bytes", resContent.Length).Tracef("Response body: %s", resContent.LogString(uint64(options.ResponseBodyLogSize)))
+			if err = checksums.Verify(); err != nil {
+				log.Errorf("Response body is corrupted", err)
+				return resContent, err
+			}
+			if err = checkEnvelope(options.ResponseEnvelope, resContent); err != nil {
+				log.Errorf("Response body carries an error", err)
+				return resContent, err
+			}
 			if resContent.Length > 0 {
-				err = json.Unmarshal(resContent.Data, results)
-				if err != nil {
-					return resContent, errors.JSONUnmarshalError.WrapIfNotMe(err)
+				if err = decodeResults(resContent, results, options); err != nil {
+					return resContent, err // err is already decorated
 				}
 			}
 			return resContent, nil
 		}
 
 		// Reading all the response body into the Content
-		resContent, err := ContentFromReader(res.Body, resContentType, core.Atoi(res.Header.Get("Content-Length"), 0), res.Header, res.Cookies(), log)
+		resContent, err := ContentFromReader(body, resContentType, core.Atoi(res.Header.Get("Content-Length"), 0), res.Header, res.Cookies(), keepEncoding(options.DisableCompression), KeepRawData(options.KeepRawData), log)
 		if err != nil {
 			log.Errorf("Failed to read response body: %v%s", err, "") // the extra string arg is to prevent the logger to dump the stack trace
-			return nil, err                                           // err is already "decorated" by ContentReader
+			return nil, err                                           // err is already "decorated" by ContentFromReader
+		}
+		if err = verifyResponse(options.ResponseVerifier, resContent); err != nil {
+			log.Errorf("Failed to verify the response", err)
+			return resContent, err
+		}
+		if options.ResponseDecryption != nil {
+			if resContent, err = decryptResponse(options.ResponseDecryption, resContent); err != nil {
+				return nil, err // err is already decorated
+			}
+		}
+		setResponseInfo(resContent, res, tracer)
+		if err = checksums.Verify(); err != nil {
+			log.Errorf("Response body is corrupted", err)
+			return resContent, err
+		}
+		if len(resContent.Type) == 0 && looksLikeJSON(resContent.Data) {
+			log.Tracef("Response body looks like JSON")
+			resContent.Type = "application/json"
+		}
+		log.Record("bytes", resContent.Length).Tracef("Response body: %s", resContent.LogString(uint64(options.ResponseBodyLogSize)))
+		if err = checkEnvelope(options.ResponseEnvelope, resContent); err != nil {
+			log.Errorf("Response body carries an error", err)
+			return resContent, err
 		}
-		log.Tracef("Response body in %s: %s", time.Since(start), resContent.LogString(uint64(options.ResponseBodyLogSize)))
 
 		return resContent, nil
 	}
@@ -260,12 +675,65 @@ func Send(options *Options, results interface{}) (*Content, error) {
 	return nil, errors.Wrapf(errors.HTTPStatusRequestTimeout, "Giving up after %d attempts (%s)", options.Attempts, time.Since(start))
 }
 
+// normalizeURL computes the URL of the options from URLString or BaseURL and Path, then applies PathParameters, Parameters, and QueryValues
+func normalizeURL(options *Options) (err error) {
+	if options.URL == nil && len(options.URLString) > 0 {
+		if options.URL, err = parseURL(options.URLString); err != nil {
+			return err
+		}
+	}
+	if options.URL == nil {
+		if options.BaseURL == nil {
+			return errors.ArgumentMissing.With("URL")
+		}
+		options.URL = joinURL(options.BaseURL, options.Path)
+	}
+	if len(options.PathParameters) > 0 {
+		if err = expandPathTemplate(options.URL, options.PathParameters); err != nil {
+			return err
+		}
+	}
+	if options.Parameters != nil || options.QueryValues != nil {
+		query := options.URL.Query()
+		for key, value := range options.Parameters {
+			query.Add(key, value)
+		}
+		for key, values := range options.QueryValues {
+			query[key] = append(query[key], values...)
+		}
+		options.URL.RawQuery = query.Encode()
+	}
+	return nil
+}
+
+// nextEndpoint tells the LoadBalancer the attempt sent to the given Endpoint failed and gets the Endpoint of the next attempt
+//
+// The URL of the options is built again from the BaseURL of the next Endpoint.
+func nextEndpoint(log *logger.Logger, options *Options, endpoint *Endpoint, failure error) (*Endpoint, error) {
+	options.LoadBalancer.Done(endpoint, failure)
+	next, err := options.LoadBalancer.Next()
+	if err != nil {
+		return nil, err
+	}
+	options.BaseURL = next.URL
+	options.URL = nil
+	if err = normalizeURL(options); err != nil {
+		options.LoadBalancer.Done(next, nil)
+		return nil, err
+	}
+	log.Infof("Sending the next attempt to %s", next.URL)
+	return next, nil
+}
+
 func normalizeOptions(options *Options, results interface{}) (err error) {
 	if options == nil {
 		return errors.ArgumentMissing.With("options")
 	}
-	if options.URL == nil {
-		return errors.ArgumentMissing.With("URL")
+	if err = normalizeURL(options); err != nil {
+		return err
+	}
+	if scheme := strings.ToLower(options.URL.Scheme); scheme != "http" && scheme != "https" && !core.Contains(options.AllowedSchemes, scheme) {
+		return UnsupportedURLScheme.With(options.URL.Scheme, append([]string{"http", "https"}, options.AllowedSchemes...))
 	}
 	if options.Context == nil {
 		options.Context = context.Background()
@@ -287,17 +755,23 @@ func normalizeOptions(options *Options, results interface{}) (err error) {
 		options.ResponseBodyLogSize = 0
 	}
 	if len(options.RequestID) == 0 {
-		options.RequestID = uuid.Must(uuid.NewRandom()).String()
+		if requestID, found := requestIDFromContext(options.Context, options.RequestIDContextKey); found {
+			options.RequestID = requestID
+		} else {
+			options.RequestID = uuid.Must(uuid.NewRandom()).String()
+		}
+	}
+	if len(options.RequestIDHeader) == 0 {
+		options.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if len(options.AttemptHeader) == 0 {
+		options.AttemptHeader = DefaultAttemptHeader
 	}
 	if len(options.UserAgent) == 0 {
 		options.UserAgent = "Request " + VERSION
 	}
 	if len(options.Accept) == 0 {
-		if _, ok := results.(io.Writer); !ok && results != nil {
-			options.Accept = "application/json"
-		} else {
-			options.Accept = "*"
-		}
+		options.Accept = AcceptFor(results)
 	}
 	if options.Timeout == 0 {
 		options.Timeout = time.Duration(DefaultTimeout)
@@ -311,24 +785,87 @@ func normalizeOptions(options *Options, results interface{}) (err error) {
 	if options.InterAttemptBackoffInterval < 1*time.Second {
 		options.InterAttemptBackoffInterval = time.Duration(DefaultInterAttemptBackoffInterval)
 	}
-	if len(options.RetryableStatusCodes) == 0 {
-		options.RetryableStatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	if options.MaxRetryAfter <= 0 {
+		options.MaxRetryAfter = DefaultMaxRetryAfter
 	}
-	if options.Parameters != nil {
-		query := options.URL.Query()
-		for key, value := range options.Parameters {
-			query.Add(key, value)
+	if len(options.SRV) > 0 && options.SRVResolver == nil {
+		options.SRVResolver = net.DefaultResolver
+	}
+	if options.ProgressFunc != nil {
+		if _, ok := options.ProgressWriter.(*progressReporter); !ok {
+			options.ProgressWriter = newProgressReporter(options.ProgressWriter, options.ProgressFunc, options.ProgressInterval)
 		}
-		options.URL.RawQuery = query.Encode()
 	}
+	if options.RetryableErrors == nil {
+		options.RetryableErrors = DefaultRetryableErrorClassifier
+	}
+	if len(options.RetryableStatusCodes) == 0 {
+		options.RetryableStatusCodes = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	customTransport := options.ConnectTimeout > 0 || options.IPPreference != IPAny || options.TLSHandshakeTimeout > 0 || options.ResponseHeaderTimeout > 0 ||
+		options.DisableCompression ||
+		options.InsecureSkipVerify || options.TLSMinVersion > 0 || len(options.ServerName) > 0 || len(options.PinnedCertificates) > 0 ||
+		options.Proxy != nil || options.MaxResponseHeaderBytes > 0
 	if options.Transport == nil {
 		options.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	} else if customTransport {
+		// the Transport might be shared with other requests, so we work on a copy
+		options.Transport = options.Transport.Clone()
+	}
+	if customTransport {
+		if options.ConnectTimeout > 0 || options.IPPreference != IPAny {
+			options.Transport.DialContext = dialContext(options.Transport.DialContext, options.ConnectTimeout, options.IPPreference)
+		}
+		if options.TLSHandshakeTimeout > 0 {
+			options.Transport.TLSHandshakeTimeout = options.TLSHandshakeTimeout
+		}
+		if options.ResponseHeaderTimeout > 0 {
+			options.Transport.ResponseHeaderTimeout = options.ResponseHeaderTimeout
+		}
+		if options.DisableCompression {
+			options.Transport.DisableCompression = true
+		}
+		if options.InsecureSkipVerify || options.TLSMinVersion > 0 || len(options.ServerName) > 0 || len(options.PinnedCertificates) > 0 {
+			if options.Transport.TLSClientConfig == nil {
+				options.Transport.TLSClientConfig = &tls.Config{}
+			}
+			if options.InsecureSkipVerify {
+				options.Transport.TLSClientConfig.InsecureSkipVerify = true
+			}
+			if options.TLSMinVersion > 0 {
+				options.Transport.TLSClientConfig.MinVersion = options.TLSMinVersion
+			}
+			if len(options.ServerName) > 0 {
+				options.Transport.TLSClientConfig.ServerName = options.ServerName
+			}
+			if len(options.PinnedCertificates) > 0 {
+				options.Transport.TLSClientConfig.VerifyConnection = verifyPinnedCertificates(options.URL.Hostname(), options.PinnedCertificates)
+			}
+		}
+		if options.Proxy != nil {
+			options.Transport.Proxy = http.ProxyURL(options.Proxy)
+		}
+		if options.MaxResponseHeaderBytes > 0 {
+			options.Transport.MaxResponseHeaderBytes = options.MaxResponseHeaderBytes
+		}
 	}
-	if options.Proxy != nil {
-		options.Transport.Proxy = http.ProxyURL(options.Proxy)
+	if options.GetPayload != nil && options.Payload == nil {
+		if options.Payload, err = options.GetPayload(); err != nil {
+			return errors.Wrap(err, "Failed to get the payload")
+		}
+	}
+	if options.PayloadLength != 0 || options.GetPayload != nil {
+		if reader, ok := options.Payload.(io.Reader); ok {
+			if _, ok := reader.(*ContentReader); !ok {
+				options.Payload = payloadStream(reader, options)
+			}
+			if options.GetPayload == nil {
+				options.Attempts = 1 // a stream cannot be sent again
+			}
+		}
 	}
 	if options.Attempts > 1 {
-		if options.Payload != nil {
+		if options.Payload != nil && options.GetPayload == nil {
 			if _, ok := options.Payload.(io.Reader); ok {
 				if _, ok := options.Payload.(io.Seeker); !ok {
 					return errors.WrapErrors(errors.ArgumentInvalid.With("Payload", fmt.Sprintf("%T", options.Payload)), fmt.Errorf("Payload must be an io.Seeker if you want to retry the request"))
@@ -341,12 +878,25 @@ func normalizeOptions(options *Options, results interface{}) (err error) {
 			}
 		}
 	}
+	if isContentReader(options.Payload) && (options.PayloadEncryption != nil || options.Signer != nil) {
+		return errors.WrapErrors(errors.ArgumentInvalid.With("Payload", fmt.Sprintf("%T", options.Payload)), fmt.Errorf("a streamed Payload cannot be encrypted or signed"))
+	}
+	if isContentReader(results) && options.ResponseVerifier != nil {
+		return errors.WrapErrors(errors.ArgumentInvalid.With("results", fmt.Sprintf("%T", results)), fmt.Errorf("a streamed response cannot be verified before it is read"))
+	}
 	return nil
 }
 
 // buildRequestContent builds a Content for the request
 func buildRequestContent(log *logger.Logger, options *Options) (content *Content, err error) {
 	// Analyze payload
+	if len(options.Attachments) > 0 {
+		fields, err := formFields(log, options.Payload)
+		if err != nil {
+			return nil, err
+		}
+		return buildMultipartContent(log, options, fields)
+	}
 	if options.Payload == nil {
 		if options.Attachment == nil {
 			return &Content{}, nil
@@ -358,7 +908,18 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 		}
 	}
 
-	if _content, ok := options.Payload.(Content); ok {
+	if stream, ok := options.Payload.(*ContentReader); ok {
+		log.Tracef("Payload is a *ContentReader (Type: %s, size: %d), it will be streamed", stream.Type, stream.Length)
+		content = &Content{Type: stream.Type, Name: stream.Name}
+		if len(options.PayloadType) > 0 {
+			content.Type = options.PayloadType
+		} else if len(content.Type) == 0 {
+			content.Type = "application/octet-stream"
+		}
+		if stream.Length > 0 {
+			content.Length = uint64(stream.Length)
+		}
+	} else if _content, ok := options.Payload.(Content); ok {
 		log.Tracef("Payload is a Content (Type: %s, size: %d)", _content.Type, _content.Length)
 		if len(options.PayloadType) > 0 {
 			_content.Type = options.PayloadType
@@ -368,18 +929,54 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 		content = &_content
 	} else if _content, ok := options.Payload.(*Content); ok {
 		log.Tracef("Payload is a *Content (Type: %s, size: %d)", _content.Type, _content.Length)
+		copied := *_content // the Content of the caller is not modified
 		if len(options.PayloadType) > 0 {
-			_content.Type = options.PayloadType
-		} else if len(_content.Type) == 0 {
-			_content.Type = "application/octet-stream"
+			copied.Type = options.PayloadType
+		} else if len(copied.Type) == 0 {
+			copied.Type = "application/octet-stream"
+		}
+		content = &copied
+	} else if section, ok := sectionReader(options.Payload); ok {
+		log.Tracef("Payload is a ReaderAt (Data Type: %s, size: %d)", options.PayloadType, section.Size())
+		if content, err = ContentFromReader(section, options.PayloadType); err == nil {
+			if file, ok := options.Payload.(*os.File); ok {
+				content.Name = filepath.Base(file.Name())
+			}
+			if len(content.Type) == 0 {
+				content.Type = http.DetectContentType(content.Data) // only looks at the first 512 bytes
+				log.Tracef("Payload type sniffed as %s", content.Type)
+			}
 		}
-		content = _content
 	} else if reader, ok := options.Payload.(io.Reader); ok {
 		log.Tracef("Payload is a Reader (Data Type: %s)", options.PayloadType)
 		content, _ = ContentFromReader(reader, options.PayloadType, 0, nil, nil)
+	} else if fields, ok := options.Payload.([]FormField); ok {
+		if options.Attachment != nil || strings.HasPrefix(options.PayloadType, "multipart/form-data") {
+			content, err = buildMultipartContent(log, options, fields)
+		} else {
+			log.Tracef("Payload is a list of FormFields, encoding it as a form")
+			if len(options.PayloadType) == 0 {
+				options.PayloadType = "application/x-www-form-urlencoded"
+			}
+			content = ContentWithData([]byte(encodeFields(fields)), options.PayloadType)
+		}
+	} else if encoder, found := encoderFor(options.PayloadType); found {
+		var payload []byte
+
+		log.Tracef("Payload is encoded with the Encoder registered for %s", options.PayloadType)
+		if payload, err = encoder.Encode(options.Payload); err == nil {
+			content = ContentWithData(payload, options.PayloadType)
+		}
 	} else {
 		payloadType := reflect.TypeOf(options.Payload)
-		if payloadType.Kind() == reflect.Struct || (payloadType.Kind() == reflect.Ptr && reflect.Indirect(reflect.ValueOf(options.Payload)).Kind() == reflect.Struct) { // JSONify the payload
+		if options.PayloadType == "application/x-www-form-urlencoded" && (payloadType.Kind() == reflect.Struct || (payloadType.Kind() == reflect.Ptr && reflect.Indirect(reflect.ValueOf(options.Payload)).Kind() == reflect.Struct)) {
+			var form url.Values
+
+			log.Tracef("Payload is a Struct, encoding it as a form")
+			if form, err = encodeForm(options.Payload); err == nil {
+				content = ContentWithData([]byte(form.Encode()), options.PayloadType)
+			}
+		} else if payloadType.Kind() == reflect.Struct || (payloadType.Kind() == reflect.Ptr && reflect.Indirect(reflect.ValueOf(options.Payload)).Kind() == reflect.Struct) { // JSONify the payload
 			var payload []byte
 
 			log.Tracef("Payload is a Struct, JSONifying it")
@@ -387,7 +984,7 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 			if len(options.PayloadType) == 0 {
 				options.PayloadType = "application/json"
 			}
-			if payload, err = marshal(options.Payload); err == nil {
+			if payload, err = options.KeyTranslation.marshal(options.Payload); err == nil {
 				content = ContentWithData(payload, options.PayloadType)
 			}
 		} else if payloadType.Kind() == reflect.Array || payloadType.Kind() == reflect.Slice {
@@ -403,7 +1000,7 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 
 				log.Tracef("Payload is an array or a slice, JSONifying it")
 				options.PayloadType = "application/json"
-				if payload, err = marshal(options.Payload); err == nil {
+				if payload, err = options.KeyTranslation.marshal(options.Payload); err == nil {
 					content = ContentWithData(payload, options.PayloadType)
 				}
 			}
@@ -413,26 +1010,14 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 				var payload []byte
 
 				log.Tracef("Payload is a map and its type is application/json, JSONifying it")
-				if payload, err = marshal(options.Payload); err == nil {
+				if payload, err = options.KeyTranslation.marshal(options.Payload); err == nil {
 					content = ContentWithData(payload, options.PayloadType)
 				}
 			default:
-				// Collect the attributes from the map
-				attributes := map[string]string{}
-				if stringMap, ok := options.Payload.(map[string]string); ok {
-					log.Tracef("Payload is a StringMap")
-					for key, value := range stringMap {
-						attributes[key] = value
-					}
-				} else { // traverse the map, collecting values if they are Stringer. Note: This can be slow...
-					log.Tracef("Payload is a Map")
-					items := reflect.ValueOf(options.Payload)
-					for _, item := range items.MapKeys() {
-						value := items.MapIndex(item)
-						if stringer, ok := value.Interface().(fmt.Stringer); ok {
-							attributes[item.String()] = stringer.String()
-						}
-					}
+				// Collect the fields from the map
+				var fields []FormField
+				if fields, err = formFields(log, options.Payload); err != nil {
+					return nil, err
 				}
 
 				// Build the content as a Form or a Multipart Data Form
@@ -441,60 +1026,11 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 					if len(options.PayloadType) == 0 {
 						options.PayloadType = "application/x-www-form-urlencoded"
 					}
-					form := url.Values{}
-					for key, value := range attributes {
-						form.Set(key, value)
-					}
-					return ContentWithData([]byte(form.Encode()), options.PayloadType), nil
+					return ContentWithData([]byte(encodeFields(fields)), options.PayloadType), nil
 				}
-
-				log.Tracef("Building a multipart data form with 1 attachment")
-				body := &bytes.Buffer{}
-				writer := multipart.NewWriter(body)
-				for key, value := range attributes {
-					if strings.HasPrefix(key, ">") {
-						key = strings.TrimPrefix(key, ">")
-						if len(key) == 0 {
-							return nil, errors.Errorf("Empty key for multipart form field with attachment")
-						}
-						if len(value) == 0 {
-							return nil, errors.Errorf("Empty value for multipart form field %s", key)
-						}
-						partHeader := textproto.MIMEHeader{}
-						partHeader.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"", key, value))
-						if len(options.AttachmentType) > 0 {
-							partHeader.Add("Content-Type", options.AttachmentType)
-						}
-						part, err := writer.CreatePart(partHeader)
-						if err != nil {
-							return nil, errors.Wrapf(err, "Failed to create multipart for field %s", key)
-						}
-						// if options.Attempts == 1, we don't need to seek to the beginning of the attachment
-						if options.Attempts > 1 {
-							_, err = options.Attachment.(io.Seeker).Seek(0, io.SeekStart)
-							if err != nil {
-								return nil, errors.Wrapf(err, "Failed to seek to beginning of attachment for field %s", key)
-							}
-						}
-						written, err := io.Copy(part, options.Attachment)
-						if err != nil {
-							return nil, errors.Errorf("Failed to write attachment to multipart form field %s", key)
-						}
-						if written == 0 {
-							return nil, errors.Errorf("Missing/Empty Attachment for multipart form field %s", key)
-						}
-						log.Tracef("Wrote %d bytes to multipart form field %s", written, key)
-					} else {
-						if err := writer.WriteField(key, value); err != nil {
-							return nil, errors.Wrapf(err, "Failed to create multipart form field %s", key)
-						}
-						log.Tracef("  Added field %s = %s", key, value)
-					}
-				}
-				if err := writer.Close(); err != nil {
-					return nil, errors.Wrap(err, "Failed to create multipart data")
+				if content, err = buildMultipartContent(log, options, fields); err != nil {
+					return nil, err
 				}
-				content, _ = ContentFromReader(body, writer.FormDataContentType())
 			}
 		}
 	}
@@ -503,22 +1039,73 @@ func buildRequestContent(log *logger.Logger, options *Options) (content *Content
 	}
 	if content != nil {
 		if options.RequestBodyLogSize > 0 {
-			log.Tracef("Request body %d bytes: \n%s", content.Length, string(content.Data[:int(math.Min(float64(options.RequestBodyLogSize), float64(content.Length)))]))
+			log.Record("bytes", content.Length).Tracef("Request body: \n%s", string(content.Data[:int(math.Min(float64(options.RequestBodyLogSize), float64(len(content.Data))))]))
 		} else {
-			log.Tracef("Request body %d bytes", content.Length)
+			log.Record("bytes", content.Length).Tracef("Request body")
 		}
 		return content, nil
 	}
 	return nil, errors.ArgumentInvalid.With("payload")
 }
 
-func buildRequest(log *logger.Logger, options *Options) (*http.Request, error) {
-	reqContent, err := buildRequestContent(log, options)
+// payloadStream gets a ContentReader that streams the given reader as the Payload
+func payloadStream(reader io.Reader, options *Options) *ContentReader {
+	length := options.PayloadLength
+	if length == 0 {
+		length = -1
+	}
+	stream := &ContentReader{Type: options.PayloadType, Length: length}
+	closer, _ := reader.(io.Closer)
+	stream.setBody(reader, closer, nil)
+	return stream
+}
+
+// rewindPayload gets a new body from GetPayload before the request is sent again
+func rewindPayload(options *Options) error {
+	if options.GetPayload == nil {
+		return nil
+	}
+	body, err := options.GetPayload()
 	if err != nil {
-		return nil, err // err is already decorated
+		return errors.Wrap(err, "Failed to get the payload")
+	}
+	options.Payload = payloadStream(body, options)
+	return nil
+}
+
+// sectionReader gets a reader from the current offset when the payload is a file or an io.ReaderAt that knows its size
+//
+// Since it does not move the offset of the payload, the payload can be read again on each attempt.
+func sectionReader(payload interface{}) (*io.SectionReader, bool) {
+	readerAt, ok := payload.(io.ReaderAt)
+	if !ok {
+		return nil, false
 	}
+	var size int64
+	if stater, ok := payload.(interface{ Stat() (os.FileInfo, error) }); ok {
+		info, err := stater.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return nil, false
+		}
+		size = info.Size()
+	} else if sizer, ok := payload.(interface{ Size() int64 }); ok {
+		size = sizer.Size()
+	} else {
+		return nil, false
+	}
+	var offset int64
+	if seeker, ok := payload.(io.Seeker); ok {
+		if current, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			offset = min(current, size)
+		}
+	}
+	return io.NewSectionReader(readerAt, offset, size-offset), true
+}
+
+func buildRequest(log *logger.Logger, options *Options, reqContent *Content) (*http.Request, error) {
+	stream, streaming := options.Payload.(*ContentReader)
 	if len(options.Method) == 0 {
-		if reqContent.Length > 0 {
+		if reqContent.Length > 0 || streaming {
 			options.Method = "POST"
 		} else {
 			options.Method = "GET"
@@ -527,18 +1114,47 @@ func buildRequest(log *logger.Logger, options *Options) (*http.Request, error) {
 	}
 
 	reader := reqContent.Reader()
+	if streaming {
+		reader = stream
+	}
 
+	if reporter, ok := options.ProgressWriter.(*progressReporter); ok {
+		reporter.setTotal(int64(reqContent.Length))
+	}
 	if options.ProgressWriter != nil {
 		reader = &progressReader{
-			Reader:   reqContent.Reader(),
+			Reader:   reader,
 			Progress: options.ProgressWriter,
 		}
 	}
 
+	if options.MaxBytesPerSecond > 0 {
+		reader = newThrottledReader(options.Context, reader, options.MaxBytesPerSecond)
+	}
+
+	var trailer http.Header
+	if len(options.Trailer) > 0 {
+		trailer = options.Trailer.Clone()
+		reader = &trailerReader{Reader: reader, trailer: trailer, fill: options.TrailerFunc}
+	}
+
 	req, err := http.NewRequestWithContext(options.Context, options.Method, options.URL.String(), reader)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	if options.MaxBytesPerSecond > 0 && reqContent.Length > 0 {
+		req.ContentLength = int64(reqContent.Length)
+	}
+	if streaming {
+		req.ContentLength = stream.Length // -1 when unknown, the body is then sent chunked
+	}
+	if options.GetPayload != nil {
+		req.GetBody = options.GetPayload // used when following 307/308 redirects
+	}
+	if trailer != nil {
+		req.Trailer = trailer
+		req.ContentLength = -1 // trailers are sent only after a chunked body
+	}
 
 	// Close indicates to close the connection or after sending this request and reading its response.
 	// setting this field prevents re-use of TCP connections between requests to the same hosts, as if Transport.DisableKeepAlives were set.
@@ -547,31 +1163,189 @@ func buildRequest(log *logger.Logger, options *Options) (*http.Request, error) {
 	// Setting request headers
 	req.Header.Set("User-Agent", options.UserAgent)
 	req.Header.Set("Accept", options.Accept)
-	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Add("Accept-Encoding", "deflate")
+	if !options.DisableCompression {
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Add("Accept-Encoding", "deflate")
+	}
 	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("X-Request-Id", options.RequestID)
-	if len(options.Authorization) > 0 {
+	req.Header.Set(options.RequestIDHeader, options.RequestID)
+	if options.SendTimeoutHint && options.Timeout > 0 {
+		req.Header.Set("X-Request-Timeout", strconv.FormatInt(options.Timeout.Milliseconds(), 10))
+	}
+	if options.TokenProvider != nil {
+		authorization, err := options.TokenProvider.Authorization(options.Context)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get the Authorization from the TokenProvider")
+		}
+		req.Header.Set("Authorization", authorization)
+	} else if len(options.Authorization) > 0 {
 		req.Header.Set("Authorization", options.Authorization)
 	}
+	if options.APIKey != nil {
+		if err := options.APIKey.apply(req); err != nil {
+			return nil, err
+		}
+	}
 	if len(reqContent.Type) > 0 {
 		req.Header.Set("Content-Type", reqContent.Type)
 	}
-	if reqContent.Length > 0 {
-		req.Header.Set("Content-Length", strconv.FormatUint(reqContent.Length, 10))
+	if options.PayloadEncryption != nil && len(reqContent.Data) > 0 {
+		req.Header.Set(ContentEncryptionHeader, options.PayloadEncryption.Algorithm.String())
 	}
-	for key, value := range options.Headers {
-		req.Header.Set(key, value)
+	if reqContent.Length > 0 && trailer == nil {
+		req.Header.Set("Content-Length", strconv.FormatUint(reqContent.Length, 10))
 	}
+	setHeaders(req.Header, options.Headers, options.PreserveHeaderCase)
+	mergeHeader(req.Header, options.Header)
 
 	if len(options.Cookies) > 0 {
 		for _, cookie := range options.Cookies {
 			req.AddCookie(cookie)
 		}
 	}
+	if options.Signer != nil {
+		if err := options.Signer.Sign(req, reqContent.Data); err != nil {
+			return nil, errors.Wrap(err, "Failed to sign the request")
+		}
+	}
 	return req, nil
 }
 
+// setHeaders sets the headers in header
+//
+// If preserveCase is true, the keys are not canonicalized.
+func setHeaders(header http.Header, headers map[string]string, preserveCase bool) {
+	for key, value := range headers {
+		if preserveCase {
+			header.Del(key)
+			header[key] = []string{value}
+		} else {
+			header.Set(key, value)
+		}
+	}
+}
+
+// mergeHeader merges the values of source into header
+//
+// The values of source replace the values of header for the same key, the keys of source keep their casing.
+func mergeHeader(header, source http.Header) {
+	for key, values := range source {
+		header.Del(key)
+		header[key] = append([]string{}, values...)
+	}
+}
+
+// setResponseInfo sets the status, URL, protocol, timing, and connection of the response in the Content
+func setResponseInfo(content *Content, res *http.Response, tracer *timingTracer) {
+	content.StatusCode = res.StatusCode
+	if content.URL == nil && res.Request != nil {
+		content.URL = res.Request.URL
+	}
+	content.Proto = res.Proto
+	content.Timing = tracer.Timing()
+	content.Connection = tracer.Connection()
+	content.ServerTiming = parseServerTiming(res.Header.Values("Server-Timing"))
+	content.Trailer = res.Trailer
+}
+
+// checkResponseHeaders checks the headers contain the required headers
+//
+// A header matches if its value is the required value, optionally followed by parameters (e.g.: "application/json; charset=utf-8" matches "application/json").
+// An empty required value matches any value.
+func checkResponseHeaders(headers http.Header, required map[string]string) error {
+	names := make([]string, 0, len(required))
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, expected := headers.Get(name), required[name]
+		if len(value) == 0 {
+			if len(expected) == 0 {
+				return ResponseHeaderInvalid.With(name, "any value")
+			}
+			return ResponseHeaderInvalid.With(name, expected)
+		}
+		if len(expected) > 0 && value != expected && !strings.HasPrefix(value, expected+";") {
+			return ResponseHeaderInvalid.With(name, expected)
+		}
+	}
+	return nil
+}
+
+// asyncLocation gets the URL to poll for an asynchronous operation from the response headers
+//
+// The headers are checked in this order: Operation-Location, Azure-AsyncOperation, Location.
+// Relative URLs are resolved against the request URL.
+func asyncLocation(headers http.Header, requestURL *url.URL) *url.URL {
+	for _, header := range []string{"Operation-Location", "Azure-AsyncOperation", "Location"} {
+		if value := headers.Get(header); len(value) > 0 {
+			if requestURL == nil {
+				if location, err := url.Parse(value); err == nil && location.IsAbs() {
+					return location
+				}
+				continue
+			}
+			if location, err := requestURL.Parse(value); err == nil {
+				return location
+			}
+		}
+	}
+	return nil
+}
+
+// asyncStatusOptions gets the Options to GET the status of an asynchronous operation at the given location
+func asyncStatusOptions(options *Options, location *url.URL) *Options {
+	statusOptions := options.Clone()
+	statusOptions.Method = http.MethodGet
+	statusOptions.URL = location
+	statusOptions.URLString = ""
+	statusOptions.BaseURL = nil
+	statusOptions.Path = ""
+	statusOptions.PathParameters = nil
+	statusOptions.Parameters = nil
+	statusOptions.QueryValues = nil
+	statusOptions.Payload = nil
+	statusOptions.PayloadType = ""
+	statusOptions.Attachment = nil
+	statusOptions.Attachments = nil
+	statusOptions.ProgressWriter = nil
+	return statusOptions
+}
+
+// joinURL joins a base URL and a relative path, merging their queries
+//
+// The path is always appended to the path of the base URL, whether it starts with a slash or not.
+func joinURL(baseURL *url.URL, path string) *url.URL {
+	path, rawQuery, _ := strings.Cut(path, "?")
+	joined := baseURL.JoinPath(path)
+	if strings.HasSuffix(path, "/") && !strings.HasSuffix(joined.Path, "/") {
+		joined.Path += "/"
+	}
+	if len(rawQuery) > 0 {
+		if len(joined.RawQuery) > 0 {
+			joined.RawQuery += "&" + rawQuery
+		} else {
+			joined.RawQuery = rawQuery
+		}
+	}
+	return joined
+}
+
+// parseURL parses a URL given as a string
+//
+// It returns an errors.InvalidURL error if the URL cannot be parsed or has no scheme.
+func parseURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.WrapErrors(errors.InvalidURL.With(rawURL), err)
+	}
+	if !parsed.IsAbs() {
+		return nil, errors.InvalidURL.With(rawURL)
+	}
+	return parsed, nil
+}
+
 func marshal(payload interface{}) ([]byte, error) {
 	data, err := json.Marshal(payload)
 	if errors.Is(err, errors.JSONMarshalError) {
@@ -0,0 +1,45 @@
+package request
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultRequestIDHeader is the header that carries the RequestID by default
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// requestIDContextKey is the type of the context key used by ContextWithRequestID
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of the context that carries the request ID
+//
+// Send uses it as the RequestID of the requests sent with that context, unless the Options have their own RequestID.
+// This way, a server can propagate the request ID it received to the requests it sends to other services.
+func ContextWithRequestID(parent context.Context, requestID string) context.Context {
+	return context.WithValue(parent, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext gets the request ID carried by the context, see ContextWithRequestID
+func RequestIDFromContext(context context.Context) (string, bool) {
+	return requestIDFromContext(context, nil)
+}
+
+// requestIDFromContext gets the request ID carried by the context with the given key, or the key of ContextWithRequestID if it is nil
+//
+// The value of the key must be a non-empty string or fmt.Stringer (e.g.: uuid.UUID).
+func requestIDFromContext(context context.Context, key interface{}) (string, bool) {
+	if context == nil {
+		return "", false
+	}
+	if key == nil {
+		key = requestIDContextKey{}
+	}
+	var requestID string
+	switch value := context.Value(key).(type) {
+	case string:
+		requestID = value
+	case fmt.Stringer:
+		requestID = value.String()
+	}
+	return requestID, len(requestID) > 0
+}
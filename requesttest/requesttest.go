@@ -0,0 +1,195 @@
+// Package requesttest provides mock HTTP servers to test the clients built with github.com/gildas/go-request
+//
+// The Server answers on these endpoints, with any method:
+//
+//	/echo          responds with an Echo of the request as JSON
+//	/status/{code} responds with the given status code
+//	/retry         responds with X-Return-Status (default: 503) until the X-Attempt header reaches X-Max-Retry (default: 5),
+//	               with a Retry-After header if X-Retry-After is given, then responds with "body"
+//	/timeout       waits for the "delay" query parameter (default: 5s) or until the request is cancelled
+//	/attachment    validates a multipart form with a "file" part and responds with an Attachment as JSON,
+//	               if the "type" query parameter is given, the file must have this Content-Type
+//
+// Any other path gets a 404 with an empty JSON object.
+package requesttest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-core"
+	"github.com/gildas/go-errors"
+)
+
+// Server is a mock HTTP server that counts the requests per path
+type Server struct {
+	*httptest.Server
+	counts map[string]int
+	lock   sync.Mutex
+}
+
+// Echo describes the request received by the /echo endpoint
+type Echo struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   url.Values  `json:"query,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Attachment describes the file received by the /attachment endpoint
+type Attachment struct {
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"contentType"`
+	Size        int64      `json:"size"`
+	Fields      url.Values `json:"fields,omitempty"`
+}
+
+// DefaultTimeoutDelay defines how long the /timeout endpoint waits by default
+const DefaultTimeoutDelay = 5 * time.Second
+
+// NewServer starts a new mock HTTP server
+//
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	server := &Server{counts: map[string]int{}}
+	server.Server = httptest.NewServer(server.handler())
+	return server
+}
+
+// NewTLSServer starts a new mock HTTPS server
+//
+// The caller should call Close when finished, to shut it down.
+// Use the Server's Client() or its Certificate() to trust it.
+func NewTLSServer() *Server {
+	server := &Server{counts: map[string]int{}}
+	server.Server = httptest.NewTLSServer(server.handler())
+	return server
+}
+
+// Endpoint gets the URL of the given path on this Server
+func (server *Server) Endpoint(path string) *url.URL {
+	endpoint, _ := url.Parse(server.URL + path)
+	return endpoint
+}
+
+// Count gets how many requests were received on the given path
+func (server *Server) Count(path string) int {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.counts[path]
+}
+
+// Reset resets the request counters
+func (server *Server) Reset() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.counts = map[string]int{}
+}
+
+func (server *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", echoHandler)
+	mux.HandleFunc("/status/{code}", statusHandler)
+	mux.HandleFunc("/retry", retryHandler)
+	mux.HandleFunc("/timeout", timeoutHandler)
+	mux.HandleFunc("/attachment", attachmentHandler)
+	mux.HandleFunc("/", func(res http.ResponseWriter, req *http.Request) {
+		core.RespondWithJSON(res, http.StatusNotFound, struct{}{})
+	})
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		server.lock.Lock()
+		server.counts[req.URL.Path]++
+		server.lock.Unlock()
+		mux.ServeHTTP(res, req)
+	})
+}
+
+func echoHandler(res http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		core.RespondWithError(res, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+	core.RespondWithJSON(res, http.StatusOK, Echo{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   req.URL.Query(),
+		Headers: req.Header,
+		Body:    string(body),
+	})
+}
+
+func statusHandler(res http.ResponseWriter, req *http.Request) {
+	code, err := strconv.Atoi(req.PathValue("code"))
+	if err != nil || code < 100 || code > 999 {
+		core.RespondWithError(res, http.StatusBadRequest, errors.ArgumentInvalid.With("code", req.PathValue("code")))
+		return
+	}
+	res.WriteHeader(code)
+}
+
+func retryHandler(res http.ResponseWriter, req *http.Request) {
+	max := core.Atoi(req.Header.Get("X-Max-Retry"), 5)
+	attempt := core.Atoi(req.Header.Get("X-Attempt"), 0)
+	if attempt < max { // On the max-th attempt, we want to return 200
+		if retryAfter := req.Header.Get("X-Retry-After"); len(retryAfter) > 0 {
+			res.Header().Set("Retry-After", retryAfter)
+		}
+		res.WriteHeader(core.Atoi(req.Header.Get("X-Return-Status"), http.StatusServiceUnavailable))
+		return
+	}
+	res.Header().Set("Content-Type", "text/plain")
+	_, _ = res.Write([]byte("body"))
+}
+
+func timeoutHandler(res http.ResponseWriter, req *http.Request) {
+	delay := DefaultTimeoutDelay
+	if value := req.URL.Query().Get("delay"); len(value) > 0 {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			core.RespondWithError(res, http.StatusBadRequest, errors.ArgumentInvalid.With("delay", value))
+			return
+		}
+		delay = parsed
+	}
+	select {
+	case <-time.After(delay):
+		res.Header().Set("Content-Type", "text/plain")
+		_, _ = res.Write([]byte("body"))
+	case <-req.Context().Done():
+	}
+}
+
+func attachmentHandler(res http.ResponseWriter, req *http.Request) {
+	if err := req.ParseMultipartForm(1024 * 1024); err != nil {
+		core.RespondWithError(res, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+	file, header, err := req.FormFile("file")
+	if err != nil {
+		core.RespondWithError(res, http.StatusBadRequest, errors.ArgumentMissing.With("file"))
+		return
+	}
+	defer file.Close()
+	if header.Size == 0 {
+		core.RespondWithError(res, http.StatusBadRequest, errors.ArgumentMissing.With("file"))
+		return
+	}
+	if expected := req.URL.Query().Get("type"); len(expected) > 0 && header.Header.Get("Content-Type") != expected {
+		core.RespondWithError(res, http.StatusUnsupportedMediaType, errors.ArgumentInvalid.With("Content-Type", header.Header.Get("Content-Type")))
+		return
+	}
+	core.RespondWithJSON(res, http.StatusOK, Attachment{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+		Fields:      req.MultipartForm.Value,
+	})
+}
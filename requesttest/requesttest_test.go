@@ -0,0 +1,119 @@
+package requesttest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+	"github.com/gildas/go-request/requesttest"
+)
+
+func TestCanEcho(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	echo := requesttest.Echo{}
+	_, err := request.Send(&request.Options{
+		URL:     server.Endpoint("/echo?page=2"),
+		Payload: request.ContentWithData([]byte("hello"), "text/plain"),
+		Headers: map[string]string{"X-Custom": "value"},
+	}, &echo)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, echo.Method)
+	assert.Equal(t, "/echo", echo.Path)
+	assert.Equal(t, "2", echo.Query.Get("page"))
+	assert.Equal(t, "value", echo.Headers.Get("X-Custom"))
+	assert.Equal(t, "hello", echo.Body)
+	assert.Equal(t, 1, server.Count("/echo"))
+}
+
+func TestCanRespondWithStatus(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	content, err := request.Send(&request.Options{URL: server.Endpoint("/status/418"), Attempts: 1}, nil)
+	require.Error(t, err)
+	require.NotNil(t, content)
+	assert.Equal(t, http.StatusTeapot, content.StatusCode)
+
+	_, err = request.Send(&request.Options{URL: server.Endpoint("/not-found"), Attempts: 1}, nil)
+	assert.ErrorIs(t, err, errors.HTTPNotFound)
+}
+
+func TestCanRetry(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	content, err := request.Send(&request.Options{
+		URL:                       server.Endpoint("/retry"),
+		Headers:                   map[string]string{"X-Max-Retry": "2", "X-Retry-After": "0"},
+		InterAttemptUseRetryAfter: true,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(content.Data))
+	assert.Equal(t, 2, server.Count("/retry"))
+
+	server.Reset()
+	assert.Equal(t, 0, server.Count("/retry"))
+}
+
+func TestCanTimeout(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	_, err := request.Send(&request.Options{
+		URL:      server.Endpoint("/timeout?delay=1s"),
+		Timeout:  100 * time.Millisecond,
+		Attempts: 1,
+	}, nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errors.HTTPStatusRequestTimeout)
+
+	content, err := request.Send(&request.Options{URL: server.Endpoint("/timeout?delay=10ms")}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(content.Data))
+}
+
+func TestCanValidateAttachment(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+
+	attachment := requesttest.Attachment{}
+	_, err := request.Send(&request.Options{
+		URL:            server.Endpoint("/attachment?type=text/plain"),
+		Payload:        map[string]string{"ID": "1234", ">file": "hello.txt"},
+		Attachment:     strings.NewReader("Hello, World!"),
+		AttachmentType: "text/plain",
+	}, &attachment)
+	require.NoError(t, err)
+	assert.Equal(t, "hello.txt", attachment.Filename)
+	assert.Equal(t, "text/plain", attachment.ContentType)
+	assert.Equal(t, int64(13), attachment.Size)
+	assert.Equal(t, "1234", attachment.Fields.Get("ID"))
+
+	_, err = request.Send(&request.Options{
+		URL:            server.Endpoint("/attachment?type=image/png"),
+		Payload:        map[string]string{">file": "hello.txt"},
+		Attachment:     strings.NewReader("Hello, World!"),
+		AttachmentType: "text/plain",
+		Attempts:       1,
+	}, nil)
+	assert.ErrorIs(t, err, errors.HTTPStatusUnsupportedMediaType)
+}
+
+func TestCanStartTLSServer(t *testing.T) {
+	server := requesttest.NewTLSServer()
+	defer server.Close()
+
+	_, err := request.Send(&request.Options{
+		URL:                server.Endpoint("/status/204"),
+		InsecureSkipVerify: true,
+	}, nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,159 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// RetryableErrorClassifier tells if an error returned while sending a request can be retried
+type RetryableErrorClassifier interface {
+	IsRetryable(err error) bool
+}
+
+// RetryableErrorClassifierFunc is a function that implements RetryableErrorClassifier
+type RetryableErrorClassifierFunc func(err error) bool
+
+// RetryableErrorClassifiers is a RetryableErrorClassifier that retries an error if any of its classifiers does
+//
+// Use it to extend the default set:
+//
+//	options.RetryableErrors = request.RetryableErrorClassifiers{request.DefaultRetryableErrorClassifier, myClassifier}
+type RetryableErrorClassifiers []RetryableErrorClassifier
+
+// DefaultRetryableErrorClassifier retries connection resets, refusals, and aborts, broken pipes,
+// temporary DNS failures, timeouts (including TLS handshake timeouts), and unexpected EOFs
+var DefaultRetryableErrorClassifier RetryableErrorClassifier = RetryableErrorClassifierFunc(isRetryableError)
+
+// IsRetryable tells if the error can be retried
+func (classifier RetryableErrorClassifierFunc) IsRetryable(err error) bool {
+	return classifier(err)
+}
+
+// IsRetryable tells if the error can be retried
+func (classifiers RetryableErrorClassifiers) IsRetryable(err error) bool {
+	for _, classifier := range classifiers {
+		if classifier != nil && classifier.IsRetryable(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	dnsErr := &net.DNSError{}
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	urlErr := &url.Error{}
+	if errors.As(err, &urlErr) {
+		// TLS handshake timeouts are reported as url.Error timeouts
+		return urlErr.Timeout() || urlErr.Temporary() || urlErr.Unwrap() == io.EOF
+	}
+	return false
+}
+
+// isIdempotentRequest tells if the request can be sent again without duplicating its side effects
+//
+// POST, PATCH, and other non-idempotent requests can be sent again if they carry an Idempotency-Key header.
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	for key, values := range req.Header {
+		// the key is not canonical when Options.PreserveHeaderCase is set
+		if strings.EqualFold(key, "Idempotency-Key") && len(values) > 0 && len(values[0]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeliveryAmbiguous tells if the request might have reached the server despite the error
+//
+// Only DNS failures and connections that could not be established guarantee the request was not delivered.
+func isDeliveryAmbiguous(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return false
+	}
+	dnsErr := &net.DNSError{}
+	if errors.As(err, &dnsErr) {
+		return false
+	}
+	opErr := &net.OpError{}
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	return true
+}
+
+// AttemptInfo describes an attempt to send a request, it is given to Options.OnAttempt
+//
+// OnAttempt is called before each attempt (Done is false) and after it (Done is true).
+type AttemptInfo struct {
+	Attempt    uint          // the attempt number, starting at 1
+	Attempts   uint          // the maximum number of attempts
+	Done       bool          // true once the attempt is over
+	StatusCode int           // the status of the response, 0 if none was received
+	Error      error         // the error of the attempt, if any
+	Duration   time.Duration // how long the attempt took
+	Delay      time.Duration // how long Send waits before the next attempt, 0 if there is none
+
+	Connection *ConnectionInfo // the connection the attempt was sent on, nil if none was obtained
+}
+
+// checkRetryBudget checks the next attempt can start before the deadline of the context, if any
+//
+// returns RetryBudgetExceeded when waiting for the delay would go past the deadline.
+func checkRetryBudget(ctx context.Context, delay time.Duration) error {
+	if ctx == nil {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); delay >= left {
+			return RetryBudgetExceeded.With(delay.String(), left.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// parseRetryAfter parses the Retry-After header, which can be a number of seconds or an HTTP date
+//
+// returns false if the header is missing or invalid
+func parseRetryAfter(headers http.Header) (time.Duration, bool) {
+	value := strings.TrimSpace(headers.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,52 @@
+package request_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gildas/go-request"
+)
+
+func TestDefaultRetryableErrorClassifier(t *testing.T) {
+	retryables := []error{
+		&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+		&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+		&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNABORTED)},
+		&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)},
+		&url.Error{Op: "Get", URL: "http://localhost", Err: &net.DNSError{Err: "server misbehaving", Name: "localhost", IsTemporary: true}},
+		&url.Error{Op: "Get", URL: "http://localhost", Err: io.EOF},
+		&url.Error{Op: "Get", URL: "http://localhost", Err: context.DeadlineExceeded},
+	}
+	for _, err := range retryables {
+		assert.True(t, request.DefaultRetryableErrorClassifier.IsRetryable(err), "Error should be retryable: %s", err)
+	}
+
+	permanents := []error{
+		nil,
+		errors.New("not retryable"),
+		&url.Error{Op: "Get", URL: "http://localhost", Err: &net.DNSError{Err: "no such host", Name: "localhost", IsNotFound: true}},
+		&url.Error{Op: "Get", URL: "http://localhost", Err: errors.New("unsupported protocol scheme")},
+	}
+	for _, err := range permanents {
+		assert.False(t, request.DefaultRetryableErrorClassifier.IsRetryable(err), "Error should not be retryable: %v", err)
+	}
+}
+
+func TestCanExtendRetryableErrorClassifiers(t *testing.T) {
+	custom := errors.New("custom error")
+	classifier := request.RetryableErrorClassifiers{
+		request.DefaultRetryableErrorClassifier,
+		request.RetryableErrorClassifierFunc(func(err error) bool { return errors.Is(err, custom) }),
+	}
+	assert.True(t, classifier.IsRetryable(custom), "Custom error should be retryable")
+	assert.True(t, classifier.IsRetryable(syscall.ECONNRESET), "ECONNRESET should be retryable")
+	assert.False(t, classifier.IsRetryable(errors.New("not retryable")), "Error should not be retryable")
+}
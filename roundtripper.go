@@ -0,0 +1,120 @@
+package request
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// roundTripper sends requests with Send and the default Options it was created with
+type roundTripper struct {
+	defaults Options
+}
+
+// NewRoundTripper creates an http.RoundTripper that sends requests with Send
+//
+// The retry, backoff, logging, and authorization behaviors of the defaults are applied to every request,
+// so they can be used by third-party SDKs that only accept an *http.Client:
+//
+//	client := &http.Client{Transport: request.NewRoundTripper(&request.Options{Attempts: 3, Logger: log})}
+//
+// The method, URL, headers, body, and context of the requests override the defaults.
+// The response bodies are read entirely by Send before they are returned.
+// Responses with an error status are returned as responses, not as errors.
+func NewRoundTripper(defaults *Options) http.RoundTripper {
+	tripper := &roundTripper{}
+	if defaults != nil {
+		tripper.defaults = *defaults
+	}
+	if tripper.defaults.Transport == nil {
+		// share the connections among the requests
+		tripper.defaults.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return tripper
+}
+
+// RoundTrip executes a single HTTP transaction
+//
+// implements http.RoundTripper
+func (tripper roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	options := tripper.defaults
+	options.Method = req.Method
+	options.URL = req.URL
+	options.BaseURL = nil
+	options.Context = req.Context()
+	options.Headers = map[string]string{}
+	for key, value := range tripper.defaults.Headers {
+		options.Headers[key] = value
+	}
+	options.Header = http.Header{}
+	mergeHeader(options.Header, tripper.defaults.Header)
+	requestIDHeader := tripper.defaults.RequestIDHeader
+	if len(requestIDHeader) == 0 {
+		requestIDHeader = DefaultRequestIDHeader
+	}
+	for key, values := range req.Header {
+		if key == http.CanonicalHeaderKey(requestIDHeader) {
+			options.RequestID = values[0]
+			continue
+		}
+		switch key {
+		case "Accept":
+			options.Accept = strings.Join(values, ", ")
+		case "User-Agent":
+			options.UserAgent = values[0]
+		case "Authorization":
+			options.Authorization = values[0]
+		case "Cookie":
+			options.Headers[key] = strings.Join(values, "; ")
+		default:
+			options.Header[key] = append([]string{}, values...)
+		}
+	}
+	options.Payload = nil
+	options.PayloadType = ""
+	options.Attachment = nil
+	options.Attachments = nil
+	if req.Body != nil && req.Body != http.NoBody {
+		payload, err := ContentFromReader(req.Body, req.Header.Get("Content-Type"))
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(payload.Type) == 0 {
+			payload.Type = "application/octet-stream"
+		}
+		options.Payload = payload
+	}
+
+	content, err := Send(&options, nil)
+	if err != nil && (content == nil || content.StatusCode < 400) {
+		return nil, err
+	}
+	// the response body is already uncompressed, see ContentWithData, unless compression is disabled
+	header := content.Headers.Clone()
+	uncompressed := false
+	if header.Get("Content-Encoding") == "gzip" && !options.DisableCompression {
+		header.Del("Content-Encoding")
+		uncompressed = true
+	}
+	header.Set("Content-Length", strconv.Itoa(len(content.Data)))
+	proto, major, minor := content.Proto, 1, 1
+	if parsedMajor, parsedMinor, ok := http.ParseHTTPVersion(proto); ok {
+		major, minor = parsedMajor, parsedMinor
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(content.StatusCode) + " " + http.StatusText(content.StatusCode),
+		StatusCode:    content.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          io.NopCloser(content.Reader()),
+		ContentLength: int64(len(content.Data)),
+		Uncompressed:  uncompressed,
+		Request:       req,
+	}, nil
+}
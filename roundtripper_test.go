@@ -0,0 +1,74 @@
+package request_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+	"github.com/gildas/go-request/requesttest"
+)
+
+func TestCanUseRoundTripper(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+	client := &http.Client{Transport: request.NewRoundTripper(&request.Options{
+		Headers: map[string]string{"X-Default": "default"},
+	})}
+
+	req, err := http.NewRequest(http.MethodPut, server.Endpoint("/echo?page=2").String(), bytes.NewReader([]byte(`{"ID":"1234"}`)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer ThisIsAToken")
+	req.Header.Add("Forwarded", "for=192.0.2.60")
+	req.Header.Add("Forwarded", "for=198.51.100.17")
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "200 OK", res.Status)
+
+	echo := requesttest.Echo{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&echo))
+	assert.Equal(t, http.MethodPut, echo.Method)
+	assert.Equal(t, "2", echo.Query.Get("page"))
+	assert.Equal(t, `{"ID":"1234"}`, echo.Body)
+	assert.Equal(t, "application/json", echo.Headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer ThisIsAToken", echo.Headers.Get("Authorization"))
+	assert.Equal(t, "default", echo.Headers.Get("X-Default"))
+	assert.Equal(t, []string{"for=192.0.2.60", "for=198.51.100.17"}, echo.Headers.Values("Forwarded"), "Repeated headers should be kept")
+}
+
+func TestCanRetryWithRoundTripper(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+	client := &http.Client{Transport: request.NewRoundTripper(&request.Options{
+		InterAttemptUseRetryAfter: true,
+	})}
+
+	req, _ := http.NewRequest(http.MethodGet, server.Endpoint("/retry").String(), nil)
+	req.Header.Set("X-Max-Retry", "2")
+	req.Header.Set("X-Retry-After", "0")
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, "body", string(body))
+	assert.Equal(t, 2, server.Count("/retry"))
+}
+
+func TestCanGetErrorStatusWithRoundTripper(t *testing.T) {
+	server := requesttest.NewServer()
+	defer server.Close()
+	client := &http.Client{Transport: request.NewRoundTripper(nil)}
+
+	res, err := client.Get(server.Endpoint("/status/404").String())
+	require.NoError(t, err, "Error statuses should be returned as responses")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,142 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// S3PostPolicy describes a browser-style upload to an S3 bucket with a POST policy
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+type S3PostPolicy struct {
+	Bucket     string        // name of the bucket
+	Key        string        // key of the object, "${filename}" is replaced by S3 with the name of the uploaded file
+	Expiration time.Time     // when the policy expires, by default: 1 hour after it is signed
+	Fields     []FormField   // other fields to send (e.g.: acl, Content-Type, success_action_status, x-amz-meta-*), each one is added to the conditions
+	Conditions []interface{} // other conditions (e.g.: []interface{}{"content-length-range", 0, 10485760})
+}
+
+// PostPolicyFields gets the form fields of an S3 POST upload with the given policy, signed at the given time
+//
+// The fields are: key, the Fields of the policy, x-amz-algorithm, x-amz-credential, x-amz-date, x-amz-security-token (if any), policy, and x-amz-signature.
+// The file must be sent after them.
+func (signer AWSSigV4Signer) PostPolicyFields(policy S3PostPolicy, at time.Time) ([]FormField, error) {
+	if len(signer.AccessKeyID) == 0 {
+		return nil, errors.ArgumentMissing.With("AccessKeyID")
+	}
+	if len(signer.SecretAccessKey) == 0 {
+		return nil, errors.ArgumentMissing.With("SecretAccessKey")
+	}
+	if len(signer.Region) == 0 {
+		return nil, errors.ArgumentMissing.With("Region")
+	}
+	if len(policy.Bucket) == 0 {
+		return nil, errors.ArgumentMissing.With("Bucket")
+	}
+	if len(policy.Key) == 0 {
+		return nil, errors.ArgumentMissing.With("Key")
+	}
+	at = at.UTC()
+	amzDate := at.Format("20060102T150405Z")
+	date := at.Format("20060102")
+	credential := signer.AccessKeyID + "/" + date + "/" + signer.Region + "/s3/aws4_request"
+	expiration := policy.Expiration
+	if expiration.IsZero() {
+		expiration = at.Add(1 * time.Hour)
+	}
+
+	fields := []FormField{{Name: "key", Value: policy.Key}}
+	fields = append(fields, policy.Fields...)
+	fields = append(fields,
+		FormField{Name: "x-amz-algorithm", Value: awsSigV4Algorithm},
+		FormField{Name: "x-amz-credential", Value: credential},
+		FormField{Name: "x-amz-date", Value: amzDate},
+	)
+	if len(signer.SessionToken) > 0 {
+		fields = append(fields, FormField{Name: "x-amz-security-token", Value: signer.SessionToken})
+	}
+
+	conditions := []interface{}{map[string]string{"bucket": policy.Bucket}}
+	if prefix, _, found := strings.Cut(policy.Key, "${filename}"); found {
+		conditions = append(conditions, []string{"starts-with", "$key", prefix})
+	} else {
+		conditions = append(conditions, map[string]string{"key": policy.Key})
+	}
+	for _, field := range fields[1:] {
+		conditions = append(conditions, map[string]string{field.Name: field.Value})
+	}
+	conditions = append(conditions, policy.Conditions...)
+	document, err := json.Marshal(struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{
+		Expiration: expiration.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Conditions: conditions,
+	})
+	if err != nil {
+		return nil, errors.JSONMarshalError.Wrap(err)
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(document)
+
+	key := awsHMAC([]byte("AWS4"+signer.SecretAccessKey), date)
+	key = awsHMAC(key, signer.Region)
+	key = awsHMAC(key, "s3")
+	key = awsHMAC(key, "aws4_request")
+	signature := hex.EncodeToString(awsHMAC(key, encodedPolicy))
+
+	return append(fields,
+		FormField{Name: "policy", Value: encodedPolicy},
+		FormField{Name: "x-amz-signature", Value: signature},
+	), nil
+}
+
+// SendS3PostUpload uploads a file to an S3 bucket with a POST policy signed by the signer, like a browser would
+//
+// If the options have no URL, the file is sent to https://<bucket>.s3.<region>.amazonaws.com/.
+// The field name of the file is always "file", if the file has a ContentType it is sent in the Content-Type field unless the policy has one.
+//
+// S3 responds with 204 No Content, unless the policy has a success_action_status or a success_action_redirect field.
+func SendS3PostUpload(options *Options, signer *AWSSigV4Signer, policy S3PostPolicy, file Attachment) (*Content, error) {
+	if options == nil {
+		return nil, errors.ArgumentMissing.With("options")
+	}
+	if signer == nil {
+		return nil, errors.ArgumentMissing.With("signer")
+	}
+	if file.Reader == nil {
+		return nil, errors.ArgumentMissing.With("file")
+	}
+	if len(file.ContentType) > 0 {
+		hasContentType := false
+		for _, field := range policy.Fields {
+			hasContentType = hasContentType || strings.EqualFold(field.Name, "Content-Type")
+		}
+		if !hasContentType {
+			policy.Fields = append(append([]FormField{}, policy.Fields...), FormField{Name: "Content-Type", Value: file.ContentType})
+		}
+	}
+	fields, err := signer.PostPolicyFields(policy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	file.FieldName = "file"
+	uploadOptions := options.Clone()
+	if uploadOptions.URL == nil && len(uploadOptions.URLString) == 0 && uploadOptions.BaseURL == nil {
+		uploadOptions.URL = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", policy.Bucket, signer.Region), Path: "/"}
+	}
+	uploadOptions.Method = http.MethodPost
+	uploadOptions.Payload = fields
+	uploadOptions.PayloadType = ""
+	uploadOptions.Attachment = nil
+	uploadOptions.Attachments = []Attachment{file}
+	uploadOptions.Signer = nil // the policy is signed, not the request
+	return Send(uploadOptions, nil)
+}
@@ -0,0 +1,131 @@
+package request
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// Scheduler limits the number of requests sent at once, and dispatches the waiting requests by priority
+//
+// Requests with a higher Options.Priority are sent first, requests with the same priority are sent in the order they were given.
+// A request holds its slot until Send returns, including its retries.
+//
+// The same Scheduler is given to the Options of all the requests that should share the concurrency cap.
+type Scheduler struct {
+	MaxConcurrent int // the maximum number of requests sent at once, no limit if 0
+
+	running int
+	waiting schedulerQueue
+	next    uint64
+	lock    sync.Mutex
+}
+
+// schedulerWaiter is a request waiting for a slot
+type schedulerWaiter struct {
+	priority int
+	order    uint64
+	ready    chan struct{}
+	index    int
+}
+
+// schedulerQueue is a priority queue of waiting requests
+//
+// implements heap.Interface
+type schedulerQueue []*schedulerWaiter
+
+// NewScheduler instantiates a new Scheduler that sends at most maxConcurrent requests at once
+func NewScheduler(maxConcurrent int) *Scheduler {
+	return &Scheduler{MaxConcurrent: maxConcurrent}
+}
+
+// Running gets the number of requests currently sent
+func (scheduler *Scheduler) Running() int {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	return scheduler.running
+}
+
+// Waiting gets the number of requests waiting for a slot
+func (scheduler *Scheduler) Waiting() int {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	return len(scheduler.waiting)
+}
+
+// acquire waits for a slot, or until the context is done
+func (scheduler *Scheduler) acquire(ctx context.Context, priority int) error {
+	scheduler.lock.Lock()
+	if scheduler.MaxConcurrent <= 0 || (scheduler.running < scheduler.MaxConcurrent && len(scheduler.waiting) == 0) {
+		scheduler.running++
+		scheduler.lock.Unlock()
+		return nil
+	}
+	waiter := &schedulerWaiter{priority: priority, order: scheduler.next, ready: make(chan struct{})}
+	scheduler.next++
+	heap.Push(&scheduler.waiting, waiter)
+	scheduler.lock.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		scheduler.lock.Lock()
+		if waiter.index >= 0 {
+			heap.Remove(&scheduler.waiting, waiter.index)
+			scheduler.lock.Unlock()
+			return errors.WithStack(ctx.Err())
+		}
+		scheduler.lock.Unlock()
+		// the slot was given to us while the context was done
+		scheduler.release()
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// release gives the slot to the waiting request with the highest priority, if any
+func (scheduler *Scheduler) release() {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	if len(scheduler.waiting) > 0 {
+		waiter := heap.Pop(&scheduler.waiting).(*schedulerWaiter)
+		close(waiter.ready)
+		return
+	}
+	scheduler.running--
+}
+
+func (queue schedulerQueue) Len() int { return len(queue) }
+
+func (queue schedulerQueue) Less(i, j int) bool {
+	if queue[i].priority != queue[j].priority {
+		return queue[i].priority > queue[j].priority
+	}
+	return queue[i].order < queue[j].order
+}
+
+func (queue schedulerQueue) Swap(i, j int) {
+	queue[i], queue[j] = queue[j], queue[i]
+	queue[i].index = i
+	queue[j].index = j
+}
+
+func (queue *schedulerQueue) Push(item interface{}) {
+	waiter := item.(*schedulerWaiter)
+	waiter.index = len(*queue)
+	*queue = append(*queue, waiter)
+}
+
+func (queue *schedulerQueue) Pop() interface{} {
+	old := *queue
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	waiter.index = -1
+	*queue = old[:len(old)-1]
+	return waiter
+}
@@ -0,0 +1,132 @@
+package request_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-request"
+)
+
+func (suite *RequestSuite) TestCanLimitConcurrentRequestsWithScheduler() {
+	running := atomic.Int32{}
+	maxRunning := atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			previous := maxRunning.Load()
+			if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+	scheduler := request.NewScheduler(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serverURL, _ := url.Parse(server.URL)
+			_, err := request.Send(&request.Options{
+				URL:       serverURL,
+				Scheduler: scheduler,
+				Logger:    suite.Logger,
+			}, nil)
+			suite.Assert().NoError(err, "Failed sending request, err=%+v", err)
+		}()
+	}
+	wg.Wait()
+	suite.Assert().Equal(int32(2), maxRunning.Load(), "The server should have received at most 2 requests at once")
+	suite.Assert().Equal(0, scheduler.Running(), "No request should be running")
+	suite.Assert().Equal(0, scheduler.Waiting(), "No request should be waiting")
+}
+
+func (suite *RequestSuite) TestCanSendRequestsByPriorityWithScheduler() {
+	release := make(chan struct{})
+	lock := sync.Mutex{}
+	received := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "blocker" {
+			<-release
+		}
+		lock.Lock()
+		received = append(received, name)
+		lock.Unlock()
+	}))
+	defer server.Close()
+	scheduler := request.NewScheduler(1)
+
+	send := func(name string, priority int, wg *sync.WaitGroup) {
+		defer wg.Done()
+		serverURL, _ := url.Parse(server.URL)
+		_, err := request.Send(&request.Options{
+			URL:        serverURL,
+			Parameters: map[string]string{"name": name},
+			Scheduler:  scheduler,
+			Priority:   priority,
+			Logger:     suite.Logger,
+		}, nil)
+		suite.Assert().NoError(err, "Failed sending request %s, err=%+v", name, err)
+	}
+	waitFor := func(waiting int) {
+		suite.Require().Eventually(func() bool { return scheduler.Waiting() == waiting }, 2*time.Second, 10*time.Millisecond, "%d requests should be waiting", waiting)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go send("blocker", 0, &wg)
+	suite.Require().Eventually(func() bool { return scheduler.Running() == 1 }, 2*time.Second, 10*time.Millisecond, "The blocker should be running")
+	go send("bulk-1", -1, &wg)
+	waitFor(1)
+	go send("bulk-2", -1, &wg)
+	waitFor(2)
+	go send("interactive", 10, &wg)
+	waitFor(3)
+	close(release)
+	wg.Wait()
+	suite.Assert().Equal([]string{"blocker", "interactive", "bulk-1", "bulk-2"}, received)
+}
+
+func (suite *RequestSuite) TestShouldFailWaitingForSchedulerWhenContextIsDone() {
+	scheduler := request.NewScheduler(1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serverURL, _ := url.Parse(server.URL)
+		_, _ = request.Send(&request.Options{URL: serverURL, Scheduler: scheduler, Logger: suite.Logger}, nil)
+	}()
+	suite.Require().Eventually(func() bool { return scheduler.Running() == 1 }, 2*time.Second, 10*time.Millisecond, "The first request should be running")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	serverURL, _ := url.Parse(server.URL)
+	_, err := request.Send(&request.Options{
+		Context:   ctx,
+		URL:       serverURL,
+		Scheduler: scheduler,
+		Logger:    suite.Logger,
+	}, nil)
+	suite.Require().Error(err, "Send should have failed")
+	suite.Assert().ErrorIs(err, context.DeadlineExceeded, "error should be a context deadline, error: %+v", err)
+	suite.Assert().Equal(0, scheduler.Waiting(), "No request should be waiting")
+
+	close(release)
+	wg.Wait()
+	suite.Assert().Equal(0, scheduler.Running(), "No request should be running")
+}
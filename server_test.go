@@ -1,14 +1,20 @@
 package request_test
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -17,6 +23,9 @@ import (
 	"github.com/gildas/go-request"
 )
 
+// requestBodies stores the request bodies per request identifier
+var requestBodies sync.Map
+
 func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.HandlerFunc {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		log := suite.Logger.Child("server", "handler")
@@ -74,6 +83,51 @@ func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.
 				if _, err := res.Write([]byte(fmt.Sprintf("%d", len(items)))); err != nil {
 					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
 				}
+			case "/form":
+				if err := req.ParseForm(); err != nil {
+					log.Errorf("Failed to parse request as a form", err)
+					core.RespondWithError(res, http.StatusBadRequest, err)
+					return
+				}
+				log.Infof("POST Form: %+#v", req.PostForm)
+				if _, err := res.Write([]byte(req.PostForm.Encode())); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/batch":
+				reqContent, err := request.ContentFromReader(req.Body, req.Header.Get("Content-Type"))
+				if err != nil {
+					log.Errorf("Failed to read request content", err)
+					core.RespondWithError(res, http.StatusBadRequest, err)
+					return
+				}
+				parts, err := reqContent.Parts()
+				if err != nil {
+					log.Errorf("Failed to read batch parts", err)
+					core.RespondWithError(res, http.StatusBadRequest, err)
+					return
+				}
+				body := &bytes.Buffer{}
+				writer := multipart.NewWriter(body)
+				for _, part := range parts {
+					batchReq, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(part.Data)))
+					if err != nil {
+						log.Errorf("Failed to read batch request", err)
+						core.RespondWithError(res, http.StatusBadRequest, err)
+						return
+					}
+					log.Infof("Batch Request: %s %s", batchReq.Method, batchReq.URL)
+					partWriter, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/http"}})
+					if batchReq.Method == http.MethodGet && batchReq.URL.Path == "/results" {
+						_, _ = partWriter.Write([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"code\": 1234}"))
+					} else {
+						_, _ = partWriter.Write([]byte("HTTP/1.1 404 Not Found\r\nContent-Type: application/json\r\n\r\n{}"))
+					}
+				}
+				_ = writer.Close()
+				res.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+				if _, err := res.Write(body.Bytes()); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
 			case "/bytes":
 				reqContent, err := request.ContentFromReader(req.Body, req.Header.Get("Content-Type"))
 				if err != nil {
@@ -203,6 +257,31 @@ func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.
 					res.WriteHeader(returnStatus)
 					return
 				}
+			case "/async":
+				res.Header().Set("Location", "/async/status?step=1")
+				res.Header().Set("Retry-After", "0")
+				res.WriteHeader(http.StatusAccepted)
+			case "/retry_boundary":
+				max := core.Atoi(req.Header.Get("X-Max-Retry"), 5)
+				attempt := core.Atoi(req.Header.Get("X-Attempt"), 0)
+				body, err := io.ReadAll(req.Body)
+				if err != nil {
+					log.Errorf("Failed to read request content", err)
+					core.RespondWithError(res, http.StatusBadRequest, err)
+					return
+				}
+				if previous, found := requestBodies.LoadOrStore(req.Header.Get("X-Request-Id"), string(body)); found && previous.(string) != string(body) {
+					log.Errorf("Attempt %d sent a different body", attempt)
+					res.WriteHeader(http.StatusConflict)
+					return
+				}
+				if attempt < max { // On the max-th attempt, we want to return 200
+					res.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if _, err := res.Write([]byte("body")); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
 			case "/timeout":
 				time.Sleep(5 * time.Second)
 			default:
@@ -260,6 +339,13 @@ func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.
 				if _, err := res.Write([]byte(``)); err != nil {
 					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
 				}
+			case "/many_headers":
+				for i := 0; i < 20; i++ {
+					res.Header().Add(fmt.Sprintf("X-Header-%02d", i), strings.Repeat("x", 64))
+				}
+				if _, err := res.Write([]byte(`body`)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
 			case "/binary_data":
 				res.Header().Add("Custom-Header", "custom-value")
 				res.Header().Add("Content-Type", "application/octet-stream") // we want to force the content type
@@ -295,6 +381,35 @@ func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.
 				if _, err := res.Write([]byte("body")); err != nil {
 					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
 				}
+			case "/problem":
+				res.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+				res.WriteHeader(http.StatusForbidden)
+				if _, err := res.Write([]byte(`{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/server-timing":
+				res.Header().Set("Content-Type", "text/plain")
+				res.Header().Add("Server-Timing", `cache;desc="Cache Read";dur=23.2, db;dur=53`)
+				res.Header().Add("Server-Timing", "miss")
+				if _, err := res.Write([]byte(req.Header.Get("X-Request-Timeout"))); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/apikey":
+				found := ""
+				if req.Header.Get("X-Api-Key") == "ThisIsAKey" {
+					found = "header"
+				} else if req.URL.Query().Get("api_key") == "ThisIsAKey" {
+					found = "query"
+				} else if cookie, err := req.Cookie("api_key"); err == nil && cookie.Value == "ThisIsAKey" {
+					found = "cookie"
+				}
+				if len(found) == 0 {
+					res.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if _, err := res.Write([]byte(found)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
 			case "/retry":
 				max := core.Atoi(req.Header.Get("X-Max-Retry"), 5)
 				attempt := core.Atoi(req.Header.Get("X-Attempt"), 0)
@@ -333,6 +448,50 @@ func CreateTestServerHandler(suite *RequestSuite, server *httptest.Server) http.
 				if _, err := res.Write([]byte(`{"code": 1234}`)); err != nil {
 					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
 				}
+			case "/users/1234/orders/a/b", "/files/dir/sub dir/file.txt":
+				if _, err := res.Write([]byte(req.URL.EscapedPath())); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/async/status":
+				step := core.Atoi(req.URL.Query().Get("step"), 0)
+				if step < 3 {
+					res.Header().Set("Location", fmt.Sprintf("/async/status?step=%d", step+1))
+					res.Header().Set("Retry-After", "0")
+					res.WriteHeader(http.StatusAccepted)
+					return
+				}
+				res.Header().Set("Content-Type", "application/json")
+				if _, err := res.Write([]byte(`{"code": 1234}`)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/query":
+				if _, err := res.Write([]byte(req.URL.RawQuery)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/results_bom":
+				res.Header().Add("Content-Type", "text/json")
+				if _, err := res.Write([]byte("\xEF\xBB\xBF  \n{\"code\": 1234}")); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/results_notype":
+				res.Header()["Content-Type"] = nil // prevents the server from sniffing the content type
+				if _, err := res.Write([]byte(`{"code": 1234}`)); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/checksum":
+				res.Header().Set("Content-Type", "text/plain")
+				res.Header().Set("Content-MD5", "hBotaJrYa9FhFEdFPCLG/A==")
+				res.Header().Set("x-amz-checksum-sha256", "Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU=")
+				res.Header().Set("Digest", "sha-256=Iw2DWNyOiJC0xY3utikS7i8gNXrpKlzIYbmOaP4xrLU=,unknown=1234")
+				if _, err := res.Write([]byte("body")); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
+			case "/checksum_mismatch":
+				res.Header().Set("Content-Type", "text/plain")
+				res.Header().Set("Content-MD5", "hBotaJrYa9FhFEdFPCLG/A==")
+				if _, err := res.Write([]byte("corrupted body")); err != nil {
+					log.Errorf("Failed to Write response to %s %s, error: %s", req.Method, req.URL, err)
+				}
 			case "/timeout":
 				time.Sleep(5 * time.Second)
 			default:
@@ -387,6 +546,13 @@ func CreateTestServer(suite *RequestSuite) *httptest.Server {
 	return testserver
 }
 
+func CreateTestTLSServer(suite *RequestSuite) *httptest.Server {
+	testserver := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	testserver.Config = &http.Server{Handler: CreateTestServerHandler(suite, testserver)}
+	testserver.StartTLS()
+	return testserver
+}
+
 type EConnResetListener struct {
 	net.Listener
 	MaxResets int
@@ -442,3 +608,65 @@ func CreateEConnAbortedTestServer(suite *RequestSuite, failAttempts int) *httpte
 		_, _ = w.Write([]byte("OK"))
 	}))
 }
+
+// CreateHedgeTestServer creates a server whose first request is slow and all others are fast
+func CreateHedgeTestServer(suite *RequestSuite, slow time.Duration, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if requests.Add(1) == 1 {
+			select {
+			case <-time.After(slow):
+			case <-req.Context().Done():
+				suite.Logger.Infof("Slow request was cancelled")
+				return
+			}
+		}
+		res.Header().Add("Content-Type", "text/plain")
+		_, _ = res.Write([]byte("body"))
+	}))
+}
+
+// CreateRangeTestServer creates a server that serves the data, with byte ranges if acceptRanges is true
+func CreateRangeTestServer(data []byte, acceptRanges bool, requests *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		if !acceptRanges {
+			req.Header.Del("Range")
+			res.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			if req.Method != http.MethodHead {
+				_, _ = res.Write(data)
+			}
+			return
+		}
+		http.ServeContent(res, req, "data.bin", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+// CreateEncryptionTestServer creates a server that decrypts the payload with the key and responds with its uppercase version encrypted with the key
+func CreateEncryptionTestServer(suite *RequestSuite, key []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		reqContent, err := request.ContentFromReader(req.Body, req.Header.Get("Content-Type"), req.Header)
+		if err != nil {
+			core.RespondWithError(res, http.StatusBadRequest, err)
+			return
+		}
+		algorithm, err := request.CryptoAlgorithmFromString(req.Header.Get(request.ContentEncryptionHeader))
+		if err != nil {
+			core.RespondWithError(res, http.StatusBadRequest, err)
+			return
+		}
+		payload, err := reqContent.Decrypt(algorithm, key)
+		if err != nil {
+			suite.Logger.Errorf("Failed to decrypt the payload", err)
+			core.RespondWithError(res, http.StatusBadRequest, err)
+			return
+		}
+		encrypted, err := request.ContentWithData(bytes.ToUpper(payload.Data), "text/plain").Encrypt(algorithm, key)
+		if err != nil {
+			core.RespondWithError(res, http.StatusInternalServerError, err)
+			return
+		}
+		res.Header().Set("Content-Type", "text/plain")
+		res.Header().Set(request.ContentEncryptionHeader, algorithm.String())
+		_, _ = res.Write(encrypted.Data)
+	}))
+}
@@ -0,0 +1,84 @@
+package request_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanSignWithOAuth1(t *testing.T) {
+	// Example from https://developer.twitter.com/en/docs/authentication/oauth-1-0a/creating-a-signature
+	signer := request.OAuth1Signer{
+		ConsumerKey:    "xvz1evFS4wEEPTGEFPHBog",
+		ConsumerSecret: "kAcSOqF21Fu85e7zjz7ZN2U4ZRhfV3WpwPAoE3Z7kBw",
+		Token:          "370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb",
+		TokenSecret:    "LswwdoUaIvS8ltyTt5jkRh4J50vUPVVHtR2YPi5kE",
+	}
+	payload := []byte("status=Hello%20Ladies%20%2B%20Gentlemen%2C%20a%20signed%20OAuth%20request%21")
+	req, err := http.NewRequest(http.MethodPost, "https://api.twitter.com/1.1/statuses/update.json?include_entities=true", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	require.NoError(t, signer.SignAt(req, payload, time.Unix(1318622958, 0), "kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg"))
+	authorization := req.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(authorization, "OAuth "), "Authorization should be OAuth")
+	assert.Contains(t, authorization, `oauth_signature="hCtSmYh%2BiHYCEqBWrE7C7hYmtUk%3D"`)
+	assert.Contains(t, authorization, `oauth_signature_method="HMAC-SHA1"`)
+	assert.Contains(t, authorization, `oauth_token="370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb"`)
+}
+
+func TestCanSignWithOAuth1ParametersSharingAPrefix(t *testing.T) {
+	signer := request.OAuth1Signer{ConsumerKey: "consumer", ConsumerSecret: "secret"}
+	// the parameters are sorted by name then by value: a=1&a1=2&b=1&b=2
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/resource?a1=2&a=1&b=2&b=1", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, signer.SignAt(req, nil, time.Unix(1318622958, 0), "nonce"))
+	assert.Contains(t, req.Header.Get("Authorization"), `oauth_signature="YkueqRtPbct6qZSVRAH%2F%2B4bD16U%3D"`)
+}
+
+func TestCanSignWithOAuth1RSASHA1(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer := request.OAuth1Signer{ConsumerKey: "consumer", SignatureMethod: request.OAuth1RSASHA1, PrivateKey: key, Realm: "Example"}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/resource", nil)
+	require.NoError(t, signer.Sign(req, nil))
+	authorization := req.Header.Get("Authorization")
+	assert.True(t, strings.HasPrefix(authorization, `OAuth realm="Example", oauth_consumer_key="consumer"`), "Wrong Authorization: %s", authorization)
+	assert.Contains(t, authorization, `oauth_signature_method="RSA-SHA1"`)
+
+	err = request.OAuth1Signer{ConsumerKey: "consumer", SignatureMethod: request.OAuth1RSASHA1}.Sign(req, nil)
+	assert.ErrorIs(t, err, errors.ArgumentMissing)
+}
+
+func TestCanSignWithHawk(t *testing.T) {
+	// Examples from https://github.com/mozilla/hawk
+	signer := request.HawkSigner{ID: "dh37fgj492je", Key: "werxhqb98rpaxn39848xrunpaw3489ruxnpa98w4rxn", Ext: "some-app-ext-data"}
+	at := time.Unix(1353832234, 0)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com:8000/resource/1?b=1&a=2", nil)
+	require.NoError(t, err)
+	require.NoError(t, signer.SignAt(req, nil, at, "j4h3g2"))
+	assert.Equal(t, `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", ext="some-app-ext-data", mac="6R4rV5iE+NPoym+WwjeHzjAGXUtLNIxmo1vpMofpLAE="`, req.Header.Get("Authorization"))
+
+	req, err = http.NewRequest(http.MethodPost, "http://example.com:8000/resource/1?b=1&a=2", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	require.NoError(t, signer.SignAt(req, []byte("Thank you for flying Hawk"), at, "j4h3g2"))
+	assert.Equal(t, `Hawk id="dh37fgj492je", ts="1353832234", nonce="j4h3g2", hash="Yi9LfIIFRtBEPt74PVmbTF/xVAwPn7ub15ePICfgnuY=", ext="some-app-ext-data", mac="aSe1DERmZuRl3pI36/9BdZmnErTw3sNzOOAUlfeKjVw="`, req.Header.Get("Authorization"))
+}
+
+func TestShouldFailSigningWithHawkWithUnknownAlgorithm(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	err := request.HawkSigner{ID: "id", Key: "key", Algorithm: "md5"}.Sign(req, nil)
+	assert.ErrorIs(t, err, errors.ArgumentInvalid)
+}
@@ -0,0 +1,191 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// AWSSigV4Signer signs requests with AWS Signature Version 4
+//
+// The host, content-type, content-md5, and x-amz-* headers are signed.
+//
+// implements Signer
+type AWSSigV4Signer struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // if not empty, it is sent in the X-Amz-Security-Token header
+	Region          string // e.g.: "us-east-1"
+	Service         string // e.g.: "s3", "execute-api", "es"
+}
+
+const awsSigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// NewAWSSigV4Signer creates a new AWS Signature Version 4 Signer
+func NewAWSSigV4Signer(accessKeyID, secretAccessKey, region, service string) *AWSSigV4Signer {
+	return &AWSSigV4Signer{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Region:          region,
+		Service:         service,
+	}
+}
+
+// Sign signs the request with the current time
+//
+// implements Signer
+func (signer AWSSigV4Signer) Sign(req *http.Request, payload []byte) error {
+	return signer.SignAt(req, payload, time.Now())
+}
+
+// SignAt signs the request as if it was sent at the given time
+func (signer AWSSigV4Signer) SignAt(req *http.Request, payload []byte, at time.Time) error {
+	if len(signer.AccessKeyID) == 0 {
+		return errors.ArgumentMissing.With("AccessKeyID")
+	}
+	if len(signer.SecretAccessKey) == 0 {
+		return errors.ArgumentMissing.With("SecretAccessKey")
+	}
+	if len(signer.Region) == 0 {
+		return errors.ArgumentMissing.With("Region")
+	}
+	if len(signer.Service) == 0 {
+		return errors.ArgumentMissing.With("Service")
+	}
+	at = at.UTC()
+	amzDate := at.Format("20060102T150405Z")
+	date := at.Format("20060102")
+	payloadHash := sha256.Sum256(payload)
+	hexPayloadHash := hex.EncodeToString(payloadHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if len(signer.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", signer.SessionToken)
+	}
+	if signer.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", hexPayloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := awsCanonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL, signer.Service != "s3"),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		hexPayloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + signer.Region + "/" + signer.Service + "/aws4_request"
+	stringToSign := awsSigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := awsHMAC([]byte("AWS4"+signer.SecretAccessKey), date)
+	key = awsHMAC(key, signer.Region)
+	key = awsHMAC(key, signer.Service)
+	key = awsHMAC(key, "aws4_request")
+	signature := hex.EncodeToString(awsHMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", awsSigV4Algorithm+" Credential="+signer.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+func awsHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalURI gets the URI-encoded path, the segments are encoded twice except for S3
+func awsCanonicalURI(u *url.URL, encodeTwice bool) string {
+	path := u.EscapedPath()
+	if len(path) == 0 {
+		return "/"
+	}
+	if !encodeTwice {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = percentEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery gets the query sorted by encoded key and value, URI-encoded
+func awsCanonicalQuery(query url.Values) string {
+	parameters := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, [2]string{percentEncode(key), percentEncode(value)})
+		}
+	}
+	return joinSortedParameters(parameters)
+}
+
+// awsCanonicalHeaders gets the canonical headers and the list of signed headers
+func awsCanonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for key, values := range req.Header {
+		name := strings.ToLower(key)
+		if name == "content-type" || name == "content-md5" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(values))
+			for i, value := range values {
+				trimmed[i] = strings.Join(strings.Fields(value), " ")
+			}
+			headers[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sb := strings.Builder{}
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(":")
+		sb.WriteString(headers[name])
+		sb.WriteString("\n")
+	}
+	return sb.String(), strings.Join(names, ";")
+}
+
+// percentEncode encodes everything but the unreserved characters of RFC 3986
+// joinSortedParameters joins the encoded (name, value) pairs as name=value, sorted by name then by value
+func joinSortedParameters(parameters [][2]string) string {
+	sort.Slice(parameters, func(i, j int) bool {
+		if parameters[i][0] != parameters[j][0] {
+			return parameters[i][0] < parameters[j][0]
+		}
+		return parameters[i][1] < parameters[j][1]
+	})
+	joined := make([]string, len(parameters))
+	for i, parameter := range parameters {
+		joined[i] = parameter[0] + "=" + parameter[1]
+	}
+	return strings.Join(joined, "&")
+}
+
+func percentEncode(value string) string {
+	sb := strings.Builder{}
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteString("%")
+			sb.WriteString(strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package request_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request"
+)
+
+// Test vectors from the AWS Signature Version 4 Test Suite
+var awsTestSigner = request.NewAWSSigV4Signer("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service")
+var awsTestTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestCanSignWithAWSSigV4(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", http.MethodGet, "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"post-vanilla", http.MethodPost, "https://example.amazonaws.com/", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"get-vanilla-query-order-key-case", http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-query-key-prefix", http.MethodGet, "https://example.amazonaws.com/?a1=2&a=1", "aec7f90782b4b370f7be0a574b55c67226c3d5336a46214182f3f31ce7e74215"},
+		{"get-query-key-prefix-and-values", http.MethodGet, "https://example.amazonaws.com/?b=2&a1=2&b=1&a=1", "bea05fb275974e14b911367c9b3a29800a9fd79e439726093480d2196d7a916f"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, test.url, nil)
+		require.NoError(t, err)
+		require.NoError(t, awsTestSigner.SignAt(req, nil, awsTestTime), "Failed to sign %s", test.name)
+		assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+		assert.Equal(t,
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+test.signature,
+			req.Header.Get("Authorization"),
+			"Wrong signature for %s", test.name,
+		)
+	}
+}
+
+func TestCanSignS3WithAWSSigV4(t *testing.T) {
+	signer := request.NewAWSSigV4Signer("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "s3")
+	signer.SessionToken = "session"
+	req, err := http.NewRequest(http.MethodPut, "https://bucket.s3.amazonaws.com/my%20file.txt", nil)
+	require.NoError(t, err)
+	require.NoError(t, signer.SignAt(req, []byte("Hello"), awsTestTime))
+	assert.Equal(t, "185f8db32271fe25f561a6fc938b2e264306ec304eda518007d1764826381969", req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+}
+
+func TestShouldFailSigningWithAWSSigV4WithoutCredentials(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	err := request.NewAWSSigV4Signer("", "secret", "us-east-1", "service").Sign(req, nil)
+	assert.ErrorIs(t, err, errors.ArgumentMissing)
+}
+
+func TestCanSendRequestWithSigner(t *testing.T) {
+	authorizations := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		authorizations = append(authorizations, req.Header.Get("Authorization"))
+		_, _ = res.Write([]byte("body"))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	content, err := request.Send(&request.Options{
+		URL:     serverURL,
+		Payload: request.ContentWithData([]byte("Hello"), "text/plain"),
+		Signer:  awsTestSigner,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(content.Data))
+	require.Len(t, authorizations, 1)
+	assert.True(t, strings.HasPrefix(authorizations[0], "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), "Request should be signed")
+	assert.Contains(t, authorizations[0], "SignedHeaders=content-type;host;x-amz-date,")
+}
@@ -0,0 +1,172 @@
+package request
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// SingleFlight shares one network call between concurrent identical GET requests
+//
+// Requests are identical when they have the same URL, Authorization, API Key, Accept, Headers, and Cookies.
+// Each caller gets its own copy of the resulting Content, and its results are decoded from it.
+// The shared call does not stop when one of its callers cancels its Context.
+//
+// Requests with a TokenProvider or a Signer, and requests whose results are an io.Writer or a ContentReader, are never shared.
+//
+// The same SingleFlight is given to the Options of all the requests that should share their calls.
+type SingleFlight struct {
+	calls map[string]*flightCall
+	lock  sync.Mutex
+}
+
+// flightCall is a call in flight, shared by all the callers of the same key
+type flightCall struct {
+	done    chan struct{}
+	content *Content
+	err     error
+	shared  int
+}
+
+// NewSingleFlight instantiates a new SingleFlight
+func NewSingleFlight() *SingleFlight {
+	return &SingleFlight{calls: map[string]*flightCall{}}
+}
+
+// InFlight gets the number of calls currently in flight
+func (flight *SingleFlight) InFlight() int {
+	flight.lock.Lock()
+	defer flight.lock.Unlock()
+	return len(flight.calls)
+}
+
+// do runs send once for all the concurrent callers of the same key
+//
+// The call runs on a context that does not depend on any of its callers, so a caller that gives up does not fail the others.
+// Each caller waits for the call until its own context is done.
+//
+// returns true if the call was shared with another caller
+func (flight *SingleFlight) do(ctx context.Context, key string, send func(context.Context) (*Content, error)) (*Content, bool, error) {
+	flight.lock.Lock()
+	if flight.calls == nil {
+		flight.calls = map[string]*flightCall{}
+	}
+	call, found := flight.calls[key]
+	if found {
+		call.shared++
+	} else {
+		call = &flightCall{done: make(chan struct{})}
+		flight.calls[key] = call
+		go func() {
+			call.content, call.err = send(context.WithoutCancel(ctx))
+			flight.lock.Lock()
+			delete(flight.calls, key)
+			flight.lock.Unlock()
+			close(call.done)
+		}()
+	}
+	flight.lock.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, found, errors.WithStack(ctx.Err())
+	}
+	flight.lock.Lock()
+	shared := found || call.shared > 0
+	flight.lock.Unlock()
+	return cloneContent(call.content), shared, call.err
+}
+
+// cloneContent copies a Content so callers sharing a call do not share its Data and Headers
+func cloneContent(content *Content) *Content {
+	if content == nil {
+		return nil
+	}
+	clone := *content
+	clone.Data = append([]byte(nil), content.Data...)
+	if content.RawData != nil {
+		clone.RawData = append([]byte(nil), content.RawData...)
+	}
+	clone.Headers = content.Headers.Clone()
+	return &clone
+}
+
+// singleFlightKey gets the key that identifies identical requests
+//
+// returns false if the request cannot be shared
+func singleFlightKey(options *Options, results interface{}) (string, bool) {
+	method := options.Method
+	if len(method) == 0 && options.Payload == nil && options.Attachment == nil && len(options.Attachments) == 0 {
+		method = http.MethodGet // as buildRequest would compute it
+	}
+	if method != http.MethodGet || options.TokenProvider != nil || options.Signer != nil {
+		return "", false
+	}
+	if _, ok := results.(io.Writer); ok || isContentReader(results) {
+		return "", false
+	}
+	key := []string{method, options.URL.String(), options.Authorization, options.Accept}
+	if options.APIKey != nil {
+		key = append(key, options.APIKey.Name, options.APIKey.Key)
+	}
+	// headers and cookies often carry the identity of the caller (session cookie, tenant, Authorization), they must match too
+	headers := options.Header.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	for name, value := range options.Headers {
+		headers.Add(name, value)
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key = append(key, http.CanonicalHeaderKey(name)+": "+strings.Join(headers[name], ", "))
+	}
+	cookies := make([]string, 0, len(options.Cookies))
+	for _, cookie := range options.Cookies {
+		if cookie != nil {
+			cookies = append(cookies, cookie.Name+"="+cookie.Value)
+		}
+	}
+	sort.Strings(cookies)
+	key = append(key, "Cookie: "+strings.Join(cookies, "; "))
+	return strings.Join(key, "\n"), true
+}
+
+// sendSingleFlight sends the request via the SingleFlight of the options and decodes the shared Content in the results
+func sendSingleFlight(options *Options, results interface{}, key string) (*Content, error) {
+	log := requestLogger(options).Child(nil, "singleflight", "reqid", options.RequestID, "method", options.Method)
+	content, shared, err := options.SingleFlight.do(options.Context, key, func(ctx context.Context) (*Content, error) {
+		// options are already normalized, they must not be applied again
+		flightOptions := *options
+		flightOptions.Context = ctx
+		flightOptions.SingleFlight = nil
+		flightOptions.BaseURL = nil
+		flightOptions.Path = ""
+		flightOptions.PathParameters = nil
+		flightOptions.Parameters = nil
+		flightOptions.QueryValues = nil
+		return Send(&flightOptions, nil)
+	})
+	if shared {
+		log.Debugf("Shared the call to %s %s", options.Method, options.URL)
+	}
+	if err != nil {
+		return content, err
+	}
+	if results != nil && content != nil && content.Length > 0 {
+		if err = decodeResults(content, results, options); err != nil {
+			return content, err // err is already decorated
+		}
+	}
+	return content, nil
+}
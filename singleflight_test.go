@@ -0,0 +1,129 @@
+package request_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gildas/go-request"
+)
+
+func CreateSingleFlightTestServer(suite *RequestSuite, calls *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(200 * time.Millisecond) // gives the other requests time to join the call
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "` + r.URL.Query().Get("id") + `"}`))
+	}))
+}
+
+func (suite *RequestSuite) TestCanShareIdenticalRequestsWithSingleFlight() {
+	calls := atomic.Int32{}
+	server := CreateSingleFlightTestServer(suite, &calls)
+	defer server.Close()
+	flight := request.NewSingleFlight()
+
+	var wg sync.WaitGroup
+	results := make([]stuff, 5)
+	contents := make([]*request.Content, 5)
+	errs := make([]error, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			serverURL, _ := url.Parse(server.URL)
+			contents[i], errs[i] = request.Send(&request.Options{
+				URL:          serverURL,
+				Parameters:   map[string]string{"id": "1234"},
+				SingleFlight: flight,
+				Logger:       suite.Logger,
+			}, &results[i])
+		}(i)
+	}
+	wg.Wait()
+	suite.Assert().Equal(int32(1), calls.Load(), "The server should have received 1 request")
+	suite.Assert().Equal(0, flight.InFlight(), "There should be no call in flight")
+	for i := range results {
+		suite.Require().NoError(errs[i], "Failed sending request #%d, err=%+v", i, errs[i])
+		suite.Assert().Equal("1234", results[i].ID)
+		suite.Require().NotNil(contents[i], "Content #%d should not be nil", i)
+		suite.Assert().Equal(http.StatusOK, contents[i].StatusCode)
+	}
+	contents[0].Data[0] = 'X'
+	suite.Assert().NotEqual(contents[0].Data[0], contents[1].Data[0], "Contents should not share their data")
+}
+
+func (suite *RequestSuite) TestShouldNotShareDifferentRequestsWithSingleFlight() {
+	calls := atomic.Int32{}
+	server := CreateSingleFlightTestServer(suite, &calls)
+	defer server.Close()
+	flight := request.NewSingleFlight()
+
+	var wg sync.WaitGroup
+	for _, options := range []*request.Options{
+		{Parameters: map[string]string{"id": "1234"}},
+		{Parameters: map[string]string{"id": "5678"}},
+		{Parameters: map[string]string{"id": "1234"}, Authorization: "Bearer 1234"},
+		{Parameters: map[string]string{"id": "1234"}, Method: http.MethodPost, Payload: stuff{"1234"}},
+		{Parameters: map[string]string{"id": "1234"}, Headers: map[string]string{"X-Tenant": "acme"}},
+		{Parameters: map[string]string{"id": "1234"}, Cookies: []*http.Cookie{{Name: "session", Value: "1234"}}},
+	} {
+		wg.Add(1)
+		go func(options *request.Options) {
+			defer wg.Done()
+			options.URL, _ = url.Parse(server.URL)
+			options.SingleFlight = flight
+			options.Logger = suite.Logger
+			_, err := request.Send(options, nil)
+			suite.Assert().NoError(err, "Failed sending request, err=%+v", err)
+		}(options)
+	}
+	wg.Wait()
+	suite.Assert().Equal(int32(6), calls.Load(), "The server should have received 6 requests")
+}
+
+func (suite *RequestSuite) TestShouldNotFailSharedCallWhenLeaderCancels() {
+	calls := atomic.Int32{}
+	server := CreateSingleFlightTestServer(suite, &calls)
+	defer server.Close()
+	flight := request.NewSingleFlight()
+	serverURL, _ := url.Parse(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	var leaderErr, followerErr error
+	leader, follower := stuff{}, stuff{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, leaderErr = request.Send(&request.Options{
+			Context:      ctx,
+			URL:          serverURL,
+			Parameters:   map[string]string{"id": "1234"},
+			SingleFlight: flight,
+			Logger:       suite.Logger,
+		}, &leader)
+	}()
+	time.Sleep(50 * time.Millisecond) // the leader's call is in flight
+	go func() {
+		defer wg.Done()
+		_, followerErr = request.Send(&request.Options{
+			URL:          serverURL,
+			Parameters:   map[string]string{"id": "1234"},
+			SingleFlight: flight,
+			Logger:       suite.Logger,
+		}, &follower)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	suite.Assert().ErrorIs(leaderErr, context.Canceled, "The leader should have been canceled, err=%+v", leaderErr)
+	suite.Require().NoError(followerErr, "Failed sending request, err=%+v", followerErr)
+	suite.Assert().Equal("1234", follower.ID)
+	suite.Assert().Equal(int32(1), calls.Load(), "The server should have received 1 request")
+}
@@ -0,0 +1,66 @@
+package request
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-core"
+	"github.com/gildas/go-errors"
+)
+
+// SRVResolver resolves DNS SRV records, net.DefaultResolver implements it
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// resolveSRV resolves the SRV record and gets the host:port of the best target that is not excluded
+//
+// The resolver sorts the targets by priority and randomizes them by weight (RFC 2782).
+// If all targets are excluded, the best one is returned.
+func resolveSRV(ctx context.Context, resolver SRVResolver, name string, excluded ...string) (string, error) {
+	_, records, err := resolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return "", errors.WrapErrors(errors.NotFound.With("SRV", name), err)
+	}
+	if len(records) == 0 {
+		return "", errors.NotFound.With("SRV", name)
+	}
+	hosts := make([]string, 0, len(records))
+	for _, record := range records {
+		hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+	}
+	for _, host := range hosts {
+		if !core.Contains(excluded, host) {
+			return host, nil
+		}
+	}
+	return hosts[0], nil
+}
+
+// applySRV sets the BaseURL host from the SRV record of the options
+//
+// If there is no BaseURL, "https" is used as the scheme.
+func applySRV(options *Options) error {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	resolver := options.SRVResolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	host, err := resolveSRV(ctx, resolver, options.SRV)
+	if err != nil {
+		return err
+	}
+	baseURL := url.URL{Scheme: "https"}
+	if options.BaseURL != nil {
+		baseURL = *options.BaseURL
+	}
+	baseURL.Host = host
+	options.BaseURL = &baseURL
+	return nil
+}
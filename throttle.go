@@ -0,0 +1,83 @@
+package request
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// tokenBucket limits a throughput to a rate of bytes per second, with a burst of one second
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+	return &tokenBucket{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// burst gets the maximum number of bytes that should be transferred at once
+func (bucket *tokenBucket) burst() int {
+	if bucket.rate < 1 {
+		return 1
+	}
+	return int(bucket.rate)
+}
+
+// take takes n tokens, waiting until they are available or the context is done
+func (bucket *tokenBucket) take(ctx context.Context, n int) error {
+	bucket.lock.Lock()
+	now := time.Now()
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.rate {
+		bucket.tokens = bucket.rate
+	}
+	bucket.last = now
+	bucket.tokens -= float64(n)
+	var wait time.Duration
+	if bucket.tokens < 0 {
+		wait = time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+	}
+	bucket.lock.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// throttledReader limits the throughput of a reader
+type throttledReader struct {
+	io.Reader
+	context context.Context
+	bucket  *tokenBucket
+}
+
+func newThrottledReader(ctx context.Context, reader io.Reader, bytesPerSecond int64) *throttledReader {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &throttledReader{Reader: reader, context: ctx, bucket: newTokenBucket(bytesPerSecond)}
+}
+
+func (reader *throttledReader) Read(data []byte) (int, error) {
+	if burst := reader.bucket.burst(); len(data) > burst {
+		data = data[:burst]
+	}
+	n, err := reader.Reader.Read(data)
+	if n > 0 {
+		if waitErr := reader.bucket.take(reader.context, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,166 @@
+package request
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-logger"
+)
+
+// Timing contains the durations of the phases of an HTTP request
+//
+// DNSLookup, Connect, and TLSHandshake are 0 when a connection was reused.
+type Timing struct {
+	DNSLookup    time.Duration `json:"dnsLookup,omitempty"`
+	Connect      time.Duration `json:"connect,omitempty"`
+	TLSHandshake time.Duration `json:"tlsHandshake,omitempty"`
+	FirstByte    time.Duration `json:"firstByte,omitempty"` // time to first byte, since the request started
+	Total        time.Duration `json:"total,omitempty"`     // since the request started until the response body was read
+}
+
+// ConnectionInfo describes the connection a request was sent on
+type ConnectionInfo struct {
+	Reused     bool          `json:"reused,omitempty"`     // true if the connection was used by a previous request
+	WasIdle    bool          `json:"wasIdle,omitempty"`    // true if the connection was taken from the idle pool
+	IdleTime   time.Duration `json:"idleTime,omitempty"`   // how long the connection was idle, if WasIdle
+	LocalAddr  string        `json:"localAddr,omitempty"`  // local address of the connection
+	RemoteAddr string        `json:"remoteAddr,omitempty"` // remote address of the connection
+}
+
+// timingTracer collects the Timing of a request via httptrace and logs each phase at trace level
+type timingTracer struct {
+	log          *logger.Logger
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timing       Timing
+	connection   *ConnectionInfo
+	lock         sync.Mutex
+}
+
+func newTimingTracer(log *logger.Logger) *timingTracer {
+	return &timingTracer{log: log, start: time.Now()}
+}
+
+// ClientTrace gets the httptrace.ClientTrace that collects the Timing
+func (tracer *timingTracer) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.DNSLookup = time.Since(tracer.dnsStart)
+			tracer.log.Tracef("DNS lookup in %s (addresses: %v, error: %v)", tracer.timing.DNSLookup, info.Addrs, info.Err)
+		},
+		ConnectStart: func(network, addr string) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.Connect = time.Since(tracer.connectStart)
+			tracer.log.Tracef("Connected to %s/%s in %s (error: %v)", network, addr, tracer.timing.Connect, err)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.connection = &ConnectionInfo{Reused: info.Reused, WasIdle: info.WasIdle, IdleTime: info.IdleTime}
+			if info.Conn != nil {
+				tracer.connection.LocalAddr = info.Conn.LocalAddr().String()
+				tracer.connection.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			if info.Reused {
+				tracer.log.Tracef("Reusing connection to %s (idle for %s)", info.Conn.RemoteAddr(), info.IdleTime)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.TLSHandshake = time.Since(tracer.tlsStart)
+			tracer.log.Tracef("TLS handshake in %s (version: %s, error: %v)", tracer.timing.TLSHandshake, tls.VersionName(state.Version), err)
+		},
+		GotFirstResponseByte: func() {
+			tracer.lock.Lock()
+			defer tracer.lock.Unlock()
+			tracer.timing.FirstByte = time.Since(tracer.start)
+			tracer.log.Tracef("First response byte in %s", tracer.timing.FirstByte)
+		},
+	}
+}
+
+// Timing gets the collected Timing, its Total is computed now
+func (tracer *timingTracer) Timing() *Timing {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	timing := tracer.timing
+	timing.Total = time.Since(tracer.start)
+	return &timing
+}
+
+// Connection gets the ConnectionInfo of the request, nil if no connection was obtained
+func (tracer *timingTracer) Connection() *ConnectionInfo {
+	tracer.lock.Lock()
+	defer tracer.lock.Unlock()
+	if tracer.connection == nil {
+		return nil
+	}
+	connection := *tracer.connection
+	return &connection
+}
+
+// ServerTimingMetric is a metric of the Server-Timing response header
+type ServerTimingMetric struct {
+	Name        string        `json:"name"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// parseServerTiming parses the values of Server-Timing headers
+//
+// e.g.: cache;desc="Cache Read";dur=23.2, db;dur=53, app;dur=47.2
+func parseServerTiming(values []string) []ServerTimingMetric {
+	metrics := []ServerTimingMetric{}
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			parameters := strings.Split(entry, ";")
+			name := strings.TrimSpace(parameters[0])
+			if len(name) == 0 {
+				continue
+			}
+			metric := ServerTimingMetric{Name: name}
+			for _, parameter := range parameters[1:] {
+				key, value, _ := strings.Cut(parameter, "=")
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					if milliseconds, err := strconv.ParseFloat(value, 64); err == nil {
+						metric.Duration = time.Duration(milliseconds * float64(time.Millisecond))
+					}
+				case "desc":
+					metric.Description = value
+				}
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	return metrics
+}
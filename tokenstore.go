@@ -0,0 +1,205 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gildas/go-errors"
+	"github.com/zalando/go-keyring"
+)
+
+// TokenStore persists OAuth2 tokens, one per audience (e.g.: a client ID, an API, a user)
+//
+// Load returns nil and no error when the store has no token for the audience.
+type TokenStore interface {
+	Load(context context.Context, audience string) (*OAuth2Token, error)
+	Save(context context.Context, audience string, token *OAuth2Token) error
+}
+
+// DefaultKeyringService is the service under which a KeyringTokenStore saves the tokens by default
+const DefaultKeyringService = "go-request"
+
+// MemoryTokenStore keeps OAuth2 tokens in memory, they are lost when the process ends
+//
+// implements TokenStore
+type MemoryTokenStore struct {
+	tokens sync.Map
+}
+
+// Load loads the token of the audience
+//
+// implements TokenStore
+func (store *MemoryTokenStore) Load(context context.Context, audience string) (*OAuth2Token, error) {
+	if token, found := store.tokens.Load(audience); found {
+		copied := *token.(*OAuth2Token)
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// Save saves the token of the audience, a nil token removes it
+//
+// implements TokenStore
+func (store *MemoryTokenStore) Save(context context.Context, audience string, token *OAuth2Token) error {
+	if token == nil {
+		store.tokens.Delete(audience)
+		return nil
+	}
+	copied := *token
+	store.tokens.Store(audience, &copied)
+	return nil
+}
+
+// FileTokenStore keeps OAuth2 tokens in a JSON file that only the current user can read
+//
+// The file and its folder are created when the first token is saved.
+// A good place for the file of a CLI tool is in os.UserConfigDir() (e.g.: ~/.config/mytool/tokens.json).
+//
+// implements TokenStore
+type FileTokenStore struct {
+	Path string // the path of the file
+
+	mutex sync.Mutex
+}
+
+// NewFileTokenStore creates a FileTokenStore that keeps the tokens in the given file
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// Load loads the token of the audience
+//
+// implements TokenStore
+func (store *FileTokenStore) Load(context context.Context, audience string) (*OAuth2Token, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	tokens, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+	if token, found := tokens[audience]; found {
+		return token, nil
+	}
+	return nil, nil
+}
+
+// Save saves the token of the audience, a nil token removes it
+//
+// The file is replaced atomically, so a process that crashes while saving does not lose the other tokens.
+//
+// implements TokenStore
+func (store *FileTokenStore) Save(context context.Context, audience string, token *OAuth2Token) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	tokens, err := store.read()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		delete(tokens, audience)
+	} else {
+		tokens[audience] = token
+	}
+	payload, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return errors.JSONMarshalError.Wrap(err)
+	}
+	folder := filepath.Dir(store.Path)
+	if err = os.MkdirAll(folder, 0700); err != nil {
+		return errors.WithStack(err)
+	}
+	file, err := os.CreateTemp(folder, "."+filepath.Base(store.Path)+".*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(file.Name()) // fails silently once the file is renamed
+	if _, err = file.Write(payload); err != nil {
+		_ = file.Close()
+		return errors.WithStack(err)
+	}
+	if err = file.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(file.Name(), store.Path))
+}
+
+// read reads the tokens of the file, there are none if the file does not exist
+func (store *FileTokenStore) read() (map[string]*OAuth2Token, error) {
+	if len(store.Path) == 0 {
+		return nil, errors.ArgumentMissing.With("Path")
+	}
+	tokens := map[string]*OAuth2Token{}
+	payload, err := os.ReadFile(store.Path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err = json.Unmarshal(payload, &tokens); err != nil {
+		return nil, errors.JSONUnmarshalError.Wrap(err)
+	}
+	return tokens, nil
+}
+
+// KeyringTokenStore keeps OAuth2 tokens in the keyring of the OS (macOS Keychain, Windows Credential Manager, or Secret Service on Linux)
+//
+// Each token is saved as a secret of the Service with the audience as the user.
+//
+// implements TokenStore
+type KeyringTokenStore struct {
+	Service string // the service of the secrets, by default: go-request
+}
+
+// NewKeyringTokenStore creates a KeyringTokenStore that saves the tokens under the given service
+func NewKeyringTokenStore(service string) *KeyringTokenStore {
+	return &KeyringTokenStore{Service: service}
+}
+
+// Load loads the token of the audience
+//
+// implements TokenStore
+func (store KeyringTokenStore) Load(context context.Context, audience string) (*OAuth2Token, error) {
+	secret, err := keyring.Get(store.service(), audience)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	token := OAuth2Token{}
+	if err = json.Unmarshal([]byte(secret), &token); err != nil {
+		return nil, errors.JSONUnmarshalError.Wrap(err)
+	}
+	return &token, nil
+}
+
+// Save saves the token of the audience, a nil token removes it
+//
+// implements TokenStore
+func (store KeyringTokenStore) Save(context context.Context, audience string, token *OAuth2Token) error {
+	if token == nil {
+		if err := keyring.Delete(store.service(), audience); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return errors.JSONMarshalError.Wrap(err)
+	}
+	return errors.WithStack(keyring.Set(store.service(), audience, string(payload)))
+}
+
+// service gets the service of the secrets
+func (store KeyringTokenStore) service() string {
+	if len(store.Service) == 0 {
+		return DefaultKeyringService
+	}
+	return store.Service
+}
@@ -0,0 +1,80 @@
+package request_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+
+	"github.com/gildas/go-request"
+)
+
+func testTokenStore(t *testing.T, store request.TokenStore) {
+	ctx := context.Background()
+	token, err := store.Load(ctx, "my-client")
+	require.NoError(t, err)
+	assert.Nil(t, token, "The store should not have a token yet")
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	require.NoError(t, store.Save(ctx, "my-client", &request.OAuth2Token{AccessToken: "ACCESS-1", RefreshToken: "REFRESH-1", ExpiresAt: expiresAt}))
+	require.NoError(t, store.Save(ctx, "other-client", &request.OAuth2Token{AccessToken: "ACCESS-2"}))
+
+	token, err = store.Load(ctx, "my-client")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "ACCESS-1", token.AccessToken)
+	assert.Equal(t, "REFRESH-1", token.RefreshToken)
+	assert.True(t, expiresAt.Equal(token.ExpiresAt))
+
+	require.NoError(t, store.Save(ctx, "my-client", nil))
+	token, err = store.Load(ctx, "my-client")
+	require.NoError(t, err)
+	assert.Nil(t, token, "The token should have been removed")
+
+	token, err = store.Load(ctx, "other-client")
+	require.NoError(t, err)
+	require.NotNil(t, token, "The other tokens should be kept")
+	assert.Equal(t, "ACCESS-2", token.AccessToken)
+}
+
+func TestCanUseMemoryTokenStore(t *testing.T) {
+	testTokenStore(t, &request.MemoryTokenStore{})
+}
+
+func TestCanUseFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mytool", "tokens.json")
+	testTokenStore(t, request.NewFileTokenStore(path))
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Only the user should be able to read the tokens")
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "Temporary files should be removed")
+}
+
+func TestShouldFailUsingFileTokenStoreWithoutPath(t *testing.T) {
+	_, err := request.NewFileTokenStore("").Load(context.Background(), "my-client")
+	assert.ErrorIs(t, err, errors.ArgumentMissing)
+}
+
+func TestShouldFailLoadingFromCorruptedFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	require.NoError(t, os.WriteFile(path, []byte("not JSON"), 0600))
+	_, err := request.NewFileTokenStore(path).Load(context.Background(), "my-client")
+	assert.ErrorIs(t, err, errors.JSONUnmarshalError)
+}
+
+func TestCanUseKeyringTokenStore(t *testing.T) {
+	keyring.MockInit()
+	testTokenStore(t, request.NewKeyringTokenStore("mytool"))
+}
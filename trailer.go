@@ -0,0 +1,28 @@
+package request
+
+import (
+	"io"
+	"net/http"
+)
+
+// trailerReader calls a func to fill the trailers once the request body is sent
+type trailerReader struct {
+	io.Reader
+	trailer http.Header
+	fill    func(trailer http.Header)
+	filled  bool
+}
+
+// Read reads the request body
+//
+// implements io.Reader
+func (reader *trailerReader) Read(data []byte) (int, error) {
+	read, err := reader.Reader.Read(data)
+	if err == io.EOF && !reader.filled {
+		reader.filled = true
+		if reader.fill != nil {
+			reader.fill(reader.trailer)
+		}
+	}
+	return read, err
+}
@@ -0,0 +1,51 @@
+package request
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// pathTemplateExpression matches the RFC 6570 simple ({var}) and reserved ({+var}) expressions
+var pathTemplateExpression = regexp.MustCompile(`\{(\+?)([A-Za-z0-9_.]+)\}`)
+
+// expandPathTemplate expands the RFC 6570 expressions of the URL's Path with the given parameters
+//
+// Simple expressions ({id}) are fully escaped, reserved expressions ({+path}) keep their slashes.
+func expandPathTemplate(u *url.URL, parameters map[string]string) error {
+	matches := pathTemplateExpression.FindAllStringSubmatchIndex(u.Path, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	path := strings.Builder{}
+	rawPath := strings.Builder{}
+	last := 0
+	for _, match := range matches {
+		literal := u.Path[last:match[0]]
+		path.WriteString(literal)
+		rawPath.WriteString((&url.URL{Path: literal}).EscapedPath())
+
+		reserved := match[3] > match[2]
+		name := u.Path[match[4]:match[5]]
+		value, found := parameters[name]
+		if !found {
+			return errors.ArgumentMissing.With("PathParameters." + name)
+		}
+		path.WriteString(value)
+		if reserved {
+			rawPath.WriteString(strings.ReplaceAll(url.PathEscape(value), "%2F", "/"))
+		} else {
+			rawPath.WriteString(url.PathEscape(value))
+		}
+		last = match[1]
+	}
+	literal := u.Path[last:]
+	path.WriteString(literal)
+	rawPath.WriteString((&url.URL{Path: literal}).EscapedPath())
+
+	u.Path = path.String()
+	u.RawPath = rawPath.String()
+	return nil
+}
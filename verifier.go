@@ -0,0 +1,75 @@
+package request
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// ResponseVerifier verifies the integrity of a response before its body is decoded
+//
+// Verify is called with the headers and the raw body of successful responses.
+type ResponseVerifier interface {
+	Verify(headers http.Header, body []byte) error
+}
+
+// ResponseVerifierFunc is a function that implements ResponseVerifier
+type ResponseVerifierFunc func(headers http.Header, body []byte) error
+
+// Verify verifies the response
+func (verifier ResponseVerifierFunc) Verify(headers http.Header, body []byte) error {
+	return verifier(headers, body)
+}
+
+// DefaultHMACSignatureHeader is the header that carries the signature of a response by default
+const DefaultHMACSignatureHeader = "X-Signature"
+
+// HMACVerifier verifies responses whose header carries an HMAC of their body
+//
+// The signature can be hex or base64 encoded.
+type HMACVerifier struct {
+	Secret []byte           // the shared secret
+	Header string           // the header that carries the signature, by default: X-Signature
+	Prefix string           // the prefix of the signature, if any (e.g.: "sha256=")
+	Hash   func() hash.Hash // the hash function, by default: sha256.New
+}
+
+// Verify verifies the signature of the response
+//
+// implements ResponseVerifier
+func (verifier HMACVerifier) Verify(headers http.Header, body []byte) error {
+	header := verifier.Header
+	if len(header) == 0 {
+		header = DefaultHMACSignatureHeader
+	}
+	newHash := verifier.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	signature, found := strings.CutPrefix(headers.Get(header), verifier.Prefix)
+	if len(signature) == 0 || !found {
+		return ResponseSignatureInvalid.With(header)
+	}
+	mac := hmac.New(newHash, verifier.Secret)
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return nil
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return nil
+	}
+	return ResponseSignatureInvalid.With(header)
+}
+
+// verifyResponse verifies the raw Content of a response, if there is a verifier
+func verifyResponse(verifier ResponseVerifier, content *Content) error {
+	if verifier == nil {
+		return nil
+	}
+	return verifier.Verify(content.Headers, content.Data)
+}
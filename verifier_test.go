@@ -0,0 +1,46 @@
+package request_test
+
+import (
+	"crypto/sha1"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gildas/go-request"
+)
+
+func TestCanVerifyResponseWithHMAC(t *testing.T) {
+	// Example from https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+	verifier := request.HMACVerifier{
+		Secret: []byte("It's a Secret to Everybody"),
+		Header: "X-Hub-Signature-256",
+		Prefix: "sha256=",
+	}
+	headers := http.Header{}
+	headers.Set("X-Hub-Signature-256", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	assert.NoError(t, verifier.Verify(headers, []byte("Hello, World!")))
+
+	headers = http.Header{}
+	headers.Set("X-Signature", "dXEH6g6yUJ/CESIczphLijdXC211hsIsRvQ3nIsEPhc=")
+	assert.NoError(t, request.HMACVerifier{Secret: []byte("It's a Secret to Everybody")}.Verify(headers, []byte("Hello, World!")), "base64 signatures should be verified")
+}
+
+func TestShouldFailVerifyingResponseWithInvalidHMAC(t *testing.T) {
+	verifier := request.HMACVerifier{Secret: []byte("It's a Secret to Everybody"), Prefix: "sha256="}
+	headers := http.Header{}
+	err := verifier.Verify(headers, []byte("Hello, World!"))
+	assert.ErrorIs(t, err, request.ResponseSignatureInvalid, "A missing signature should not be verified")
+
+	headers.Set("X-Signature", "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	err = verifier.Verify(headers, []byte("Hello, World!"))
+	assert.ErrorIs(t, err, request.ResponseSignatureInvalid, "A signature without its prefix should not be verified")
+
+	headers.Set("X-Signature", "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17")
+	err = verifier.Verify(headers, []byte("Hello, World?"))
+	assert.ErrorIs(t, err, request.ResponseSignatureInvalid, "A tampered body should not be verified")
+
+	verifier.Hash = sha1.New
+	err = verifier.Verify(headers, []byte("Hello, World!"))
+	assert.ErrorIs(t, err, request.ResponseSignatureInvalid, "The signature should be computed with the given hash")
+}
@@ -0,0 +1,186 @@
+// Package webhook delivers webhooks with the request package.
+//
+// The messages are signed with HMAC-SHA256 and carry the delivery headers of the Standard Webhooks specification
+// (https://www.standardwebhooks.com): webhook-id, webhook-timestamp, and webhook-signature.
+//
+// Failed deliveries are retried following a Schedule that spans hours, each attempt can be recorded with a callback.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-request"
+	"github.com/google/uuid"
+)
+
+// Headers sent with each webhook message
+const (
+	IDHeader        = "Webhook-Id"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+)
+
+// DefaultSchedule defines the delays before each delivery attempt
+//
+// The last attempt happens a little more than a day after the first one.
+var DefaultSchedule = []time.Duration{
+	0,
+	5 * time.Second,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	5 * time.Hour,
+	10 * time.Hour,
+	10 * time.Hour,
+}
+
+// DefaultTolerance defines how old a message can be when it is verified
+const DefaultTolerance = 5 * time.Minute
+
+// SignatureInvalid is returned when the signature of a message cannot be verified
+var SignatureInvalid = errors.NewSentinel(http.StatusUnauthorized, "error.webhook.signature.invalid", "Invalid webhook signature (%s)")
+
+// Sender delivers webhook messages
+type Sender struct {
+	Secret    []byte          // secret used to sign the messages
+	Schedule  []time.Duration // delays before each delivery attempt, by default: DefaultSchedule
+	Options   request.Options // options used to send the messages (e.g.: Logger, Transport, Timeout, Headers)
+	OnAttempt func(Attempt)   // if not nil, it is called after each delivery attempt (e.g.: to record it in a ledger)
+}
+
+// Attempt describes a delivery attempt
+type Attempt struct {
+	MessageID  string        `json:"messageId"`
+	Number     int           `json:"number"` // starts at 1
+	URL        *url.URL      `json:"-"`
+	At         time.Time     `json:"at"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Error      error         `json:"-"`
+}
+
+// Deliver sends the payload as JSON to the target URL, retrying following the Schedule
+//
+// Deliver blocks until the message is delivered, the Schedule is exhausted, the server rejects the message
+// (4xx other than 408 and 429), or the context is done.
+//
+// returns the message ID
+func (sender Sender) Deliver(ctx context.Context, target *url.URL, payload interface{}) (string, error) {
+	if target == nil {
+		return "", errors.ArgumentMissing.With("target")
+	}
+	if len(sender.Secret) == 0 {
+		return "", errors.ArgumentMissing.With("Secret")
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.JSONMarshalError.Wrap(err)
+	}
+	schedule := sender.Schedule
+	if len(schedule) == 0 {
+		schedule = DefaultSchedule
+	}
+	messageID := "msg_" + uuid.NewString()
+
+	for number, delay := range schedule {
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return messageID, errors.WithStack(ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		attempt := sender.send(ctx, target, messageID, body)
+		attempt.Number = number + 1
+		if sender.OnAttempt != nil {
+			sender.OnAttempt(attempt)
+		}
+		if attempt.Error == nil {
+			return messageID, nil
+		}
+		if isPermanent(attempt.StatusCode) {
+			return messageID, attempt.Error
+		}
+		err = attempt.Error
+	}
+	return messageID, errors.Wrapf(err, "Giving up after %d attempts", len(schedule))
+}
+
+func (sender Sender) send(ctx context.Context, target *url.URL, messageID string, body []byte) Attempt {
+	attempt := Attempt{MessageID: messageID, URL: target, At: time.Now()}
+	options := sender.Options
+	options.Context = ctx
+	options.Method = http.MethodPost
+	options.URL = target
+	options.Attempts = 1 // retries follow the Schedule
+	options.Headers = map[string]string{}
+	for key, value := range sender.Options.Headers {
+		options.Headers[key] = value
+	}
+	options.Headers[IDHeader] = messageID
+	options.Headers[TimestampHeader] = strconv.FormatInt(attempt.At.Unix(), 10)
+	options.Headers[SignatureHeader] = Sign(sender.Secret, messageID, attempt.At, body)
+	options.Payload = &request.Content{Type: "application/json", Data: body, Length: uint64(len(body))}
+
+	content, err := request.Send(&options, nil)
+	attempt.Duration = time.Since(attempt.At)
+	attempt.Error = err
+	if content != nil {
+		attempt.StatusCode = content.StatusCode
+	}
+	return attempt
+}
+
+// isPermanent tells if the status code means the message should not be sent again
+func isPermanent(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
+
+// Sign computes the signature of a message
+//
+// The signature is "v1," followed by the base64 HMAC-SHA256 of "id.timestamp.body".
+func Sign(secret []byte, messageID string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(messageID + "." + strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifies the signature of a received message
+//
+// The message is rejected if its timestamp is further than tolerance from now (by default: DefaultTolerance).
+// The signature header can contain several space separated signatures (e.g.: while secrets are rotated).
+func Verify(secret []byte, headers http.Header, body []byte, tolerance time.Duration) error {
+	messageID := headers.Get(IDHeader)
+	if len(messageID) == 0 {
+		return errors.ArgumentMissing.With(IDHeader)
+	}
+	seconds, err := strconv.ParseInt(headers.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return errors.WrapErrors(errors.ArgumentInvalid.With(TimestampHeader, headers.Get(TimestampHeader)), err)
+	}
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	timestamp := time.Unix(seconds, 0)
+	if age := time.Since(timestamp); age > tolerance || age < -tolerance {
+		return SignatureInvalid.With("timestamp is out of tolerance")
+	}
+	expected := Sign(secret, messageID, timestamp, body)
+	for _, signature := range strings.Fields(headers.Get(SignatureHeader)) {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return SignatureInvalid.With("no signature matches")
+}
@@ -0,0 +1,145 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gildas/go-request/webhook"
+)
+
+var secret = []byte("my-webhook-secret")
+
+// createReceiver creates a server that verifies the messages and answers the first failures messages with the given status
+func createReceiver(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	received := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.NoError(t, webhook.Verify(secret, req.Header, body, 0), "Message should be verified")
+		if received.Add(1) <= failures {
+			res.WriteHeader(status)
+			return
+		}
+		res.WriteHeader(http.StatusNoContent)
+	}))
+	return server, received
+}
+
+func TestCanDeliverWebhook(t *testing.T) {
+	server, received := createReceiver(t, 0, 0)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	attempts := []webhook.Attempt{}
+	sender := webhook.Sender{
+		Secret:    secret,
+		OnAttempt: func(attempt webhook.Attempt) { attempts = append(attempts, attempt) },
+	}
+	messageID, err := sender.Deliver(context.Background(), serverURL, map[string]string{"event": "user.created"})
+	require.NoError(t, err, "Failed to deliver the webhook")
+	assert.NotEmpty(t, messageID)
+	assert.Equal(t, int32(1), received.Load())
+	require.Len(t, attempts, 1)
+	assert.Equal(t, messageID, attempts[0].MessageID)
+	assert.Equal(t, 1, attempts[0].Number)
+	assert.Equal(t, http.StatusNoContent, attempts[0].StatusCode)
+}
+
+func TestCanRetryDeliveringWebhook(t *testing.T) {
+	server, received := createReceiver(t, 2, http.StatusServiceUnavailable)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	attempts := []webhook.Attempt{}
+	sender := webhook.Sender{
+		Secret:    secret,
+		Schedule:  []time.Duration{0, 10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		OnAttempt: func(attempt webhook.Attempt) { attempts = append(attempts, attempt) },
+	}
+	_, err := sender.Deliver(context.Background(), serverURL, map[string]string{"event": "user.created"})
+	require.NoError(t, err, "Failed to deliver the webhook")
+	assert.Equal(t, int32(3), received.Load())
+	require.Len(t, attempts, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, attempts[0].StatusCode)
+	assert.Error(t, attempts[0].Error)
+	assert.Equal(t, http.StatusNoContent, attempts[2].StatusCode)
+	assert.NoError(t, attempts[2].Error)
+}
+
+func TestShouldNotRetryRejectedWebhook(t *testing.T) {
+	server, received := createReceiver(t, 10, http.StatusBadRequest)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	sender := webhook.Sender{
+		Secret:   secret,
+		Schedule: []time.Duration{0, 10 * time.Millisecond, 10 * time.Millisecond},
+	}
+	_, err := sender.Deliver(context.Background(), serverURL, map[string]string{"event": "user.created"})
+	require.Error(t, err, "Should have failed delivering the webhook")
+	assert.ErrorIs(t, err, errors.HTTPBadRequest)
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestShouldGiveUpDeliveringWebhook(t *testing.T) {
+	server, received := createReceiver(t, 10, http.StatusInternalServerError)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	sender := webhook.Sender{
+		Secret:   secret,
+		Schedule: []time.Duration{0, 10 * time.Millisecond},
+	}
+	_, err := sender.Deliver(context.Background(), serverURL, map[string]string{"event": "user.created"})
+	require.Error(t, err, "Should have failed delivering the webhook")
+	assert.ErrorIs(t, err, errors.HTTPInternalServerError)
+	assert.Equal(t, int32(2), received.Load())
+}
+
+func TestShouldStopDeliveringWebhookWhenContextIsDone(t *testing.T) {
+	server, _ := createReceiver(t, 10, http.StatusServiceUnavailable)
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	sender := webhook.Sender{Secret: secret}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := sender.Deliver(ctx, serverURL, map[string]string{"event": "user.created"})
+	require.Error(t, err, "Should have failed delivering the webhook")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestShouldFailVerifyingWithWrongSignature(t *testing.T) {
+	body := []byte(`{"event":"user.created"}`)
+	now := time.Now()
+	headers := http.Header{}
+	headers.Set(webhook.IDHeader, "msg_1234")
+	headers.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	headers.Set(webhook.SignatureHeader, webhook.Sign([]byte("another secret"), "msg_1234", now, body))
+	err := webhook.Verify(secret, headers, body, 0)
+	assert.ErrorIs(t, err, webhook.SignatureInvalid)
+
+	headers.Set(webhook.SignatureHeader, webhook.Sign([]byte("another secret"), "msg_1234", now, body)+" "+webhook.Sign(secret, "msg_1234", now, body))
+	assert.NoError(t, webhook.Verify(secret, headers, body, 0), "One of the signatures should match")
+}
+
+func TestShouldFailVerifyingOldMessage(t *testing.T) {
+	body := []byte(`{"event":"user.created"}`)
+	then := time.Now().Add(-1 * time.Hour)
+	headers := http.Header{}
+	headers.Set(webhook.IDHeader, "msg_1234")
+	headers.Set(webhook.TimestampHeader, strconv.FormatInt(then.Unix(), 10))
+	headers.Set(webhook.SignatureHeader, webhook.Sign(secret, "msg_1234", then, body))
+	assert.ErrorIs(t, webhook.Verify(secret, headers, body, 0), webhook.SignatureInvalid)
+}